@@ -0,0 +1,41 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/go-oauth2/oauth2/v4"
+)
+
+// NewJSONLinesSink create to the event subscriber writing one JSON object per line
+func NewJSONLinesSink(w io.Writer) *JSONLinesSink {
+	return &JSONLinesSink{enc: json.NewEncoder(w)}
+}
+
+// JSONLinesSink write the grant lifecycle events as JSON lines,
+// the first write error is kept and returned by Err
+type JSONLinesSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// HandleEvent write the event
+func (s *JSONLinesSink) HandleEvent(ctx context.Context, e *oauth2.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.enc.Encode(e); err != nil && s.err == nil {
+		s.err = err
+	}
+}
+
+// Err the first write error
+func (s *JSONLinesSink) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
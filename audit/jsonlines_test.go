@@ -0,0 +1,52 @@
+package audit_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/audit"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestJSONLinesSink(t *testing.T) {
+	Convey("Test JSON lines sink", t, func() {
+		var buf bytes.Buffer
+		sink := audit.NewJSONLinesSink(&buf)
+		ctx := context.Background()
+
+		sink.HandleEvent(ctx, &oauth2.Event{
+			Type:      oauth2.EventTokenIssued,
+			Time:      time.Now(),
+			ClientID:  "1",
+			UserID:    "u1",
+			GrantType: oauth2.PasswordCredentials,
+		})
+		sink.HandleEvent(ctx, &oauth2.Event{
+			Type:          oauth2.EventClientAuthFailed,
+			Time:          time.Now(),
+			ClientID:      "2",
+			IP:            "127.0.0.1",
+			CorrelationID: "req-1",
+		})
+		So(sink.Err(), ShouldBeNil)
+
+		var events []map[string]interface{}
+		scanner := bufio.NewScanner(&buf)
+		for scanner.Scan() {
+			var v map[string]interface{}
+			So(json.Unmarshal(scanner.Bytes(), &v), ShouldBeNil)
+			events = append(events, v)
+		}
+		So(len(events), ShouldEqual, 2)
+		So(events[0]["type"], ShouldEqual, "token_issued")
+		So(events[0]["grant_type"], ShouldEqual, "password")
+		So(events[1]["correlation_id"], ShouldEqual, "req-1")
+		So(events[1], ShouldNotContainKey, "user_id")
+	})
+}
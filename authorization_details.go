@@ -0,0 +1,30 @@
+package oauth2
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// AuthorizationDetail an authorization details object of the rich authorization requests (RFC 9396)
+type AuthorizationDetail map[string]interface{}
+
+// Type the authorization details type
+func (d AuthorizationDetail) Type() string {
+	v, _ := d["type"].(string)
+	return v
+}
+
+// ParseAuthorizationDetails parse the JSON array of the authorization_details parameter,
+// every object must have a type
+func ParseAuthorizationDetails(s string) ([]AuthorizationDetail, error) {
+	var details []AuthorizationDetail
+	if err := json.Unmarshal([]byte(s), &details); err != nil {
+		return nil, err
+	}
+	for _, d := range details {
+		if d.Type() == "" {
+			return nil, errors.New("the authorization details type is missing")
+		}
+	}
+	return details, nil
+}
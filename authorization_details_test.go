@@ -0,0 +1,23 @@
+package oauth2_test
+
+import (
+	"testing"
+
+	"github.com/go-oauth2/oauth2/v4"
+)
+
+func TestParseAuthorizationDetails(t *testing.T) {
+	details, err := oauth2.ParseAuthorizationDetails(`[{"type":"payment_initiation","instructedAmount":{"currency":"EUR","amount":"123.50"}}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(details) != 1 || details[0].Type() != "payment_initiation" {
+		t.Fatalf("unexpected details: %v", details)
+	}
+
+	for _, v := range []string{`{"type":"payment_initiation"}`, `[{"amount":1}]`, `[{"type":1}]`, `invalid`} {
+		if _, err := oauth2.ParseAuthorizationDetails(v); err == nil {
+			t.Fatalf("invalid details parsed: %s", v)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package oauth2
+
+// PKCERequirement whether the client has to use PKCE
+type PKCERequirement int
+
+// define the PKCE requirements
+const (
+	// the server config decides (Config.ForcePKCE)
+	PKCEDefault PKCERequirement = iota
+	PKCERequired
+	PKCEOptional
+)
+
+// ClientPolicy the authorization policy of the client, it narrows the server config:
+// the empty lists allow everything the server allows
+type ClientPolicy struct {
+	PKCE                 PKCERequirement       `json:",omitempty"`
+	CodeChallengeMethods []CodeChallengeMethod `json:",omitempty"`
+	GrantTypes           []GrantType           `json:",omitempty"`
+	ResponseTypes        []ResponseType        `json:",omitempty"`
+}
+
+// AllowsCodeChallengeMethod whether the client may use the code challenge method
+func (p *ClientPolicy) AllowsCodeChallengeMethod(ccm CodeChallengeMethod) bool {
+	if p == nil || len(p.CodeChallengeMethods) == 0 {
+		return true
+	}
+	for _, v := range p.CodeChallengeMethods {
+		if v == ccm {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsGrantType whether the client may use the grant type
+func (p *ClientPolicy) AllowsGrantType(gt GrantType) bool {
+	if p == nil || len(p.GrantTypes) == 0 {
+		return true
+	}
+	for _, v := range p.GrantTypes {
+		if v == gt {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsResponseType whether the client may use the response type
+func (p *ClientPolicy) AllowsResponseType(rt ResponseType) bool {
+	if p == nil || len(p.ResponseTypes) == 0 {
+		return true
+	}
+	for _, v := range p.ResponseTypes {
+		if v == rt {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,31 @@
+package oauth2_test
+
+import (
+	"testing"
+
+	"github.com/go-oauth2/oauth2/v4"
+)
+
+func TestClientPolicy(t *testing.T) {
+	var p *oauth2.ClientPolicy
+	if !p.AllowsGrantType(oauth2.PasswordCredentials) ||
+		!p.AllowsResponseType(oauth2.Token) ||
+		!p.AllowsCodeChallengeMethod(oauth2.CodeChallengePlain) {
+		t.Fatal("the nil policy must allow everything")
+	}
+
+	p = &oauth2.ClientPolicy{
+		PKCE:                 oauth2.PKCERequired,
+		CodeChallengeMethods: []oauth2.CodeChallengeMethod{oauth2.CodeChallengeS256},
+		GrantTypes:           []oauth2.GrantType{oauth2.AuthorizationCode, oauth2.Refreshing},
+	}
+	if !p.AllowsCodeChallengeMethod(oauth2.CodeChallengeS256) || p.AllowsCodeChallengeMethod(oauth2.CodeChallengePlain) {
+		t.Fatal("unexpected code challenge methods")
+	}
+	if !p.AllowsGrantType(oauth2.Refreshing) || p.AllowsGrantType(oauth2.ClientCredentials) {
+		t.Fatal("unexpected grant types")
+	}
+	if !p.AllowsResponseType(oauth2.Token) {
+		t.Fatal("the empty response types must allow everything")
+	}
+}
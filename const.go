@@ -3,6 +3,7 @@ package oauth2
 import (
 	"crypto/sha256"
 	"encoding/base64"
+	"sort"
 	"strings"
 )
 
@@ -11,14 +12,69 @@ type ResponseType string
 
 // define the type of authorization request
 const (
-	Code  ResponseType = "code"
-	Token ResponseType = "token"
+	Code        ResponseType = "code"
+	Token       ResponseType = "token"
+	IDToken     ResponseType = "id_token"
+	None        ResponseType = "none"
+	CodeToken   ResponseType = "code token"
+	CodeIDToken ResponseType = "code id_token"
 )
 
+// ParseResponseType parse the space separated response type values,
+// the values are sorted so "token code" is the same response type as "code token"
+func ParseResponseType(v string) ResponseType {
+	values := strings.Fields(v)
+	sort.Strings(values)
+	return ResponseType(strings.Join(values, " "))
+}
+
 func (rt ResponseType) String() string {
 	return string(rt)
 }
 
+// Has whether the response type contains the value, e.g. code for "code id_token"
+func (rt ResponseType) Has(value string) bool {
+	for _, v := range strings.Fields(string(rt)) {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ResponseMode the mechanism returning the authorization response parameters
+// https://openid.net/specs/oauth-v2-multiple-response-types-1_0.html#ResponseModes
+type ResponseMode string
+
+// define the response mode
+const (
+	ResponseModeQuery    ResponseMode = "query"
+	ResponseModeFragment ResponseMode = "fragment"
+	ResponseModeFormPost ResponseMode = "form_post"
+	// JWT Secured Authorization Response Mode (JARM)
+	ResponseModeJWT         ResponseMode = "jwt"
+	ResponseModeQueryJWT    ResponseMode = "query.jwt"
+	ResponseModeFragmentJWT ResponseMode = "fragment.jwt"
+	ResponseModeFormPostJWT ResponseMode = "form_post.jwt"
+)
+
+func (rm ResponseMode) String() string {
+	return string(rm)
+}
+
+// IsJWT whether the response parameters are returned in a signed JWT
+func (rm ResponseMode) IsJWT() bool {
+	return rm == ResponseModeJWT || strings.HasSuffix(string(rm), ".jwt")
+}
+
+// Base the mechanism returning the response parameters, e.g. query for query.jwt
+func (rm ResponseMode) Base() ResponseMode {
+	if rm == ResponseModeJWT {
+		return ""
+	}
+	return ResponseMode(strings.TrimSuffix(string(rm), ".jwt"))
+}
+
 // GrantType authorization model
 type GrantType string
 
@@ -31,14 +87,13 @@ const (
 	Implicit            GrantType = "__implicit"
 )
 
+// String the grant type value, e.g. of the extension grants,
+// the internal implicit grant type has none
 func (gt GrantType) String() string {
-	if gt == AuthorizationCode ||
-		gt == PasswordCredentials ||
-		gt == ClientCredentials ||
-		gt == Refreshing {
-		return string(gt)
+	if gt == Implicit {
+		return ""
 	}
-	return ""
+	return string(gt)
 }
 
 // CodeChallengeMethod PCKE method
@@ -26,3 +26,15 @@ func TestValidateS256NoPadding(t *testing.T) {
 		t.Fatal("not valid")
 	}
 }
+
+func TestGrantTypeString(t *testing.T) {
+	if v := oauth2.GrantType("urn:ietf:params:oauth:grant-type:device_code").String(); v != "urn:ietf:params:oauth:grant-type:device_code" {
+		t.Fatalf("unexpected extension grant type: %s", v)
+	}
+	if v := oauth2.ClientCredentials.String(); v != "client_credentials" {
+		t.Fatalf("unexpected grant type: %s", v)
+	}
+	if v := oauth2.Implicit.String(); v != "" {
+		t.Fatalf("unexpected implicit grant type: %s", v)
+	}
+}
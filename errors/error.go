@@ -5,6 +5,12 @@ import "errors"
 // New returns an error that formats as the given text.
 var New = errors.New
 
+// Is reports whether any error in err's tree matches target.
+var Is = errors.Is
+
+// As finds the first error in err's tree that matches target, and if one is found, sets target to that error value and returns true.
+var As = errors.As
+
 // known errors
 var (
 	ErrInvalidRedirectURI   = errors.New("invalid redirect uri")
@@ -17,3 +23,23 @@ var (
 	ErrMissingCodeChallenge = errors.New("missing code challenge")
 	ErrInvalidCodeChallenge = errors.New("invalid code challenge")
 )
+
+// the request has no access token, e.g. reported by the resource middleware so the challenge has no error code,
+// it matches ErrInvalidAccessToken for the callers checking the invalid tokens
+var (
+	ErrMissingAccessToken error = &wrappedError{msg: "missing access token", err: ErrInvalidAccessToken}
+)
+
+// wrappedError the error with its own message matching the wrapped error
+type wrappedError struct {
+	msg string
+	err error
+}
+
+func (e *wrappedError) Error() string {
+	return e.msg
+}
+
+func (e *wrappedError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,112 @@
+package errors
+
+import "net/http"
+
+// NewOAuthError create the OAuth error of the error code (e.g. ErrInvalidRequest) with the description
+func NewOAuthError(code error, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
+
+// OAuthError the OAuth error response with its description, URI and HTTP status code,
+// the server recognizes it anywhere in the error chain of the returned error
+type OAuthError struct {
+	// the error code, one of the known errors (e.g. ErrInvalidRequest)
+	Code error
+	// the error_description, the description of the error code is used if empty
+	Description string
+	// the error_uri
+	URI string
+	// the HTTP status code, the status code of the error code is used if 0
+	StatusCode int
+	// the state returned with the authorization error, the state of the request is used if empty
+	State string
+	// the underlying error, it isn't sent to the client
+	Cause error
+}
+
+// ErrorCode the error code sent to the client
+func (e *OAuthError) ErrorCode() string {
+	if e.Code == nil {
+		return ErrServerError.Error()
+	}
+	return e.Code.Error()
+}
+
+// Error returns the error code, the description and the underlying error
+func (e *OAuthError) Error() string {
+	s := e.ErrorCode()
+	if e.Description != "" {
+		s += ": " + e.Description
+	}
+	if e.Cause != nil {
+		s += ": " + e.Cause.Error()
+	}
+	return s
+}
+
+// Unwrap returns the error code and the underlying error
+func (e *OAuthError) Unwrap() []error {
+	errs := make([]error, 0, 2)
+	if e.Code != nil {
+		errs = append(errs, e.Code)
+	}
+	if e.Cause != nil {
+		errs = append(errs, e.Cause)
+	}
+	return errs
+}
+
+// Response create the error response,
+// the description and the status code of the error code are used when not set,
+// the status code of the unknown error codes is 400
+func (e *OAuthError) Response() *Response {
+	code := e.Code
+	if code == nil {
+		code = ErrServerError
+	}
+
+	re := &Response{
+		Error:       code,
+		Description: e.Description,
+		URI:         e.URI,
+		StatusCode:  e.StatusCode,
+	}
+	if re.Description == "" {
+		re.Description = Descriptions[code]
+	}
+	if re.StatusCode == 0 {
+		re.StatusCode = http.StatusBadRequest
+		if v, ok := StatusCodes[code]; ok {
+			re.StatusCode = v
+		}
+	}
+	return re
+}
+
+// WithURI returns a copy of the error with the error_uri
+func (e *OAuthError) WithURI(uri string) *OAuthError {
+	c := *e
+	c.URI = uri
+	return &c
+}
+
+// WithStatusCode returns a copy of the error with the HTTP status code
+func (e *OAuthError) WithStatusCode(statusCode int) *OAuthError {
+	c := *e
+	c.StatusCode = statusCode
+	return &c
+}
+
+// WithState returns a copy of the error with the state of the authorization request
+func (e *OAuthError) WithState(state string) *OAuthError {
+	c := *e
+	c.State = state
+	return &c
+}
+
+// WithCause returns a copy of the error wrapping the underlying error
+func (e *OAuthError) WithCause(cause error) *OAuthError {
+	c := *e
+	c.Cause = cause
+	return &c
+}
@@ -0,0 +1,56 @@
+package errors_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/go-oauth2/oauth2/v4/errors"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestOAuthError(t *testing.T) {
+	Convey("Test OAuth error", t, func() {
+		cause := fmt.Errorf("database unavailable")
+		err := errors.NewOAuthError(errors.ErrTemporarilyUnavailable, "").WithCause(cause)
+		So(err.Error(), ShouldEqual, "temporarily_unavailable: database unavailable")
+		So(errors.Is(err, errors.ErrTemporarilyUnavailable), ShouldBeTrue)
+		So(errors.Is(err, cause), ShouldBeTrue)
+
+		var oe *errors.OAuthError
+		So(errors.As(fmt.Errorf("wrapped: %w", err), &oe), ShouldBeTrue)
+		re := oe.Response()
+		So(re.Error, ShouldEqual, errors.ErrTemporarilyUnavailable)
+		So(re.Description, ShouldEqual, errors.Descriptions[errors.ErrTemporarilyUnavailable])
+		So(re.StatusCode, ShouldEqual, 503)
+
+		Convey("The invalid_request errors keep their own description", func() {
+			So(errors.Is(errors.ErrCodeChallengeRquired, errors.ErrInvalidRequest), ShouldBeTrue)
+			So(errors.As(errors.ErrInvalidCodeChallengeLen, &oe), ShouldBeTrue)
+			re := oe.Response()
+			So(re.Error, ShouldEqual, errors.ErrInvalidRequest)
+			So(re.Description, ShouldStartWith, "Code challenge length")
+			So(re.StatusCode, ShouldEqual, 400)
+		})
+
+		Convey("The invalid_request errors are still in the maps", func() {
+			for _, err := range []error{errors.ErrCodeChallengeRquired, errors.ErrUnsupportedCodeChallengeMethod, errors.ErrInvalidCodeChallengeLen} {
+				So(errors.Descriptions[err], ShouldNotBeEmpty)
+				So(errors.StatusCodes[err], ShouldEqual, 400)
+				So(errors.RFCStatusCodes[err], ShouldEqual, 400)
+			}
+		})
+
+		Convey("The unknown error codes are bad requests", func() {
+			re := errors.NewOAuthError(errors.New("custom_error"), "").Response()
+			So(re.Error.Error(), ShouldEqual, "custom_error")
+			So(re.StatusCode, ShouldEqual, 400)
+		})
+
+		Convey("The copies don't modify the original error", func() {
+			v := errors.ErrCodeChallengeRquired.(*errors.OAuthError).WithState("xyz")
+			So(v.State, ShouldEqual, "xyz")
+			So(errors.ErrCodeChallengeRquired.(*errors.OAuthError).State, ShouldBeEmpty)
+		})
+	})
+}
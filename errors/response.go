@@ -34,19 +34,44 @@ func (r *Response) SetHeader(key, value string) {
 
 // https://tools.ietf.org/html/rfc6749#section-5.2
 var (
-	ErrInvalidRequest                 = errors.New("invalid_request")
-	ErrUnauthorizedClient             = errors.New("unauthorized_client")
-	ErrAccessDenied                   = errors.New("access_denied")
-	ErrUnsupportedResponseType        = errors.New("unsupported_response_type")
-	ErrInvalidScope                   = errors.New("invalid_scope")
-	ErrServerError                    = errors.New("server_error")
-	ErrTemporarilyUnavailable         = errors.New("temporarily_unavailable")
-	ErrInvalidClient                  = errors.New("invalid_client")
-	ErrInvalidGrant                   = errors.New("invalid_grant")
-	ErrUnsupportedGrantType           = errors.New("unsupported_grant_type")
-	ErrCodeChallengeRquired           = errors.New("invalid_request")
-	ErrUnsupportedCodeChallengeMethod = errors.New("invalid_request")
-	ErrInvalidCodeChallengeLen        = errors.New("invalid_request")
+	ErrInvalidRequest          = errors.New("invalid_request")
+	ErrUnauthorizedClient      = errors.New("unauthorized_client")
+	ErrAccessDenied            = errors.New("access_denied")
+	ErrUnsupportedResponseType = errors.New("unsupported_response_type")
+	ErrInvalidScope            = errors.New("invalid_scope")
+	ErrServerError             = errors.New("server_error")
+	ErrTemporarilyUnavailable  = errors.New("temporarily_unavailable")
+	ErrInvalidClient           = errors.New("invalid_client")
+	ErrInvalidGrant            = errors.New("invalid_grant")
+	ErrUnsupportedGrantType    = errors.New("unsupported_grant_type")
+)
+
+// the invalid_request errors with their own description
+var (
+	ErrCodeChallengeRquired           error = NewOAuthError(ErrInvalidRequest, "PKCE is required. code_challenge is missing")
+	ErrUnsupportedCodeChallengeMethod error = NewOAuthError(ErrInvalidRequest, "Selected code_challenge_method not supported")
+	ErrInvalidCodeChallengeLen        error = NewOAuthError(ErrInvalidRequest, "Code challenge length must be between 43 and 128 charachters long")
+)
+
+// https://tools.ietf.org/html/rfc8707#section-2
+var (
+	ErrInvalidTarget = errors.New("invalid_target")
+)
+
+// https://tools.ietf.org/html/rfc6750#section-3.1
+var (
+	ErrInvalidToken      = errors.New("invalid_token")
+	ErrInsufficientScope = errors.New("insufficient_scope")
+)
+
+// the token request rate limit is exceeded, the temporarily_unavailable error with the 429 status code
+var (
+	ErrRateLimited error = NewOAuthError(ErrTemporarilyUnavailable, "Too many requests, retry after the delay given by the Retry-After header").WithStatusCode(http.StatusTooManyRequests)
+)
+
+// https://tools.ietf.org/html/rfc9396#section-5
+var (
+	ErrInvalidAuthorizationDetails = errors.New("invalid_authorization_details")
 )
 
 // Descriptions error description
@@ -64,6 +89,10 @@ var Descriptions = map[error]string{
 	ErrCodeChallengeRquired:           "PKCE is required. code_challenge is missing",
 	ErrUnsupportedCodeChallengeMethod: "Selected code_challenge_method not supported",
 	ErrInvalidCodeChallengeLen:        "Code challenge length must be between 43 and 128 charachters long",
+	ErrInvalidTarget:                  "The requested resource is invalid, missing, unknown, or malformed",
+	ErrInvalidAuthorizationDetails:    "The authorization details are invalid, unknown, or malformed",
+	ErrInvalidToken:                   "The access token provided is expired, revoked, malformed, or invalid for other reasons",
+	ErrInsufficientScope:              "The request requires higher privileges than provided by the access token",
 }
 
 // StatusCodes response error HTTP status code
@@ -81,4 +110,30 @@ var StatusCodes = map[error]int{
 	ErrCodeChallengeRquired:           400,
 	ErrUnsupportedCodeChallengeMethod: 400,
 	ErrInvalidCodeChallengeLen:        400,
+	ErrInvalidTarget:                  400,
+	ErrInvalidAuthorizationDetails:    400,
+	ErrInvalidToken:                   401,
+	ErrInsufficientScope:              403,
+}
+
+// RFCStatusCodes the error HTTP status codes of RFC 6749 section 5.2 and RFC 6750 section 3.1,
+// StatusCodes keeps the legacy status codes for compatibility
+var RFCStatusCodes = map[error]int{
+	ErrInvalidRequest:                 400,
+	ErrUnauthorizedClient:             400,
+	ErrAccessDenied:                   403,
+	ErrUnsupportedResponseType:        400,
+	ErrInvalidScope:                   400,
+	ErrServerError:                    500,
+	ErrTemporarilyUnavailable:         503,
+	ErrInvalidClient:                  401,
+	ErrInvalidGrant:                   400,
+	ErrUnsupportedGrantType:           400,
+	ErrCodeChallengeRquired:           400,
+	ErrUnsupportedCodeChallengeMethod: 400,
+	ErrInvalidCodeChallengeLen:        400,
+	ErrInvalidTarget:                  400,
+	ErrInvalidAuthorizationDetails:    400,
+	ErrInvalidToken:                   401,
+	ErrInsufficientScope:              403,
 }
@@ -0,0 +1,59 @@
+package oauth2
+
+import (
+	"context"
+	"time"
+)
+
+// EventType the type of the grant lifecycle event
+type EventType string
+
+// define the grant lifecycle events
+const (
+	EventCodeIssued       EventType = "code_issued"
+	EventCodeRedeemed     EventType = "code_redeemed"
+	EventTokenIssued      EventType = "token_issued"
+	EventRefreshRotated   EventType = "refresh_rotated"
+	EventTokenRevoked     EventType = "token_revoked"
+	EventClientAuthFailed EventType = "client_auth_failed"
+	EventPKCEFailed       EventType = "pkce_failed"
+	EventRateLimited      EventType = "rate_limited"
+)
+
+// Event the grant lifecycle event, it never carries the token values
+type Event struct {
+	Type          EventType `json:"type"`
+	Time          time.Time `json:"time"`
+	ClientID      string    `json:"client_id,omitempty"`
+	UserID        string    `json:"user_id,omitempty"`
+	GrantType     GrantType `json:"grant_type,omitempty"`
+	IP            string    `json:"ip,omitempty"`
+	CorrelationID string    `json:"correlation_id,omitempty"`
+	Error         string    `json:"error,omitempty"`
+}
+
+// EventSubscriber receive the grant lifecycle events, it is called synchronously
+type EventSubscriber interface {
+	HandleEvent(ctx context.Context, e *Event)
+}
+
+// EventSubscriberFunc the function adapter of the event subscriber
+type EventSubscriberFunc func(ctx context.Context, e *Event)
+
+// HandleEvent call f(ctx, e)
+func (f EventSubscriberFunc) HandleEvent(ctx context.Context, e *Event) {
+	f(ctx, e)
+}
+
+type correlationIDKey struct{}
+
+// WithCorrelationID returns the context carrying the correlation ID of the events
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationIDFromContext get the correlation ID of the events from the context
+func CorrelationIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
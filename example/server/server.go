@@ -64,6 +64,13 @@ func main() {
 
 	srv := server.NewServer(server.NewConfig(), manager)
 
+	issuer := fmt.Sprintf("http://localhost:%d", portvar)
+	srv.SetIssuer(issuer)
+	srv.SetMetadata(map[string]interface{}{
+		"authorization_endpoint": issuer + "/oauth/authorize",
+		"token_endpoint":         issuer + "/oauth/token",
+	})
+
 	srv.SetPasswordAuthorizationHandler(func(ctx context.Context, clientID, username, password string) (userID string, err error) {
 		if username == "test" && password == "test" {
 			userID = "test"
@@ -124,13 +131,19 @@ func main() {
 		}
 	})
 
+	http.HandleFunc("/.well-known/oauth-authorization-server", func(w http.ResponseWriter, r *http.Request) {
+		if err := srv.HandleMetadataRequest(w, r); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+
 	http.HandleFunc("/test", func(w http.ResponseWriter, r *http.Request) {
 		if dumpvar {
 			_ = dumpRequest(os.Stdout, "test", r) // Ignore the error
 		}
 		token, err := srv.ValidationBearerToken(r)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			_ = server.WriteResourceError(w, err)
 			return
 		}
 
@@ -26,3 +26,17 @@ type (
 		Token(ctx context.Context, data *GenerateBasic, isGenRefresh bool) (access, refresh string, err error)
 	}
 )
+
+type issuerKey struct{}
+
+// WithIssuer returns the context carrying the issuer identifier of the authorization server,
+// the token generators use it when they have no issuer of their own
+func WithIssuer(ctx context.Context, issuer string) context.Context {
+	return context.WithValue(ctx, issuerKey{}, issuer)
+}
+
+// IssuerFromContext get the issuer identifier of the authorization server from the context
+func IssuerFromContext(ctx context.Context) string {
+	issuer, _ := ctx.Value(issuerKey{}).(string)
+	return issuer
+}
@@ -15,6 +15,9 @@ import (
 // JWTAccessClaims jwt claims
 type JWTAccessClaims struct {
 	jwt.RegisteredClaims
+	ClientID             string                       `json:"client_id,omitempty"`
+	Scope                string                       `json:"scope,omitempty"`
+	AuthorizationDetails []oauth2.AuthorizationDetail `json:"authorization_details,omitempty"`
 }
 
 // Valid claims verification
@@ -39,16 +42,35 @@ type JWTAccessGenerate struct {
 	SignedKeyID  string
 	SignedKey    []byte
 	SignedMethod jwt.SigningMethod
+	// the iss claim, the issuer identifier of the authorization server,
+	// the issuer set with server.SetIssuer is used if empty
+	Issuer string
 }
 
 // Token based on the UUID generated token
 func (a *JWTAccessGenerate) Token(ctx context.Context, data *oauth2.GenerateBasic, isGenRefresh bool) (string, string, error) {
+	// the token is restricted to the requested resource servers (RFC 8707)
+	audience := jwt.ClaimStrings{data.Client.GetID()}
+	if rti, ok := data.TokenInfo.(oauth2.ResourceTokenInfo); ok && len(rti.GetResource()) > 0 {
+		audience = rti.GetResource()
+	}
+	issuer := a.Issuer
+	if issuer == "" {
+		issuer = oauth2.IssuerFromContext(ctx)
+	}
 	claims := &JWTAccessClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			Audience:  jwt.ClaimStrings{data.Client.GetID()},
+			Issuer:    issuer,
+			Audience:  audience,
 			Subject:   data.UserID,
+			IssuedAt:  jwt.NewNumericDate(data.TokenInfo.GetAccessCreateAt()),
 			ExpiresAt: jwt.NewNumericDate(data.TokenInfo.GetAccessCreateAt().Add(data.TokenInfo.GetAccessExpiresIn())),
 		},
+		ClientID: data.Client.GetID(),
+		Scope:    data.TokenInfo.GetScope().String(),
+	}
+	if dti, ok := data.TokenInfo.(oauth2.AuthorizationDetailsTokenInfo); ok {
+		claims.AuthorizationDetails = dti.GetAuthorizationDetails()
 	}
 
 	token := jwt.NewWithClaims(a.SignedMethod, claims)
@@ -23,6 +23,7 @@ func TestJWTAccess(t *testing.T) {
 			},
 			UserID: "000000",
 			TokenInfo: &models.Token{
+				Scope:           "read write",
 				AccessCreateAt:  time.Now(),
 				AccessExpiresIn: time.Second * 120,
 			},
@@ -50,5 +51,47 @@ func TestJWTAccess(t *testing.T) {
 		So(len(aud), ShouldEqual, 1)
 		So(aud[0], ShouldEqual, "123456")
 		So(claims.Subject, ShouldEqual, "000000")
+		So(claims.ClientID, ShouldEqual, "123456")
+		So(claims.Scope, ShouldEqual, "read write")
+		So(claims.IssuedAt, ShouldNotBeNil)
+
+		Convey("Test resource audience and authorization details", func() {
+			data.TokenInfo.(*models.Token).Resource = []string{"https://api.example.com", "https://files.example.com"}
+			data.TokenInfo.(*models.Token).AuthorizationDetails = []oauth2.AuthorizationDetail{{"type": "payment_initiation"}}
+			access, _, err := gen.Token(context.Background(), data, false)
+			So(err, ShouldBeNil)
+
+			claims := &generates.JWTAccessClaims{}
+			_, err = jwt.ParseWithClaims(access, claims, func(t *jwt.Token) (interface{}, error) {
+				return []byte("00000000"), nil
+			})
+			So(err, ShouldBeNil)
+			So([]string(claims.Audience), ShouldResemble, []string{"https://api.example.com", "https://files.example.com"})
+			So(claims.ClientID, ShouldEqual, "123456")
+			So(len(claims.AuthorizationDetails), ShouldEqual, 1)
+			So(claims.AuthorizationDetails[0].Type(), ShouldEqual, "payment_initiation")
+		})
+
+		Convey("Test issuer", func() {
+			So(claims.Issuer, ShouldBeEmpty)
+
+			// the issuer of the server is used by default
+			access, _, err := gen.Token(oauth2.WithIssuer(context.Background(), "https://server.example.com"), data, false)
+			So(err, ShouldBeNil)
+			_, err = jwt.ParseWithClaims(access, &generates.JWTAccessClaims{}, func(t *jwt.Token) (interface{}, error) {
+				return []byte("00000000"), nil
+			}, jwt.WithIssuer("https://server.example.com"))
+			So(err, ShouldBeNil)
+
+			gen.Issuer = "https://as.example.com"
+			access, _, err = gen.Token(oauth2.WithIssuer(context.Background(), "https://server.example.com"), data, false)
+			So(err, ShouldBeNil)
+
+			claims := &generates.JWTAccessClaims{}
+			_, err = jwt.ParseWithClaims(access, claims, func(t *jwt.Token) (interface{}, error) {
+				return []byte("00000000"), nil
+			}, jwt.WithIssuer("https://as.example.com"))
+			So(err, ShouldBeNil)
+		})
 	})
 }
@@ -0,0 +1,197 @@
+package generates
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/errors"
+)
+
+// opaqueVersion the prefix of the opaque token format
+const opaqueVersion = "v1."
+
+// the additional data binding the opaque token to its type
+var (
+	opaqueAccessAD  = []byte(opaqueVersion + "access")
+	opaqueRefreshAD = []byte(opaqueVersion + "refresh")
+)
+
+// opaquePayload the token information sealed into the opaque tokens
+type opaquePayload struct {
+	// the ID shared by the access and refresh tokens generated together
+	ID               string                       `json:"jti,omitempty"`
+	ClientID         string                       `json:"cid"`
+	UserID           string                       `json:"uid,omitempty"`
+	RedirectURI      string                       `json:"ruri,omitempty"`
+	Scope            string                       `json:"scp,omitempty"`
+	AccessCreateAt   time.Time                    `json:"aiat"`
+	AccessExpiresIn  time.Duration                `json:"aexp"`
+	RefreshCreateAt  time.Time                    `json:"riat"`
+	RefreshExpiresIn time.Duration                `json:"rexp,omitempty"`
+	Extension        url.Values                   `json:"ext,omitempty"`
+	Resource         []string                     `json:"aud,omitempty"`
+	GrantedResource  []string                     `json:"gaud,omitempty"`
+	Details          []oauth2.AuthorizationDetail `json:"authz,omitempty"`
+}
+
+// NewOpaqueAccessGenerate create to generate the self-contained encrypted token instance,
+// key is the AES key (16, 24 or 32 bytes)
+func NewOpaqueAccessGenerate(key []byte) (*OpaqueAccessGenerate, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &OpaqueAccessGenerate{aead: aead}, nil
+}
+
+// OpaqueAccessGenerate generate the access and refresh tokens as the AES-GCM encrypted token information,
+// so they can be loaded without a database (see store.OpaqueTokenStore) while staying opaque to the clients
+type OpaqueAccessGenerate struct {
+	aead cipher.AEAD
+}
+
+// Token based on the encrypted token information
+func (g *OpaqueAccessGenerate) Token(ctx context.Context, data *oauth2.GenerateBasic, isGenRefresh bool) (string, string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", err
+	}
+	ti := data.TokenInfo
+	payload := &opaquePayload{
+		ID:               base64.RawURLEncoding.EncodeToString(id),
+		ClientID:         ti.GetClientID(),
+		UserID:           ti.GetUserID(),
+		RedirectURI:      ti.GetRedirectURI(),
+		Scope:            ti.GetScope().String(),
+		AccessCreateAt:   ti.GetAccessCreateAt(),
+		AccessExpiresIn:  ti.GetAccessExpiresIn(),
+		RefreshCreateAt:  ti.GetRefreshCreateAt(),
+		RefreshExpiresIn: ti.GetRefreshExpiresIn(),
+	}
+	if eti, ok := ti.(oauth2.ExtendableTokenInfo); ok {
+		payload.Extension = eti.GetExtension()
+	}
+	if rti, ok := ti.(oauth2.ResourceTokenInfo); ok {
+		payload.Resource = rti.GetResource()
+		payload.GrantedResource = rti.GetGrantedResource()
+	}
+	if dti, ok := ti.(oauth2.AuthorizationDetailsTokenInfo); ok {
+		payload.Details = dti.GetAuthorizationDetails()
+	}
+
+	access, err := g.seal(payload, opaqueAccessAD)
+	if err != nil {
+		return "", "", err
+	}
+	refresh := ""
+	if isGenRefresh {
+		refresh, err = g.seal(payload, opaqueRefreshAD)
+		if err != nil {
+			return "", "", err
+		}
+	}
+	return access, refresh, nil
+}
+
+func (g *OpaqueAccessGenerate) seal(payload *opaquePayload, ad []byte) (string, error) {
+	jv, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, g.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return opaqueVersion + base64.RawURLEncoding.EncodeToString(g.aead.Seal(nonce, nonce, jv, ad)), nil
+}
+
+func (g *OpaqueAccessGenerate) open(token string, ad []byte) (*opaquePayload, bool) {
+	if !strings.HasPrefix(token, opaqueVersion) {
+		return nil, false
+	}
+	buf, err := base64.RawURLEncoding.DecodeString(token[len(opaqueVersion):])
+	if err != nil || len(buf) < g.aead.NonceSize() {
+		return nil, false
+	}
+	jv, err := g.aead.Open(nil, buf[:g.aead.NonceSize()], buf[g.aead.NonceSize():], ad)
+	if err != nil {
+		return nil, false
+	}
+	var payload opaquePayload
+	if err := json.Unmarshal(jv, &payload); err != nil {
+		return nil, false
+	}
+	return &payload, true
+}
+
+func (p *opaquePayload) decode(ti oauth2.TokenInfo) {
+	ti.SetClientID(p.ClientID)
+	ti.SetUserID(p.UserID)
+	ti.SetRedirectURI(p.RedirectURI)
+	ti.SetScope(oauth2.ParseScope(p.Scope))
+	ti.SetAccessCreateAt(p.AccessCreateAt)
+	ti.SetAccessExpiresIn(p.AccessExpiresIn)
+	ti.SetRefreshCreateAt(p.RefreshCreateAt)
+	ti.SetRefreshExpiresIn(p.RefreshExpiresIn)
+	if eti, ok := ti.(oauth2.ExtendableTokenInfo); ok && p.Extension != nil {
+		eti.SetExtension(p.Extension)
+	}
+	if rti, ok := ti.(oauth2.ResourceTokenInfo); ok && p.Resource != nil {
+		rti.SetResource(p.Resource)
+	}
+	if rti, ok := ti.(oauth2.ResourceTokenInfo); ok && p.GrantedResource != nil {
+		rti.SetGrantedResource(p.GrantedResource)
+	}
+	if dti, ok := ti.(oauth2.AuthorizationDetailsTokenInfo); ok && p.Details != nil {
+		dti.SetAuthorizationDetails(p.Details)
+	}
+}
+
+// TokenID get the ID shared by the access and refresh tokens generated together,
+// the tokens generated without an ID are identified by themselves
+func (g *OpaqueAccessGenerate) TokenID(token string) (string, bool) {
+	payload, ok := g.open(token, opaqueAccessAD)
+	if !ok {
+		payload, ok = g.open(token, opaqueRefreshAD)
+	}
+	if !ok {
+		return "", false
+	} else if payload.ID == "" {
+		return token, true
+	}
+	return payload.ID, true
+}
+
+// DecodeAccess decrypt the access token into the token information
+func (g *OpaqueAccessGenerate) DecodeAccess(access string, ti oauth2.TokenInfo) error {
+	payload, ok := g.open(access, opaqueAccessAD)
+	if !ok {
+		return errors.ErrInvalidAccessToken
+	}
+	payload.decode(ti)
+	ti.SetAccess(access)
+	return nil
+}
+
+// DecodeRefresh decrypt the refresh token into the token information
+func (g *OpaqueAccessGenerate) DecodeRefresh(refresh string, ti oauth2.TokenInfo) error {
+	payload, ok := g.open(refresh, opaqueRefreshAD)
+	if !ok {
+		return errors.ErrInvalidRefreshToken
+	}
+	payload.decode(ti)
+	ti.SetRefresh(refresh)
+	return nil
+}
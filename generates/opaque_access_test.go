@@ -0,0 +1,70 @@
+package generates_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/generates"
+	"github.com/go-oauth2/oauth2/v4/models"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestOpaqueAccess(t *testing.T) {
+	Convey("Test Opaque Access Generate", t, func() {
+		data := &oauth2.GenerateBasic{
+			Client: &models.Client{
+				ID:     "123456",
+				Secret: "123456",
+			},
+			UserID: "000000",
+			TokenInfo: &models.Token{
+				ClientID:         "123456",
+				UserID:           "000000",
+				Scope:            "all",
+				AccessCreateAt:   time.Now(),
+				AccessExpiresIn:  time.Second * 120,
+				RefreshCreateAt:  time.Now(),
+				RefreshExpiresIn: time.Hour,
+			},
+		}
+
+		gen, err := generates.NewOpaqueAccessGenerate([]byte("0123456789abcdef"))
+		So(err, ShouldBeNil)
+		access, refresh, err := gen.Token(context.Background(), data, true)
+		So(err, ShouldBeNil)
+		So(access, ShouldNotBeEmpty)
+		So(refresh, ShouldNotBeEmpty)
+		So(access, ShouldNotContainSubstring, "000000")
+
+		ti := models.NewToken()
+		err = gen.DecodeAccess(access, ti)
+		So(err, ShouldBeNil)
+		So(ti.GetAccess(), ShouldEqual, access)
+		So(ti.GetClientID(), ShouldEqual, "123456")
+		So(ti.GetUserID(), ShouldEqual, "000000")
+		So(ti.GetScope().String(), ShouldEqual, "all")
+		So(ti.GetAccessExpiresIn(), ShouldEqual, time.Second*120)
+
+		ti = models.NewToken()
+		err = gen.DecodeRefresh(refresh, ti)
+		So(err, ShouldBeNil)
+		So(ti.GetRefresh(), ShouldEqual, refresh)
+		So(ti.GetRefreshExpiresIn(), ShouldEqual, time.Hour)
+
+		// the token types can't be swapped
+		So(gen.DecodeAccess(refresh, models.NewToken()), ShouldNotBeNil)
+		So(gen.DecodeRefresh(access, models.NewToken()), ShouldNotBeNil)
+
+		// tampered tokens are rejected
+		tampered := []byte(access)
+		tampered[len(tampered)-2] ^= 1
+		So(gen.DecodeAccess(string(tampered), models.NewToken()), ShouldNotBeNil)
+
+		other, err := generates.NewOpaqueAccessGenerate([]byte("fedcba9876543210"))
+		So(err, ShouldBeNil)
+		So(other.DecodeAccess(access, models.NewToken()), ShouldNotBeNil)
+	})
+}
@@ -8,18 +8,22 @@ import (
 
 // TokenGenerateRequest provide to generate the token request parameters
 type TokenGenerateRequest struct {
-	ClientID            string
-	ClientSecret        string
-	UserID              string
-	RedirectURI         string
-	Scope               string
-	Code                string
-	CodeChallenge       string
-	CodeChallengeMethod CodeChallengeMethod
-	Refresh             string
-	CodeVerifier        string
-	AccessTokenExp      time.Duration
-	Request             *http.Request
+	ClientID             string
+	ClientSecret         string
+	UserID               string
+	RedirectURI          string
+	Scope                Scope
+	Code                 string
+	CodeChallenge        string
+	CodeChallengeMethod  CodeChallengeMethod
+	Refresh              string
+	CodeVerifier         string
+	AccessTokenExp       time.Duration
+	Resource             []string
+	AuthorizationDetails []AuthorizationDetail
+	RotateRefresh        bool // issue a new refresh token and remove the used one whatever the refreshing config
+	ClientAuthenticated  bool // the client is already authenticated, e.g. with a JWT assertion, its secret isn't verified
+	Request              *http.Request
 }
 
 // Manager authorization management interface
@@ -48,3 +52,15 @@ type Manager interface {
 	// according to the refresh token for corresponding token information
 	LoadRefreshToken(ctx context.Context, refresh string) (ti TokenInfo, err error)
 }
+
+// ConsentManager user consent management interface
+type ConsentManager interface {
+	// get the valid user consent for the client and the requested scope not consented yet
+	CheckConsent(ctx context.Context, userID, clientID string, scope Scope) (ci ConsentInfo, missingScope Scope, err error)
+
+	// add the scope to the user consent for the client
+	GrantConsent(ctx context.Context, userID, clientID string, scope Scope) (ci ConsentInfo, err error)
+
+	// delete the user consent and the tokens issued to the client for the user
+	RevokeConsent(ctx context.Context, userID, clientID string) (err error)
+}
@@ -0,0 +1,87 @@
+package manage
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/errors"
+	"github.com/go-oauth2/oauth2/v4/models"
+)
+
+var errConsentStoreNotSet = errors.New("the consent store isn't mapped")
+
+// loadConsent get the user consent that hasn't expired
+func (m *Manager) loadConsent(ctx context.Context, userID, clientID string) (oauth2.ConsentInfo, error) {
+	if m.consentStore == nil {
+		return nil, errConsentStoreNotSet
+	}
+
+	ci, err := m.consentStore.Get(ctx, userID, clientID)
+	if err != nil || ci == nil {
+		return nil, err
+	} else if exp := ci.GetExpiresIn(); exp != 0 && ci.GetCreateAt().Add(exp).Before(time.Now()) {
+		return nil, nil
+	}
+	return ci, nil
+}
+
+// CheckConsent get the valid user consent for the client and the requested scope not consented yet,
+// the consent is nil when the user hasn't consented or the consent has expired
+func (m *Manager) CheckConsent(ctx context.Context, userID, clientID string, scope oauth2.Scope) (oauth2.ConsentInfo, oauth2.Scope, error) {
+	ci, err := m.loadConsent(ctx, userID, clientID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var granted oauth2.Scope
+	if ci != nil {
+		granted = ci.GetScope()
+	}
+	return ci, scope.Difference(granted), nil
+}
+
+// GrantConsent add the scope to the user consent for the client and renew its expiration
+func (m *Manager) GrantConsent(ctx context.Context, userID, clientID string, scope oauth2.Scope) (oauth2.ConsentInfo, error) {
+	ci, err := m.loadConsent(ctx, userID, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	var granted oauth2.Scope
+	if ci != nil {
+		granted = ci.GetScope()
+	}
+
+	consent := models.NewConsent()
+	consent.SetUserID(userID)
+	consent.SetClientID(clientID)
+	consent.SetScope(granted.Union(scope))
+	consent.SetCreateAt(time.Now())
+	consent.SetExpiresIn(m.consentExp)
+	if err := m.consentStore.Save(ctx, consent); err != nil {
+		return nil, err
+	}
+	return consent, nil
+}
+
+// RevokeConsent delete the user consent and the tokens issued to the client for the user,
+// the token store has to implement oauth2.TokenRevocationStore
+func (m *Manager) RevokeConsent(ctx context.Context, userID, clientID string) error {
+	if m.consentStore == nil {
+		return errConsentStoreNotSet
+	}
+
+	rs, ok := m.tokenStore.(oauth2.TokenRevocationStore)
+	if !ok {
+		return errors.New("the token store doesn't support removing by client and user")
+	}
+	if err := m.consentStore.Remove(ctx, userID, clientID); err != nil {
+		return err
+	}
+	if err := rs.RemoveByClientUser(ctx, clientID, userID); err != nil {
+		return err
+	}
+	m.emit(ctx, nil, &oauth2.Event{Type: oauth2.EventTokenRevoked, ClientID: clientID, UserID: userID})
+	return nil
+}
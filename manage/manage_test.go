@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/errors"
 	"github.com/go-oauth2/oauth2/v4/manage"
 	"github.com/go-oauth2/oauth2/v4/models"
 	"github.com/go-oauth2/oauth2/v4/store"
@@ -32,7 +33,7 @@ func TestManager(t *testing.T) {
 			ClientID:    "1",
 			UserID:      "123456",
 			RedirectURI: "http://localhost/oauth2",
-			Scope:       "all",
+			Scope:       oauth2.ParseScope("all"),
 		}
 
 		Convey("GetClient test", func() {
@@ -53,6 +54,44 @@ func TestManager(t *testing.T) {
 		Convey("zero expiration refresh token test", func() {
 			testZeroRefreshExpirationManager(tgr, manager)
 		})
+
+		Convey("client authentication test", func() {
+			_ = clientStore.Set("2", &models.Client{
+				ID:     "2",
+				Domain: "http://localhost",
+				Public: true,
+			})
+
+			// the confidential clients must send their secret
+			_, err := manager.GenerateAccessToken(ctx, oauth2.ClientCredentials, &oauth2.TokenGenerateRequest{ClientID: "1"})
+			So(errors.Is(err, errors.ErrInvalidClient), ShouldBeTrue)
+
+			// the public clients are identified by the client ID only
+			cti, err := manager.GenerateAuthToken(ctx, oauth2.Code, &oauth2.TokenGenerateRequest{
+				ClientID:    "2",
+				UserID:      "123456",
+				RedirectURI: "http://localhost/oauth2",
+			})
+			So(err, ShouldBeNil)
+			ti, err := manager.GenerateAccessToken(ctx, oauth2.AuthorizationCode, &oauth2.TokenGenerateRequest{
+				ClientID:    "2",
+				RedirectURI: "http://localhost/oauth2",
+				Code:        cti.GetCode(),
+			})
+			So(err, ShouldBeNil)
+			So(ti.GetAccess(), ShouldNotBeEmpty)
+
+			_, err = manager.GenerateAccessToken(ctx, oauth2.ClientCredentials, &oauth2.TokenGenerateRequest{ClientID: "2"})
+			So(errors.Is(err, errors.ErrInvalidClient), ShouldBeTrue)
+
+			// the confidential clients without a registered secret can't authenticate
+			_ = clientStore.Set("3", &models.Client{ID: "3", Domain: "http://localhost"})
+			_, err = manager.GenerateAccessToken(ctx, oauth2.ClientCredentials, &oauth2.TokenGenerateRequest{
+				ClientID:     "3",
+				ClientSecret: "any",
+			})
+			So(errors.Is(err, errors.ErrInvalidClient), ShouldBeTrue)
+		})
 	})
 }
 
@@ -94,8 +133,10 @@ func testManager(tgr *oauth2.TokenGenerateRequest, manager oauth2.Manager) {
 	So(rinfo.GetClientID(), ShouldEqual, atParams.ClientID)
 
 	refreshParams := &oauth2.TokenGenerateRequest{
-		Refresh: refreshToken,
-		Scope:   "owner",
+		ClientID:     atParams.ClientID,
+		ClientSecret: "11",
+		Refresh:      refreshToken,
+		Scope:        oauth2.ParseScope("owner"),
 	}
 	rti, err := manager.RefreshAccessToken(ctx, refreshParams)
 	So(err, ShouldBeNil)
@@ -108,7 +149,7 @@ func testManager(tgr *oauth2.TokenGenerateRequest, manager oauth2.Manager) {
 
 	refreshAInfo, err := manager.LoadAccessToken(ctx, refreshAT)
 	So(err, ShouldBeNil)
-	So(refreshAInfo.GetScope(), ShouldEqual, "owner")
+	So(refreshAInfo.GetScope().String(), ShouldEqual, "owner")
 
 	err = manager.RemoveAccessToken(ctx, refreshAT)
 	So(err, ShouldBeNil)
@@ -232,3 +273,352 @@ func testZeroRefreshExpirationManager(tgr *oauth2.TokenGenerateRequest, manager
 	So(tokenInfo.GetRefresh(), ShouldEqual, refreshToken)
 	So(tokenInfo.GetRefreshExpiresIn(), ShouldEqual, 0)
 }
+
+func TestManagerRehashClientSecret(t *testing.T) {
+	Convey("Manager rehash client secret test", t, func() {
+		ctx := context.Background()
+		manager := manage.NewDefaultManager()
+		manager.MustTokenStorage(store.NewMemoryTokenStore())
+
+		old := &models.BcryptHasher{Cost: 4}
+		hash, err := old.Hash("11")
+		So(err, ShouldBeNil)
+		cli := &models.Client{
+			ID:     "1",
+			Secret: hash,
+			Hasher: &models.BcryptHasher{Cost: 5},
+		}
+		clientStore := store.NewClientStore()
+		_ = clientStore.Set("1", cli)
+		manager.MapClientStorage(clientStore)
+
+		_, err = manager.GenerateAccessToken(ctx, oauth2.ClientCredentials, &oauth2.TokenGenerateRequest{
+			ClientID:     "1",
+			ClientSecret: "12",
+		})
+		So(err, ShouldNotBeNil)
+		So(cli.Secret, ShouldEqual, hash)
+
+		_, err = manager.GenerateAccessToken(ctx, oauth2.ClientCredentials, &oauth2.TokenGenerateRequest{
+			ClientID:     "1",
+			ClientSecret: "11",
+		})
+		So(err, ShouldBeNil)
+		// the stored client is replaced by the rehashed copy
+		So(cli.Secret, ShouldEqual, hash)
+		stored, err := clientStore.GetByID(ctx, "1")
+		So(err, ShouldBeNil)
+		So(stored.GetSecret(), ShouldNotEqual, hash)
+		So(cli.Hasher.NeedsRehash(stored.GetSecret()), ShouldBeFalse)
+
+		_, err = manager.GenerateAccessToken(ctx, oauth2.ClientCredentials, &oauth2.TokenGenerateRequest{
+			ClientID:     "1",
+			ClientSecret: "11",
+		})
+		So(err, ShouldBeNil)
+
+		Convey("the refresh grant verifies and rehashes the secret too", func() {
+			ti, err := manager.GenerateAccessToken(ctx, oauth2.PasswordCredentials, &oauth2.TokenGenerateRequest{
+				ClientID:     "1",
+				ClientSecret: "11",
+				UserID:       "u1",
+			})
+			So(err, ShouldBeNil)
+			_ = clientStore.Set("1", cli)
+
+			_, err = manager.RefreshAccessToken(ctx, &oauth2.TokenGenerateRequest{
+				ClientID:     "1",
+				ClientSecret: "12",
+				Refresh:      ti.GetRefresh(),
+			})
+			So(err, ShouldEqual, errors.ErrInvalidClient)
+
+			_, err = manager.RefreshAccessToken(ctx, &oauth2.TokenGenerateRequest{
+				ClientID:     "1",
+				ClientSecret: "11",
+				Refresh:      ti.GetRefresh(),
+			})
+			So(err, ShouldBeNil)
+			stored, err := clientStore.GetByID(ctx, "1")
+			So(err, ShouldBeNil)
+			So(stored.GetSecret(), ShouldNotEqual, hash)
+		})
+	})
+}
+
+func TestManagerConsent(t *testing.T) {
+	Convey("Manager consent test", t, func() {
+		ctx := context.Background()
+		manager := manage.NewDefaultManager()
+		manager.MustTokenStorage(store.NewMemoryTokenStore())
+		clientStore := store.NewClientStore()
+		_ = clientStore.Set("1", &models.Client{ID: "1", Secret: "11", Domain: "http://localhost"})
+		manager.MapClientStorage(clientStore)
+
+		_, _, err := manager.CheckConsent(ctx, "u1", "1", oauth2.ParseScope("read"))
+		So(err, ShouldNotBeNil)
+
+		manager.MapConsentStorage(store.NewConsentStore())
+
+		ci, missing, err := manager.CheckConsent(ctx, "u1", "1", oauth2.ParseScope("read"))
+		So(err, ShouldBeNil)
+		So(ci, ShouldBeNil)
+		So(missing.String(), ShouldEqual, "read")
+
+		_, err = manager.GrantConsent(ctx, "u1", "1", oauth2.ParseScope("read"))
+		So(err, ShouldBeNil)
+
+		Convey("incremental consent", func() {
+			ci, missing, err := manager.CheckConsent(ctx, "u1", "1", oauth2.ParseScope("read write"))
+			So(err, ShouldBeNil)
+			So(ci.GetScope().String(), ShouldEqual, "read")
+			So(missing.String(), ShouldEqual, "write")
+
+			ci, err = manager.GrantConsent(ctx, "u1", "1", oauth2.ParseScope("write read"))
+			So(err, ShouldBeNil)
+			So(ci.GetScope().String(), ShouldEqual, "read write")
+
+			_, missing, err = manager.CheckConsent(ctx, "u1", "1", oauth2.ParseScope("write"))
+			So(err, ShouldBeNil)
+			So(missing, ShouldBeEmpty)
+		})
+
+		Convey("expired consent", func() {
+			manager.SetConsentExp(time.Millisecond)
+			_, err := manager.GrantConsent(ctx, "u1", "1", oauth2.ParseScope("read"))
+			So(err, ShouldBeNil)
+			time.Sleep(time.Millisecond * 5)
+
+			ci, missing, err := manager.CheckConsent(ctx, "u1", "1", oauth2.ParseScope("read"))
+			So(err, ShouldBeNil)
+			So(ci, ShouldBeNil)
+			So(missing.String(), ShouldEqual, "read")
+		})
+
+		Convey("revoke consent", func() {
+			ti, err := manager.GenerateAccessToken(ctx, oauth2.PasswordCredentials, &oauth2.TokenGenerateRequest{
+				ClientID:     "1",
+				ClientSecret: "11",
+				UserID:       "u1",
+				Scope:        oauth2.ParseScope("read"),
+			})
+			So(err, ShouldBeNil)
+
+			err = manager.RevokeConsent(ctx, "u1", "1")
+			So(err, ShouldBeNil)
+
+			ci, _, err := manager.CheckConsent(ctx, "u1", "1", oauth2.ParseScope("read"))
+			So(err, ShouldBeNil)
+			So(ci, ShouldBeNil)
+
+			_, err = manager.LoadAccessToken(ctx, ti.GetAccess())
+			So(err, ShouldNotBeNil)
+			_, err = manager.LoadRefreshToken(ctx, ti.GetRefresh())
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestManagerResource(t *testing.T) {
+	Convey("Manager resource indicators test", t, func() {
+		ctx := context.Background()
+		manager := manage.NewDefaultManager()
+		manager.MustTokenStorage(store.NewMemoryTokenStore())
+		clientStore := store.NewClientStore()
+		_ = clientStore.Set("1", &models.Client{
+			ID:        "1",
+			Secret:    "11",
+			Domain:    "http://localhost",
+			Resources: []string{"https://api.example.com", "https://files.example.com"},
+		})
+		_ = clientStore.Set("2", &models.Client{ID: "2", Secret: "22", Domain: "http://localhost"})
+		manager.MapClientStorage(clientStore)
+
+		resourceOf := func(ti oauth2.TokenInfo) []string {
+			return ti.(oauth2.ResourceTokenInfo).GetResource()
+		}
+
+		Convey("the resources must be allowed to the client", func() {
+			_, err := manager.GenerateAuthToken(ctx, oauth2.Code, &oauth2.TokenGenerateRequest{
+				ClientID: "1",
+				UserID:   "u1",
+				Resource: []string{"https://other.example.com"},
+			})
+			So(err, ShouldEqual, errors.ErrInvalidTarget)
+
+			_, err = manager.GenerateAccessToken(ctx, oauth2.ClientCredentials, &oauth2.TokenGenerateRequest{
+				ClientID:     "2",
+				ClientSecret: "22",
+				Resource:     []string{"https://api.example.com"},
+			})
+			So(err, ShouldEqual, errors.ErrInvalidTarget)
+
+			_, err = manager.GenerateAccessToken(ctx, oauth2.ClientCredentials, &oauth2.TokenGenerateRequest{
+				ClientID:     "1",
+				ClientSecret: "11",
+				Resource:     []string{"/relative"},
+			})
+			So(err, ShouldEqual, errors.ErrInvalidTarget)
+		})
+
+		Convey("the code resources restrict the token", func() {
+			code := func() string {
+				ti, err := manager.GenerateAuthToken(ctx, oauth2.Code, &oauth2.TokenGenerateRequest{
+					ClientID: "1",
+					UserID:   "u1",
+					Resource: []string{"https://api.example.com", "https://files.example.com"},
+				})
+				So(err, ShouldBeNil)
+				return ti.GetCode()
+			}
+
+			ti, err := manager.GenerateAccessToken(ctx, oauth2.AuthorizationCode, &oauth2.TokenGenerateRequest{
+				ClientID:     "1",
+				ClientSecret: "11",
+				Code:         code(),
+			})
+			So(err, ShouldBeNil)
+			So(resourceOf(ti), ShouldResemble, []string{"https://api.example.com", "https://files.example.com"})
+
+			ti, err = manager.GenerateAccessToken(ctx, oauth2.AuthorizationCode, &oauth2.TokenGenerateRequest{
+				ClientID:     "1",
+				ClientSecret: "11",
+				Code:         code(),
+				Resource:     []string{"https://files.example.com"},
+			})
+			So(err, ShouldBeNil)
+			So(resourceOf(ti), ShouldResemble, []string{"https://files.example.com"})
+
+			So(ti.(oauth2.ResourceTokenInfo).GetGrantedResource(), ShouldResemble, []string{"https://api.example.com", "https://files.example.com"})
+
+			Convey("refreshing downscopes the access token resources only", func() {
+				_, err := manager.RefreshAccessToken(ctx, &oauth2.TokenGenerateRequest{
+					ClientID:     "1",
+					ClientSecret: "11",
+					Refresh:      ti.GetRefresh(),
+					Resource:     []string{"https://other.example.com"},
+				})
+				So(err, ShouldEqual, errors.ErrInvalidTarget)
+
+				rti, err := manager.RefreshAccessToken(ctx, &oauth2.TokenGenerateRequest{
+					ClientID:     "1",
+					ClientSecret: "11",
+					Refresh:      ti.GetRefresh(),
+					Resource:     []string{"https://api.example.com"},
+				})
+				So(err, ShouldBeNil)
+				So(resourceOf(rti), ShouldResemble, []string{"https://api.example.com"})
+
+				// the refresh token is still bound to the resources of the grant
+				rti, err = manager.RefreshAccessToken(ctx, &oauth2.TokenGenerateRequest{
+					ClientID:     "1",
+					ClientSecret: "11",
+					Refresh:      rti.GetRefresh(),
+					Resource:     []string{"https://files.example.com"},
+				})
+				So(err, ShouldBeNil)
+				So(resourceOf(rti), ShouldResemble, []string{"https://files.example.com"})
+
+				rti, err = manager.RefreshAccessToken(ctx, &oauth2.TokenGenerateRequest{
+					ClientID:     "1",
+					ClientSecret: "11",
+					Refresh:      rti.GetRefresh(),
+				})
+				So(err, ShouldBeNil)
+				So(resourceOf(rti), ShouldResemble, []string{"https://api.example.com", "https://files.example.com"})
+			})
+
+			_, err = manager.GenerateAccessToken(ctx, oauth2.AuthorizationCode, &oauth2.TokenGenerateRequest{
+				ClientID:     "1",
+				ClientSecret: "11",
+				Code:         code(),
+				Resource:     []string{"https://other.example.com"},
+			})
+			So(err, ShouldEqual, errors.ErrInvalidTarget)
+		})
+	})
+}
+
+func TestManagerEvents(t *testing.T) {
+	Convey("Manager events test", t, func() {
+		ctx := oauth2.WithCorrelationID(context.Background(), "req-1")
+		manager := manage.NewDefaultManager()
+		manager.MustTokenStorage(store.NewMemoryTokenStore())
+		clientStore := store.NewClientStore()
+		_ = clientStore.Set("1", &models.Client{ID: "1", Secret: "11", Domain: "http://localhost"})
+		manager.MapClientStorage(clientStore)
+
+		var events []*oauth2.Event
+		manager.Subscribe(oauth2.EventSubscriberFunc(func(ctx context.Context, e *oauth2.Event) {
+			events = append(events, e)
+		}))
+		types := func() []oauth2.EventType {
+			var v []oauth2.EventType
+			for _, e := range events {
+				v = append(v, e.Type)
+			}
+			events = nil
+			return v
+		}
+
+		code, err := manager.GenerateAuthToken(ctx, oauth2.Code, &oauth2.TokenGenerateRequest{
+			ClientID:      "1",
+			UserID:        "u1",
+			CodeChallenge: "ThisIsAFourtyThreeCharactersLongStringThing",
+		})
+		So(err, ShouldBeNil)
+		So(events[0].CorrelationID, ShouldEqual, "req-1")
+		So(events[0].UserID, ShouldEqual, "u1")
+		So(types(), ShouldResemble, []oauth2.EventType{oauth2.EventCodeIssued})
+
+		_, err = manager.GenerateAccessToken(ctx, oauth2.AuthorizationCode, &oauth2.TokenGenerateRequest{
+			ClientID:     "1",
+			ClientSecret: "11",
+			Code:         code.GetCode(),
+			CodeVerifier: "invalid",
+		})
+		So(err, ShouldNotBeNil)
+		So(types(), ShouldResemble, []oauth2.EventType{oauth2.EventPKCEFailed})
+
+		code, err = manager.GenerateAuthToken(ctx, oauth2.Code, &oauth2.TokenGenerateRequest{
+			ClientID:      "1",
+			UserID:        "u1",
+			CodeChallenge: "ThisIsAFourtyThreeCharactersLongStringThing",
+		})
+		So(err, ShouldBeNil)
+		_ = types()
+		_, err = manager.GenerateAccessToken(ctx, oauth2.AuthorizationCode, &oauth2.TokenGenerateRequest{
+			ClientID:     "1",
+			ClientSecret: "11",
+			Code:         code.GetCode(),
+			CodeVerifier: "ThisIsAFourtyThreeCharactersLongStringThing",
+		})
+		So(err, ShouldBeNil)
+		So(types(), ShouldResemble, []oauth2.EventType{oauth2.EventCodeRedeemed, oauth2.EventTokenIssued})
+
+		_, err = manager.GenerateAccessToken(ctx, oauth2.ClientCredentials, &oauth2.TokenGenerateRequest{
+			ClientID:     "1",
+			ClientSecret: "12",
+		})
+		So(err, ShouldNotBeNil)
+		So(types(), ShouldResemble, []oauth2.EventType{oauth2.EventClientAuthFailed})
+
+		ti, err := manager.GenerateAccessToken(ctx, oauth2.PasswordCredentials, &oauth2.TokenGenerateRequest{
+			ClientID:     "1",
+			ClientSecret: "11",
+			UserID:       "u1",
+		})
+		So(err, ShouldBeNil)
+		So(events[0].GrantType, ShouldEqual, oauth2.PasswordCredentials)
+		So(types(), ShouldResemble, []oauth2.EventType{oauth2.EventTokenIssued})
+
+		rti, err := manager.RefreshAccessToken(ctx, &oauth2.TokenGenerateRequest{ClientID: "1", ClientSecret: "11", Refresh: ti.GetRefresh()})
+		So(err, ShouldBeNil)
+		So(types(), ShouldResemble, []oauth2.EventType{oauth2.EventRefreshRotated, oauth2.EventTokenIssued})
+
+		err = manager.RemoveAccessToken(ctx, rti.GetAccess())
+		So(err, ShouldBeNil)
+		So(events[0].UserID, ShouldEqual, "u1")
+		So(types(), ShouldResemble, []oauth2.EventType{oauth2.EventTokenRevoked})
+	})
+}
@@ -2,6 +2,8 @@ package manage
 
 import (
 	"context"
+	"crypto/subtle"
+	"net/http"
 	"net/url"
 	"time"
 
@@ -40,6 +42,9 @@ type Manager struct {
 	accessGenerate    oauth2.AccessGenerate
 	tokenStore        oauth2.TokenStore
 	clientStore       oauth2.ClientStore
+	consentStore      oauth2.ConsentStore
+	consentExp        time.Duration
+	subscribers       []oauth2.EventSubscriber
 }
 
 // get grant type config
@@ -57,7 +62,7 @@ func (m *Manager) grantConfig(gt oauth2.GrantType) *Config {
 	case oauth2.ClientCredentials:
 		return DefaultClientTokenCfg
 	}
-	return &Config{}
+	return DefaultExtensionTokenCfg
 }
 
 // SetAuthorizeCodeExp set the authorization code expiration time
@@ -85,6 +90,11 @@ func (m *Manager) SetClientTokenCfg(cfg *Config) {
 	m.gtcfg[oauth2.ClientCredentials] = cfg
 }
 
+// SetGrantTokenCfg set the token config of the grant type, e.g. of an extension grant
+func (m *Manager) SetGrantTokenCfg(gt oauth2.GrantType, cfg *Config) {
+	m.gtcfg[gt] = cfg
+}
+
 // SetRefreshTokenCfg set the refreshing token config
 func (m *Manager) SetRefreshTokenCfg(cfg *RefreshingConfig) {
 	m.rcfg = cfg
@@ -136,6 +146,69 @@ func (m *Manager) MustTokenStorage(stor oauth2.TokenStore, err error) {
 	m.tokenStore = stor
 }
 
+// MapConsentStorage mapping the user consent store interface
+func (m *Manager) MapConsentStorage(stor oauth2.ConsentStore) {
+	m.consentStore = stor
+}
+
+// SetConsentExp set the user consent expiration time, 0 means the consent doesn't expire
+func (m *Manager) SetConsentExp(exp time.Duration) {
+	m.consentExp = exp
+}
+
+// Subscribe add the subscribers of the grant lifecycle events
+func (m *Manager) Subscribe(subs ...oauth2.EventSubscriber) {
+	m.subscribers = append(m.subscribers, subs...)
+}
+
+// emit the grant lifecycle event to the subscribers
+func (m *Manager) emit(ctx context.Context, r *http.Request, e *oauth2.Event) {
+	if len(m.subscribers) == 0 {
+		return
+	}
+	e.Time = time.Now()
+	e.CorrelationID = oauth2.CorrelationIDFromContext(ctx)
+	if r != nil {
+		e.IP = clientIP(r)
+	}
+	for _, sub := range m.subscribers {
+		sub.HandleEvent(ctx, e)
+	}
+}
+
+// rehash the verified client secret and save the client when its hash is outdated
+func (m *Manager) rehashClientSecret(ctx context.Context, cli oauth2.ClientInfo, secret string) {
+	rh, ok := cli.(oauth2.ClientPasswordRehasher)
+	if !ok {
+		return
+	}
+	us, ok := m.clientStore.(oauth2.ClientUpdateStore)
+	if !ok {
+		return
+	}
+	// a failed rehash is retried on the next successful verification
+	if updated, err := rh.RehashPassword(secret); err == nil && updated != nil {
+		_ = us.Update(ctx, updated)
+	}
+}
+
+// check the requested resources are absolute URIs allowed to the client (RFC 8707)
+func (m *Manager) validateResource(cli oauth2.ClientInfo, resource []string) error {
+	if len(resource) == 0 {
+		return nil
+	}
+	var allowed []string
+	if rc, ok := cli.(oauth2.ClientResourceInfo); ok {
+		allowed = rc.GetResources()
+	}
+	for _, v := range resource {
+		if !isResourceURI(v) || !containsAll(allowed, []string{v}) {
+			return errors.ErrInvalidTarget
+		}
+	}
+	return nil
+}
+
 // GetClient get the client information
 func (m *Manager) GetClient(ctx context.Context, clientID string) (cli oauth2.ClientInfo, err error) {
 	cli, err = m.clientStore.GetByID(ctx, clientID)
@@ -157,6 +230,9 @@ func (m *Manager) GenerateAuthToken(ctx context.Context, rt oauth2.ResponseType,
 			return nil, err
 		}
 	}
+	if err := m.validateResource(cli, tgr.Resource); err != nil {
+		return nil, err
+	}
 
 	ti := models.NewToken()
 	if m.extractExtension != nil {
@@ -166,6 +242,8 @@ func (m *Manager) GenerateAuthToken(ctx context.Context, rt oauth2.ResponseType,
 	ti.SetUserID(tgr.UserID)
 	ti.SetRedirectURI(tgr.RedirectURI)
 	ti.SetScope(tgr.Scope)
+	ti.SetResource(tgr.Resource)
+	ti.SetAuthorizationDetails(tgr.AuthorizationDetails)
 
 	createAt := time.Now()
 	td := &oauth2.GenerateBasic{
@@ -226,6 +304,12 @@ func (m *Manager) GenerateAuthToken(ctx context.Context, rt oauth2.ResponseType,
 	if err != nil {
 		return nil, err
 	}
+
+	e := &oauth2.Event{Type: oauth2.EventCodeIssued, ClientID: tgr.ClientID, UserID: tgr.UserID, GrantType: oauth2.AuthorizationCode}
+	if rt == oauth2.Token {
+		e.Type, e.GrantType = oauth2.EventTokenIssued, oauth2.Implicit
+	}
+	m.emit(ctx, tgr.Request, e)
 	return ti, nil
 }
 
@@ -287,19 +371,42 @@ func (m *Manager) validateCodeChallenge(ti oauth2.TokenInfo, ver string) error {
 	return nil
 }
 
-// GenerateAccessToken generate the access token
-func (m *Manager) GenerateAccessToken(ctx context.Context, gt oauth2.GrantType, tgr *oauth2.TokenGenerateRequest) (oauth2.TokenInfo, error) {
+// authenticateClient get the client of the token request and verify its secret,
+// unless the server has authenticated the client, every grant authenticates the client with it
+func (m *Manager) authenticateClient(ctx context.Context, gt oauth2.GrantType, tgr *oauth2.TokenGenerateRequest) (oauth2.ClientInfo, error) {
 	cli, err := m.GetClient(ctx, tgr.ClientID)
 	if err != nil {
+		if errors.Is(err, errors.ErrInvalidClient) {
+			m.emit(ctx, tgr.Request, &oauth2.Event{Type: oauth2.EventClientAuthFailed, ClientID: tgr.ClientID, GrantType: gt})
+		}
 		return nil, err
 	}
-	if cliPass, ok := cli.(oauth2.ClientPasswordVerifier); ok {
+	if tgr.ClientAuthenticated {
+		// the server has authenticated the client
+	} else if tgr.ClientSecret == "" && !cli.IsPublic() {
+		// the confidential clients must authenticate
+		m.emit(ctx, tgr.Request, &oauth2.Event{Type: oauth2.EventClientAuthFailed, ClientID: tgr.ClientID, GrantType: gt})
+		return nil, errors.ErrInvalidClient
+	} else if cliPass, ok := cli.(oauth2.ClientPasswordVerifier); ok {
 		if !cliPass.VerifyPassword(tgr.ClientSecret) {
+			m.emit(ctx, tgr.Request, &oauth2.Event{Type: oauth2.EventClientAuthFailed, ClientID: tgr.ClientID, GrantType: gt})
 			return nil, errors.ErrInvalidClient
 		}
-	} else if len(cli.GetSecret()) > 0 && tgr.ClientSecret != cli.GetSecret() {
+		m.rehashClientSecret(ctx, cli, tgr.ClientSecret)
+	} else if (len(cli.GetSecret()) > 0 || !cli.IsPublic()) &&
+		subtle.ConstantTimeCompare([]byte(tgr.ClientSecret), []byte(cli.GetSecret())) != 1 {
+		m.emit(ctx, tgr.Request, &oauth2.Event{Type: oauth2.EventClientAuthFailed, ClientID: tgr.ClientID, GrantType: gt})
 		return nil, errors.ErrInvalidClient
 	}
+	return cli, nil
+}
+
+// GenerateAccessToken generate the access token
+func (m *Manager) GenerateAccessToken(ctx context.Context, gt oauth2.GrantType, tgr *oauth2.TokenGenerateRequest) (oauth2.TokenInfo, error) {
+	cli, err := m.authenticateClient(ctx, gt, tgr)
+	if err != nil {
+		return nil, err
+	}
 	if tgr.RedirectURI != "" {
 		if err := m.validateURI(cli.GetDomain(), tgr.RedirectURI); err != nil {
 			return nil, err
@@ -311,6 +418,7 @@ func (m *Manager) GenerateAccessToken(ctx context.Context, gt oauth2.GrantType,
 	}
 
 	var extension url.Values
+	var granted []string
 
 	if gt == oauth2.AuthorizationCode {
 		ti, err := m.getAndDelAuthorizationCode(ctx, tgr)
@@ -318,8 +426,10 @@ func (m *Manager) GenerateAccessToken(ctx context.Context, gt oauth2.GrantType,
 			return nil, err
 		}
 		if err := m.validateCodeChallenge(ti, tgr.CodeVerifier); err != nil {
+			m.emit(ctx, tgr.Request, &oauth2.Event{Type: oauth2.EventPKCEFailed, ClientID: tgr.ClientID, UserID: ti.GetUserID(), GrantType: gt, Error: err.Error()})
 			return nil, err
 		}
+		m.emit(ctx, tgr.Request, &oauth2.Event{Type: oauth2.EventCodeRedeemed, ClientID: tgr.ClientID, UserID: ti.GetUserID(), GrantType: gt})
 		tgr.UserID = ti.GetUserID()
 		tgr.Scope = ti.GetScope()
 		if exp := ti.GetAccessExpiresIn(); exp > 0 {
@@ -328,6 +438,29 @@ func (m *Manager) GenerateAccessToken(ctx context.Context, gt oauth2.GrantType,
 		if eti, ok := ti.(oauth2.ExtendableTokenInfo); ok {
 			extension = eti.GetExtension()
 		}
+		// the token can be restricted to some of the resources of the authorization
+		if rti, ok := ti.(oauth2.ResourceTokenInfo); ok && len(rti.GetResource()) > 0 {
+			granted = rti.GetResource()
+			if len(tgr.Resource) == 0 {
+				tgr.Resource = rti.GetResource()
+			} else if !containsAll(rti.GetResource(), tgr.Resource) {
+				return nil, errors.ErrInvalidTarget
+			}
+		}
+		// the token can be restricted to some of the authorization details
+		if dti, ok := ti.(oauth2.AuthorizationDetailsTokenInfo); ok && len(dti.GetAuthorizationDetails()) > 0 {
+			if len(tgr.AuthorizationDetails) == 0 {
+				tgr.AuthorizationDetails = dti.GetAuthorizationDetails()
+			} else if !containsAuthorizationDetails(dti.GetAuthorizationDetails(), tgr.AuthorizationDetails) {
+				return nil, errors.ErrInvalidAuthorizationDetails
+			}
+		}
+	}
+	if err := m.validateResource(cli, tgr.Resource); err != nil {
+		return nil, err
+	}
+	if granted == nil {
+		granted = tgr.Resource
 	}
 
 	ti := models.NewToken()
@@ -339,6 +472,9 @@ func (m *Manager) GenerateAccessToken(ctx context.Context, gt oauth2.GrantType,
 	ti.SetUserID(tgr.UserID)
 	ti.SetRedirectURI(tgr.RedirectURI)
 	ti.SetScope(tgr.Scope)
+	ti.SetResource(tgr.Resource)
+	ti.SetGrantedResource(granted)
+	ti.SetAuthorizationDetails(tgr.AuthorizationDetails)
 
 	createAt := time.Now()
 	ti.SetAccessCreateAt(createAt)
@@ -378,19 +514,23 @@ func (m *Manager) GenerateAccessToken(ctx context.Context, gt oauth2.GrantType,
 		return nil, err
 	}
 
+	m.emit(ctx, tgr.Request, &oauth2.Event{Type: oauth2.EventTokenIssued, ClientID: tgr.ClientID, UserID: tgr.UserID, GrantType: gt})
 	return ti, nil
 }
 
 // RefreshAccessToken refreshing an access token
 func (m *Manager) RefreshAccessToken(ctx context.Context, tgr *oauth2.TokenGenerateRequest) (oauth2.TokenInfo, error) {
-	ti, err := m.LoadRefreshToken(ctx, tgr.Refresh)
+	cli, err := m.authenticateClient(ctx, oauth2.Refreshing, tgr)
 	if err != nil {
 		return nil, err
 	}
 
-	cli, err := m.GetClient(ctx, ti.GetClientID())
+	ti, err := m.LoadRefreshToken(ctx, tgr.Refresh)
 	if err != nil {
 		return nil, err
+	} else if ti.GetClientID() != cli.GetID() {
+		// the refresh token is bound to the client it was issued to
+		return nil, errors.ErrInvalidGrant
 	}
 
 	oldAccess, oldRefresh := ti.GetAccess(), ti.GetRefresh()
@@ -421,11 +561,41 @@ func (m *Manager) RefreshAccessToken(ctx context.Context, tgr *oauth2.TokenGener
 		ti.SetRefreshCreateAt(td.CreateAt)
 	}
 
-	if scope := tgr.Scope; scope != "" {
+	if scope := tgr.Scope; len(scope) > 0 {
 		ti.SetScope(scope)
 	}
 
-	tv, rv, err := m.accessGenerate.Token(ctx, td, rcfg.IsGenerateRefresh)
+	// the access token can be restricted to some of the resources of the grant,
+	// the refresh token keeps all of them
+	if rti, ok := ti.(oauth2.ResourceTokenInfo); ok {
+		granted := rti.GetGrantedResource()
+		if granted == nil {
+			granted = rti.GetResource()
+			rti.SetGrantedResource(granted)
+		}
+		if resource := tgr.Resource; len(resource) > 0 {
+			if len(granted) > 0 && !containsAll(granted, resource) {
+				return nil, errors.ErrInvalidTarget
+			} else if err := m.validateResource(cli, resource); err != nil {
+				return nil, err
+			}
+			rti.SetResource(resource)
+		} else {
+			rti.SetResource(granted)
+		}
+	} else if len(tgr.Resource) > 0 {
+		return nil, errors.ErrInvalidTarget
+	}
+
+	if details := tgr.AuthorizationDetails; len(details) > 0 {
+		dti, ok := ti.(oauth2.AuthorizationDetailsTokenInfo)
+		if !ok || !containsAuthorizationDetails(dti.GetAuthorizationDetails(), details) {
+			return nil, errors.ErrInvalidAuthorizationDetails
+		}
+		dti.SetAuthorizationDetails(details)
+	}
+
+	tv, rv, err := m.accessGenerate.Token(ctx, td, rcfg.IsGenerateRefresh || tgr.RotateRefresh)
 	if err != nil {
 		return nil, err
 	}
@@ -446,7 +616,7 @@ func (m *Manager) RefreshAccessToken(ctx context.Context, tgr *oauth2.TokenGener
 		}
 	}
 
-	if rcfg.IsRemoveRefreshing && rv != "" {
+	if (rcfg.IsRemoveRefreshing || tgr.RotateRefresh) && rv != "" {
 		// remove the old refresh token
 		if err := m.tokenStore.RemoveByRefresh(ctx, oldRefresh); err != nil {
 			return nil, err
@@ -457,17 +627,37 @@ func (m *Manager) RefreshAccessToken(ctx context.Context, tgr *oauth2.TokenGener
 		ti.SetRefresh("")
 		ti.SetRefreshCreateAt(time.Now())
 		ti.SetRefreshExpiresIn(0)
+	} else {
+		m.emit(ctx, tgr.Request, &oauth2.Event{Type: oauth2.EventRefreshRotated, ClientID: ti.GetClientID(), UserID: ti.GetUserID(), GrantType: oauth2.Refreshing})
 	}
 
+	m.emit(ctx, tgr.Request, &oauth2.Event{Type: oauth2.EventTokenIssued, ClientID: ti.GetClientID(), UserID: ti.GetUserID(), GrantType: oauth2.Refreshing})
 	return ti, nil
 }
 
+// revokedEvent the revocation event of the token loaded before its removal
+func (m *Manager) revokedEvent(ctx context.Context, load func(ctx context.Context, key string) (oauth2.TokenInfo, error), key string) *oauth2.Event {
+	e := &oauth2.Event{Type: oauth2.EventTokenRevoked}
+	if len(m.subscribers) == 0 {
+		return e
+	}
+	if ti, err := load(ctx, key); err == nil && ti != nil {
+		e.ClientID, e.UserID = ti.GetClientID(), ti.GetUserID()
+	}
+	return e
+}
+
 // RemoveAccessToken use the access token to delete the token information
 func (m *Manager) RemoveAccessToken(ctx context.Context, access string) error {
 	if access == "" {
 		return errors.ErrInvalidAccessToken
 	}
-	return m.tokenStore.RemoveByAccess(ctx, access)
+	e := m.revokedEvent(ctx, m.tokenStore.GetByAccess, access)
+	if err := m.tokenStore.RemoveByAccess(ctx, access); err != nil {
+		return err
+	}
+	m.emit(ctx, nil, e)
+	return nil
 }
 
 // RemoveRefreshToken use the refresh token to delete the token information
@@ -475,7 +665,12 @@ func (m *Manager) RemoveRefreshToken(ctx context.Context, refresh string) error
 	if refresh == "" {
 		return errors.ErrInvalidAccessToken
 	}
-	return m.tokenStore.RemoveByRefresh(ctx, refresh)
+	e := m.revokedEvent(ctx, m.tokenStore.GetByRefresh, refresh)
+	if err := m.tokenStore.RemoveByRefresh(ctx, refresh); err != nil {
+		return err
+	}
+	m.emit(ctx, nil, e)
+	return nil
 }
 
 // LoadAccessToken according to the access token for corresponding token information
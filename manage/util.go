@@ -2,7 +2,10 @@ package manage
 
 import (
 	"github.com/go-oauth2/oauth2/v4"
+	"net"
+	"net/http"
 	"net/url"
+	"reflect"
 	"strings"
 
 	"github.com/go-oauth2/oauth2/v4/errors"
@@ -30,3 +33,52 @@ func DefaultValidateURI(baseURI string, redirectURI string) error {
 	}
 	return nil
 }
+
+// isResourceURI whether the resource indicator is an absolute URI without fragment
+func isResourceURI(resource string) bool {
+	u, err := url.Parse(resource)
+	return err == nil && u.IsAbs() && !strings.Contains(resource, "#")
+}
+
+// containsAll whether every value is in the set
+func containsAll(set, values []string) bool {
+	for _, v := range values {
+		found := false
+		for _, s := range set {
+			if s == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// containsAuthorizationDetails whether every requested authorization detail has been granted
+func containsAuthorizationDetails(granted, requested []oauth2.AuthorizationDetail) bool {
+	for _, v := range requested {
+		found := false
+		for _, g := range granted {
+			if reflect.DeepEqual(g, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// clientIP the IP address of the request sender
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
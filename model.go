@@ -20,6 +20,28 @@ type (
 		VerifyPassword(string) bool
 	}
 
+	// ClientResourceInfo the client restricted to the resource servers it may request tokens for
+	ClientResourceInfo interface {
+		GetResources() []string
+	}
+
+	// ClientPolicyInfo the client with its own PKCE requirement, code challenge methods,
+	// grant types and response types
+	ClientPolicyInfo interface {
+		GetPolicy() *ClientPolicy
+	}
+
+	// ClientAuthMethodInfo the client registered with its token endpoint authentication method
+	ClientAuthMethodInfo interface {
+		GetTokenEndpointAuthMethod() string
+	}
+
+	// ClientPasswordRehasher upgrade the hashed password after a successful verification,
+	// it returns the updated copy of the client to save, nil if the password is up to date
+	ClientPasswordRehasher interface {
+		RehashPassword(string) (ClientInfo, error)
+	}
+
 	// TokenInfo the token information model interface
 	TokenInfo interface {
 		New() TokenInfo
@@ -30,8 +52,8 @@ type (
 		SetUserID(string)
 		GetRedirectURI() string
 		SetRedirectURI(string)
-		GetScope() string
-		SetScope(string)
+		GetScope() Scope
+		SetScope(Scope)
 
 		GetCode() string
 		SetCode(string)
@@ -64,4 +86,36 @@ type (
 		GetExtension() url.Values
 		SetExtension(url.Values)
 	}
+
+	// ResourceTokenInfo the token information bound to the resource servers (RFC 8707),
+	// the resource is the audience of the access token and the granted resource
+	// the resources of the grant the refresh token can be exchanged for
+	ResourceTokenInfo interface {
+		TokenInfo
+		GetResource() []string
+		SetResource([]string)
+		GetGrantedResource() []string
+		SetGrantedResource([]string)
+	}
+
+	// AuthorizationDetailsTokenInfo the token information with the rich authorization details (RFC 9396)
+	AuthorizationDetailsTokenInfo interface {
+		TokenInfo
+		GetAuthorizationDetails() []AuthorizationDetail
+		SetAuthorizationDetails([]AuthorizationDetail)
+	}
+
+	// ConsentInfo the user consent model interface
+	ConsentInfo interface {
+		GetUserID() string
+		SetUserID(string)
+		GetClientID() string
+		SetClientID(string)
+		GetScope() Scope
+		SetScope(Scope)
+		GetCreateAt() time.Time
+		SetCreateAt(time.Time)
+		GetExpiresIn() time.Duration
+		SetExpiresIn(time.Duration)
+	}
 )
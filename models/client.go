@@ -1,5 +1,11 @@
 package models
 
+import (
+	"time"
+
+	"github.com/go-oauth2/oauth2/v4"
+)
+
 // Client client model
 type Client struct {
 	ID     string
@@ -7,6 +13,36 @@ type Client struct {
 	Domain string
 	Public bool
 	UserID string
+	// the resource servers the client may request tokens for (RFC 8707)
+	Resources []string
+	// the authorization policy narrowing the server config, nil uses the server config
+	Policy *oauth2.ClientPolicy
+	// the registered token endpoint authentication method, e.g. private_key_jwt
+	TokenEndpointAuthMethod string
+	// the rotated hashed secrets with their validity windows
+	Secrets []ClientSecret
+	// the hasher of the client secrets, DefaultSecretHasher is used if nil
+	Hasher SecretHasher `json:"-" bson:"-"`
+}
+
+// ClientSecret the hashed client secret with its validity window
+type ClientSecret struct {
+	Hash      string    `bson:"Hash"`
+	NotBefore time.Time `bson:"NotBefore"` // zero means immediately
+	ExpiresAt time.Time `bson:"ExpiresAt"` // zero means it doesn't expire
+}
+
+// IsValid whether the secret can be used at the time
+func (s *ClientSecret) IsValid(t time.Time) bool {
+	return !t.Before(s.NotBefore) && (s.ExpiresAt.IsZero() || t.Before(s.ExpiresAt))
+}
+
+// Clone copy the client, the copy can be modified without affecting the client
+func (c *Client) Clone() *Client {
+	cc := *c
+	cc.Resources = append([]string(nil), c.Resources...)
+	cc.Secrets = append([]ClientSecret(nil), c.Secrets...)
+	return &cc
 }
 
 // GetID client id
@@ -33,3 +69,127 @@ func (c *Client) IsPublic() bool {
 func (c *Client) GetUserID() string {
 	return c.UserID
 }
+
+// GetResources the resource servers the client may request tokens for
+func (c *Client) GetResources() []string {
+	return c.Resources
+}
+
+// GetPolicy the authorization policy of the client
+func (c *Client) GetPolicy() *oauth2.ClientPolicy {
+	return c.Policy
+}
+
+// GetTokenEndpointAuthMethod the registered token endpoint authentication method
+func (c *Client) GetTokenEndpointAuthMethod() string {
+	return c.TokenEndpointAuthMethod
+}
+
+func (c *Client) hasher() SecretHasher {
+	if c.Hasher != nil {
+		return c.Hasher
+	}
+	return DefaultSecretHasher
+}
+
+// VerifyPassword verify the client secret in constant time against the plaintext or hashed secret
+// and the valid rotated secrets, a client without any secret accepts every secret only if it's public
+func (c *Client) VerifyPassword(secret string) bool {
+	if c.Secret == "" && len(c.Secrets) == 0 {
+		return c.Public
+	}
+
+	ok := false
+	if c.Secret != "" && VerifySecret(c.Secret, secret) {
+		ok = true
+	}
+	now := time.Now()
+	for i := range c.Secrets {
+		// check every secret to not leak which one matched
+		if c.Secrets[i].IsValid(now) && VerifySecret(c.Secrets[i].Hash, secret) {
+			ok = true
+		}
+	}
+	return ok
+}
+
+// RehashPassword rehash the verified secret when its hash uses outdated parameters,
+// the client is left unchanged and the rehashed copy to save is returned, nil if the hashes are up to date
+func (c *Client) RehashPassword(secret string) (oauth2.ClientInfo, error) {
+	h := c.hasher()
+	rehash := func(stored string) (string, bool, error) {
+		if !IsHashedSecret(stored) || !VerifySecret(stored, secret) ||
+			(h.Match(stored) && !h.NeedsRehash(stored)) {
+			return stored, false, nil
+		}
+		v, err := h.Hash(secret)
+		if err != nil {
+			return stored, false, err
+		}
+		return v, true, nil
+	}
+
+	// the client may be shared by the concurrent requests
+	cc := c.Clone()
+	updated := false
+	if v, ok, err := rehash(cc.Secret); err != nil {
+		return nil, err
+	} else if ok {
+		cc.Secret = v
+		updated = true
+	}
+	now := time.Now()
+	for i := range cc.Secrets {
+		if !cc.Secrets[i].IsValid(now) {
+			continue
+		}
+		if v, ok, err := rehash(cc.Secrets[i].Hash); err != nil {
+			return nil, err
+		} else if ok {
+			cc.Secrets[i].Hash = v
+			updated = true
+		}
+	}
+	if !updated {
+		return nil, nil
+	}
+	return cc, nil
+}
+
+// RotateSecret hash and add the new secret, the current secrets stay valid for the overlap duration
+func (c *Client) RotateSecret(secret string, overlap time.Duration) error {
+	h := c.hasher()
+	now := time.Now()
+	expiresAt := now.Add(overlap)
+
+	if c.Secret != "" {
+		stored := c.Secret
+		if !IsHashedSecret(stored) {
+			v, err := h.Hash(stored)
+			if err != nil {
+				return err
+			}
+			stored = v
+		}
+		c.Secrets = append(c.Secrets, ClientSecret{Hash: stored})
+		c.Secret = ""
+	}
+
+	v, err := h.Hash(secret)
+	if err != nil {
+		return err
+	}
+
+	secrets := c.Secrets[:0]
+	for _, s := range c.Secrets {
+		if s.ExpiresAt.IsZero() || s.ExpiresAt.After(expiresAt) {
+			s.ExpiresAt = expiresAt
+		}
+		// drop the expired secrets
+		if s.ExpiresAt.After(now) {
+			secrets = append(secrets, s)
+		}
+	}
+	c.Secrets = append(secrets, ClientSecret{Hash: v, NotBefore: now})
+	return nil
+}
@@ -0,0 +1,122 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-oauth2/oauth2/v4/models"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientSecret(t *testing.T) {
+	Convey("Test client secret", t, func() {
+		hashers := []models.SecretHasher{
+			&models.BcryptHasher{Cost: 4},
+			&models.Argon2idHasher{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, SaltLen: 16},
+			&models.PBKDF2Hasher{Iterations: 1000, KeyLen: 32, SaltLen: 16},
+		}
+
+		Convey("Test secret hashers", func() {
+			for _, h := range hashers {
+				hash, err := h.Hash("secret")
+				So(err, ShouldBeNil)
+				So(hash, ShouldNotContainSubstring, "secret")
+				So(h.Match(hash), ShouldBeTrue)
+				So(h.Verify(hash, "secret"), ShouldBeTrue)
+				So(h.Verify(hash, "other"), ShouldBeFalse)
+				So(h.NeedsRehash(hash), ShouldBeFalse)
+				So(models.IsHashedSecret(hash), ShouldBeTrue)
+				So(models.VerifySecret(hash, "secret"), ShouldBeTrue)
+				So(models.VerifySecret(hash, "other"), ShouldBeFalse)
+			}
+
+			So(models.IsHashedSecret("secret"), ShouldBeFalse)
+			So(models.VerifySecret("secret", "secret"), ShouldBeTrue)
+			So(models.VerifySecret("secret", "other"), ShouldBeFalse)
+		})
+
+		Convey("Test verify password", func() {
+			hash, err := hashers[1].Hash("secret")
+			So(err, ShouldBeNil)
+
+			cli := &models.Client{ID: "1", Secret: hash}
+			So(cli.VerifyPassword("secret"), ShouldBeTrue)
+			So(cli.VerifyPassword("other"), ShouldBeFalse)
+
+			cli = &models.Client{ID: "1", Secret: "secret"}
+			So(cli.VerifyPassword("secret"), ShouldBeTrue)
+			So(cli.VerifyPassword("other"), ShouldBeFalse)
+
+			cli = &models.Client{ID: "1", Secrets: []models.ClientSecret{
+				{Hash: hash, ExpiresAt: time.Now().Add(-time.Second)},
+			}}
+			So(cli.VerifyPassword("secret"), ShouldBeFalse)
+
+			// only the public clients go without a secret
+			cli = &models.Client{ID: "1"}
+			So(cli.VerifyPassword("secret"), ShouldBeFalse)
+			So(cli.VerifyPassword(""), ShouldBeFalse)
+			cli = &models.Client{ID: "1", Public: true}
+			So(cli.VerifyPassword(""), ShouldBeTrue)
+		})
+
+		Convey("Test rehash password", func() {
+			old := &models.Argon2idHasher{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, SaltLen: 16}
+			hash, err := old.Hash("secret")
+			So(err, ShouldBeNil)
+
+			cli := &models.Client{ID: "1", Secret: hash, Hasher: old}
+			updated, err := cli.RehashPassword("secret")
+			So(err, ShouldBeNil)
+			So(updated, ShouldBeNil)
+
+			cli.Hasher = &models.Argon2idHasher{Time: 2, Memory: 1024, Threads: 1, KeyLen: 32, SaltLen: 16}
+			updated, err = cli.RehashPassword("other")
+			So(err, ShouldBeNil)
+			So(updated, ShouldBeNil)
+
+			updated, err = cli.RehashPassword("secret")
+			So(err, ShouldBeNil)
+			So(updated, ShouldNotBeNil)
+			// the client itself is left unchanged
+			So(cli.Secret, ShouldEqual, hash)
+			cli = updated.(*models.Client)
+			So(cli.Secret, ShouldNotEqual, hash)
+			So(cli.Hasher.NeedsRehash(cli.Secret), ShouldBeFalse)
+			So(cli.VerifyPassword("secret"), ShouldBeTrue)
+
+			// switch the algorithm
+			cli.Hasher = hashers[0]
+			updated, err = cli.RehashPassword("secret")
+			So(err, ShouldBeNil)
+			So(updated, ShouldNotBeNil)
+			So(hashers[0].Match(updated.GetSecret()), ShouldBeTrue)
+
+			// plaintext secrets are left alone
+			cli = &models.Client{ID: "1", Secret: "secret", Hasher: hashers[0]}
+			updated, err = cli.RehashPassword("secret")
+			So(err, ShouldBeNil)
+			So(updated, ShouldBeNil)
+		})
+
+		Convey("Test rotate secret", func() {
+			cli := &models.Client{ID: "1", Secret: "secret", Hasher: hashers[0]}
+			err := cli.RotateSecret("secret2", time.Hour)
+			So(err, ShouldBeNil)
+			So(cli.Secret, ShouldBeEmpty)
+			So(len(cli.Secrets), ShouldEqual, 2)
+			So(models.IsHashedSecret(cli.Secrets[0].Hash), ShouldBeTrue)
+			So(cli.VerifyPassword("secret"), ShouldBeTrue)
+			So(cli.VerifyPassword("secret2"), ShouldBeTrue)
+			So(cli.VerifyPassword("other"), ShouldBeFalse)
+
+			err = cli.RotateSecret("secret3", 0)
+			So(err, ShouldBeNil)
+			So(len(cli.Secrets), ShouldEqual, 1)
+			So(cli.VerifyPassword("secret"), ShouldBeFalse)
+			So(cli.VerifyPassword("secret2"), ShouldBeFalse)
+			So(cli.VerifyPassword("secret3"), ShouldBeTrue)
+		})
+	})
+}
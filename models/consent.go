@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"github.com/go-oauth2/oauth2/v4"
+)
+
+// NewConsent create to consent model instance
+func NewConsent() *Consent {
+	return &Consent{}
+}
+
+// Consent user consent model
+type Consent struct {
+	UserID    string        `bson:"UserID"`
+	ClientID  string        `bson:"ClientID"`
+	Scope     string        `bson:"Scope"`
+	CreateAt  time.Time     `bson:"CreateAt"`
+	ExpiresIn time.Duration `bson:"ExpiresIn"`
+}
+
+// GetUserID the user id
+func (c *Consent) GetUserID() string {
+	return c.UserID
+}
+
+// SetUserID the user id
+func (c *Consent) SetUserID(userID string) {
+	c.UserID = userID
+}
+
+// GetClientID the client id
+func (c *Consent) GetClientID() string {
+	return c.ClientID
+}
+
+// SetClientID the client id
+func (c *Consent) SetClientID(clientID string) {
+	c.ClientID = clientID
+}
+
+// GetScope the consented scope
+func (c *Consent) GetScope() oauth2.Scope {
+	return oauth2.ParseScope(c.Scope)
+}
+
+// SetScope the consented scope
+func (c *Consent) SetScope(scope oauth2.Scope) {
+	c.Scope = scope.String()
+}
+
+// GetCreateAt create Time
+func (c *Consent) GetCreateAt() time.Time {
+	return c.CreateAt
+}
+
+// SetCreateAt create Time
+func (c *Consent) SetCreateAt(createAt time.Time) {
+	c.CreateAt = createAt
+}
+
+// GetExpiresIn the lifetime of the consent, 0 means it doesn't expire
+func (c *Consent) GetExpiresIn() time.Duration {
+	return c.ExpiresIn
+}
+
+// SetExpiresIn the lifetime of the consent, 0 means it doesn't expire
+func (c *Consent) SetExpiresIn(exp time.Duration) {
+	c.ExpiresIn = exp
+}
@@ -0,0 +1,224 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// SecretHasher hash and verify the client secrets
+type SecretHasher interface {
+	// Hash returns the encoded hash of the secret
+	Hash(secret string) (string, error)
+	// Match reports whether the encoded hash was produced by this hasher
+	Match(hash string) bool
+	// Verify compare the secret with the encoded hash in constant time
+	Verify(hash, secret string) bool
+	// NeedsRehash reports whether the encoded hash uses other parameters than the hasher
+	NeedsRehash(hash string) bool
+}
+
+// DefaultSecretHasher the hasher of the new client secrets, the OWASP argon2id minimum (19 MiB, 2 passes, 1 thread).
+// Every client authentication runs the hasher before the client is known to be legitimate,
+// so keep the cost low and limit the token endpoint requests (see server.SetRateLimiter)
+// before raising it; the client secrets are random, not user chosen passwords
+var DefaultSecretHasher SecretHasher = &Argon2idHasher{Time: 2, Memory: 19 * 1024, Threads: 1, KeyLen: 32, SaltLen: 16}
+
+// SecretHashers the hashers used to verify the stored client secrets,
+// a secret matched by none of them is compared as plaintext
+var SecretHashers = []SecretHasher{
+	DefaultSecretHasher,
+	&BcryptHasher{Cost: bcrypt.DefaultCost},
+	&PBKDF2Hasher{Iterations: 600000, KeyLen: 32, SaltLen: 16},
+}
+
+// VerifySecret compare the secret with the hashed or plaintext stored secret in constant time
+func VerifySecret(stored, secret string) bool {
+	for _, h := range SecretHashers {
+		if h.Match(stored) {
+			return h.Verify(stored, secret)
+		}
+	}
+	return subtle.ConstantTimeCompare([]byte(stored), []byte(secret)) == 1
+}
+
+// IsHashedSecret reports whether the stored secret is hashed by one of the SecretHashers
+func IsHashedSecret(stored string) bool {
+	for _, h := range SecretHashers {
+		if h.Match(stored) {
+			return true
+		}
+	}
+	return false
+}
+
+func randomSalt(n int) ([]byte, error) {
+	salt := make([]byte, n)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+// BcryptHasher bcrypt secret hasher
+type BcryptHasher struct {
+	Cost int
+}
+
+// Hash returns the encoded hash of the secret
+func (h *BcryptHasher) Hash(secret string) (string, error) {
+	v, err := bcrypt.GenerateFromPassword([]byte(secret), h.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(v), nil
+}
+
+// Match reports whether the encoded hash was produced by this hasher
+func (h *BcryptHasher) Match(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") ||
+		strings.HasPrefix(hash, "$2b$") ||
+		strings.HasPrefix(hash, "$2y$")
+}
+
+// Verify compare the secret with the encoded hash in constant time
+func (h *BcryptHasher) Verify(hash, secret string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(secret)) == nil
+}
+
+// NeedsRehash reports whether the encoded hash uses other parameters than the hasher
+func (h *BcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != h.Cost
+}
+
+// Argon2idHasher argon2id secret hasher, the hash is encoded in the PHC string format
+type Argon2idHasher struct {
+	Time    uint32
+	Memory  uint32 // KiB
+	Threads uint8
+	KeyLen  uint32
+	SaltLen int
+}
+
+// Hash returns the encoded hash of the secret
+func (h *Argon2idHasher) Hash(secret string) (string, error) {
+	salt, err := randomSalt(h.SaltLen)
+	if err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(secret), salt, h.Time, h.Memory, h.Threads, h.KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, h.Memory, h.Time, h.Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Match reports whether the encoded hash was produced by this hasher
+func (h *Argon2idHasher) Match(hash string) bool {
+	return strings.HasPrefix(hash, "$argon2id$")
+}
+
+func (h *Argon2idHasher) decode(hash string) (params *Argon2idHasher, salt, key []byte, err error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, nil, nil, fmt.Errorf("invalid argon2id hash")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, nil, nil, fmt.Errorf("unsupported argon2id version")
+	}
+	params = &Argon2idHasher{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return nil, nil, nil, err
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, nil, nil, err
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return nil, nil, nil, err
+	}
+	params.SaltLen = len(salt)
+	params.KeyLen = uint32(len(key))
+	return params, salt, key, nil
+}
+
+// Verify compare the secret with the encoded hash in constant time
+func (h *Argon2idHasher) Verify(hash, secret string) bool {
+	p, salt, key, err := h.decode(hash)
+	if err != nil {
+		return false
+	}
+	v := argon2.IDKey([]byte(secret), salt, p.Time, p.Memory, p.Threads, p.KeyLen)
+	return subtle.ConstantTimeCompare(v, key) == 1
+}
+
+// NeedsRehash reports whether the encoded hash uses other parameters than the hasher
+func (h *Argon2idHasher) NeedsRehash(hash string) bool {
+	p, _, _, err := h.decode(hash)
+	return err != nil || *p != *h
+}
+
+// PBKDF2Hasher PBKDF2-HMAC-SHA256 secret hasher
+type PBKDF2Hasher struct {
+	Iterations int
+	KeyLen     int
+	SaltLen    int
+}
+
+// Hash returns the encoded hash of the secret
+func (h *PBKDF2Hasher) Hash(secret string) (string, error) {
+	salt, err := randomSalt(h.SaltLen)
+	if err != nil {
+		return "", err
+	}
+	key := pbkdf2.Key([]byte(secret), salt, h.Iterations, h.KeyLen, sha256.New)
+	return fmt.Sprintf("$pbkdf2-sha256$i=%d$%s$%s", h.Iterations,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Match reports whether the encoded hash was produced by this hasher
+func (h *PBKDF2Hasher) Match(hash string) bool {
+	return strings.HasPrefix(hash, "$pbkdf2-sha256$")
+}
+
+func (h *PBKDF2Hasher) decode(hash string) (params *PBKDF2Hasher, salt, key []byte, err error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 5 || parts[1] != "pbkdf2-sha256" {
+		return nil, nil, nil, fmt.Errorf("invalid pbkdf2 hash")
+	}
+	params = &PBKDF2Hasher{}
+	if _, err := fmt.Sscanf(parts[2], "i=%d", &params.Iterations); err != nil || params.Iterations <= 0 {
+		return nil, nil, nil, fmt.Errorf("invalid pbkdf2 iterations")
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[3]); err != nil {
+		return nil, nil, nil, err
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, nil, nil, err
+	}
+	params.SaltLen = len(salt)
+	params.KeyLen = len(key)
+	return params, salt, key, nil
+}
+
+// Verify compare the secret with the encoded hash in constant time
+func (h *PBKDF2Hasher) Verify(hash, secret string) bool {
+	p, salt, key, err := h.decode(hash)
+	if err != nil {
+		return false
+	}
+	v := pbkdf2.Key([]byte(secret), salt, p.Iterations, p.KeyLen, sha256.New)
+	return subtle.ConstantTimeCompare(v, key) == 1
+}
+
+// NeedsRehash reports whether the encoded hash uses other parameters than the hasher
+func (h *PBKDF2Hasher) NeedsRehash(hash string) bool {
+	p, _, _, err := h.decode(hash)
+	return err != nil || *p != *h
+}
@@ -14,22 +14,25 @@ func NewToken() *Token {
 
 // Token token model
 type Token struct {
-	ClientID            string        `bson:"ClientID"`
-	UserID              string        `bson:"UserID"`
-	RedirectURI         string        `bson:"RedirectURI"`
-	Scope               string        `bson:"Scope"`
-	Code                string        `bson:"Code"`
-	CodeChallenge       string        `bson:"CodeChallenge"`
-	CodeChallengeMethod string        `bson:"CodeChallengeMethod"`
-	CodeCreateAt        time.Time     `bson:"CodeCreateAt"`
-	CodeExpiresIn       time.Duration `bson:"CodeExpiresIn"`
-	Access              string        `bson:"Access"`
-	AccessCreateAt      time.Time     `bson:"AccessCreateAt"`
-	AccessExpiresIn     time.Duration `bson:"AccessExpiresIn"`
-	Refresh             string        `bson:"Refresh"`
-	RefreshCreateAt     time.Time     `bson:"RefreshCreateAt"`
-	RefreshExpiresIn    time.Duration `bson:"RefreshExpiresIn"`
-	Extension           url.Values    `bson:"Extension"`
+	ClientID             string                       `bson:"ClientID"`
+	UserID               string                       `bson:"UserID"`
+	RedirectURI          string                       `bson:"RedirectURI"`
+	Scope                string                       `bson:"Scope"`
+	Code                 string                       `bson:"Code"`
+	CodeChallenge        string                       `bson:"CodeChallenge"`
+	CodeChallengeMethod  string                       `bson:"CodeChallengeMethod"`
+	CodeCreateAt         time.Time                    `bson:"CodeCreateAt"`
+	CodeExpiresIn        time.Duration                `bson:"CodeExpiresIn"`
+	Access               string                       `bson:"Access"`
+	AccessCreateAt       time.Time                    `bson:"AccessCreateAt"`
+	AccessExpiresIn      time.Duration                `bson:"AccessExpiresIn"`
+	Refresh              string                       `bson:"Refresh"`
+	RefreshCreateAt      time.Time                    `bson:"RefreshCreateAt"`
+	RefreshExpiresIn     time.Duration                `bson:"RefreshExpiresIn"`
+	Extension            url.Values                   `bson:"Extension"`
+	Resource             []string                     `bson:"Resource"`
+	GrantedResource      []string                     `bson:"GrantedResource"`
+	AuthorizationDetails []oauth2.AuthorizationDetail `bson:"AuthorizationDetails"`
 }
 
 // New create to token model instance
@@ -68,13 +71,13 @@ func (t *Token) SetRedirectURI(redirectURI string) {
 }
 
 // GetScope get scope of authorization
-func (t *Token) GetScope() string {
-	return t.Scope
+func (t *Token) GetScope() oauth2.Scope {
+	return oauth2.ParseScope(t.Scope)
 }
 
 // SetScope get scope of authorization
-func (t *Token) SetScope(scope string) {
-	t.Scope = scope
+func (t *Token) SetScope(scope oauth2.Scope) {
+	t.Scope = scope.String()
 }
 
 // GetCode authorization code
@@ -196,3 +199,33 @@ func (t *Token) GetExtension() url.Values {
 func (t *Token) SetExtension(e url.Values) {
 	t.Extension = e
 }
+
+// GetResource the resource servers the token is bound to
+func (t *Token) GetResource() []string {
+	return t.Resource
+}
+
+// SetResource the resource servers the token is bound to
+func (t *Token) SetResource(resource []string) {
+	t.Resource = resource
+}
+
+// GetGrantedResource the resource servers of the grant the refresh token is bound to
+func (t *Token) GetGrantedResource() []string {
+	return t.GrantedResource
+}
+
+// SetGrantedResource the resource servers of the grant the refresh token is bound to
+func (t *Token) SetGrantedResource(resource []string) {
+	t.GrantedResource = resource
+}
+
+// GetAuthorizationDetails the rich authorization details of the token
+func (t *Token) GetAuthorizationDetails() []oauth2.AuthorizationDetail {
+	return t.AuthorizationDetails
+}
+
+// SetAuthorizationDetails the rich authorization details of the token
+func (t *Token) SetAuthorizationDetails(details []oauth2.AuthorizationDetail) {
+	t.AuthorizationDetails = details
+}
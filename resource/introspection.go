@@ -0,0 +1,256 @@
+package resource
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/errors"
+	"github.com/go-oauth2/oauth2/v4/models"
+)
+
+// IntrospectionOption the introspection client option
+type IntrospectionOption func(*IntrospectionClient)
+
+// WithHTTPClient set the HTTP client calling the introspection endpoint, http.DefaultClient by default
+func WithHTTPClient(client *http.Client) IntrospectionOption {
+	return func(c *IntrospectionClient) {
+		c.httpClient = client
+	}
+}
+
+// WithCacheTTL set how long the active tokens are cached, never beyond their expiration,
+// 0 disables the cache, 1 minute by default
+func WithCacheTTL(ttl time.Duration) IntrospectionOption {
+	return func(c *IntrospectionClient) {
+		c.cacheTTL = ttl
+	}
+}
+
+// WithCacheSize set the maximum number of the cached tokens, 10000 by default
+func WithCacheSize(size int) IntrospectionOption {
+	return func(c *IntrospectionClient) {
+		c.cacheSize = size
+	}
+}
+
+// WithTokenType set the token type of the access tokens, "Bearer" by default,
+// the active responses with another token type are rejected, the ones without a token type aren't
+func WithTokenType(tokenType string) IntrospectionOption {
+	return func(c *IntrospectionClient) {
+		c.tokenType = tokenType
+	}
+}
+
+// WithClock set the function returning the current time, time.Now by default
+func WithClock(now func() time.Time) IntrospectionOption {
+	return func(c *IntrospectionClient) {
+		c.now = now
+	}
+}
+
+// NewIntrospectionClient create the client of the token introspection endpoint (RFC 7662),
+// it authenticates with the client credentials of the resource server
+func NewIntrospectionClient(endpoint, clientID, clientSecret string, opts ...IntrospectionOption) *IntrospectionClient {
+	c := &IntrospectionClient{
+		endpoint:     endpoint,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		httpClient:   http.DefaultClient,
+		cacheTTL:     time.Minute,
+		cacheSize:    10000,
+		tokenType:    "Bearer",
+		now:          time.Now,
+		cache:        make(map[string]*introspectionEntry),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// IntrospectionClient load the token information from the remote introspection endpoint,
+// it's the TokenLoader of the resource servers which can't access the token store.
+// The active tokens are cached, the inactive ones aren't so the revocations are seen
+// once the cached tokens expire
+type IntrospectionClient struct {
+	endpoint     string
+	clientID     string
+	clientSecret string
+	httpClient   *http.Client
+	cacheTTL     time.Duration
+	cacheSize    int
+	tokenType    string
+	now          func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]*introspectionEntry
+}
+
+type introspectionEntry struct {
+	ti       *models.Token
+	expireAt time.Time
+}
+
+// IntrospectionResponse the introspection response of the token
+// https://tools.ietf.org/html/rfc7662#section-2.2
+type IntrospectionResponse struct {
+	Active               bool                         `json:"active"`
+	Scope                string                       `json:"scope,omitempty"`
+	ClientID             string                       `json:"client_id,omitempty"`
+	TokenType            string                       `json:"token_type,omitempty"`
+	Exp                  int64                        `json:"exp,omitempty"`
+	Iat                  int64                        `json:"iat,omitempty"`
+	Sub                  string                       `json:"sub,omitempty"`
+	Aud                  audience                     `json:"aud,omitempty"`
+	Iss                  string                       `json:"iss,omitempty"`
+	AuthorizationDetails []oauth2.AuthorizationDetail `json:"authorization_details,omitempty"`
+}
+
+// audience the aud claim, a string or an array of strings
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var v string
+	if err := json.Unmarshal(data, &v); err == nil {
+		*a = audience{v}
+		return nil
+	}
+	var vs []string
+	if err := json.Unmarshal(data, &vs); err != nil {
+		return err
+	}
+	*a = vs
+	return nil
+}
+
+// cacheKey the cache doesn't keep the access tokens
+func cacheKey(access string) string {
+	sum := sha256.Sum256([]byte(access))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *IntrospectionClient) load(key string, now time.Time) (oauth2.TokenInfo, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.cache[key]
+	if !ok {
+		return nil, false
+	} else if !now.Before(e.expireAt) {
+		delete(c.cache, key)
+		return nil, false
+	}
+	ti := *e.ti
+	return &ti, true
+}
+
+func (c *IntrospectionClient) store(key string, ti *models.Token, now time.Time) {
+	expireAt := now.Add(c.cacheTTL)
+	if exp := ti.GetAccessExpiresIn(); exp > 0 {
+		if v := ti.GetAccessCreateAt().Add(exp); v.Before(expireAt) {
+			expireAt = v
+		}
+	}
+	if c.cacheTTL <= 0 || !now.Before(expireAt) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.cache) >= c.cacheSize {
+		// drop the expired tokens, don't cache any more if it's still full
+		for k, e := range c.cache {
+			if !now.Before(e.expireAt) {
+				delete(c.cache, k)
+			}
+		}
+		if len(c.cache) >= c.cacheSize {
+			return
+		}
+	}
+	cached := *ti
+	c.cache[key] = &introspectionEntry{ti: &cached, expireAt: expireAt}
+}
+
+// Introspect call the introspection endpoint, the response of an inactive token only has active false
+func (c *IntrospectionClient) Introspect(ctx context.Context, token string) (*IntrospectionResponse, error) {
+	form := url.Values{
+		"token":           {token},
+		"token_type_hint": {"access_token"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(c.clientID, c.clientSecret)
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("the introspection endpoint responded with the status %d", res.StatusCode)
+	}
+	ir := &IntrospectionResponse{}
+	if err := json.NewDecoder(res.Body).Decode(ir); err != nil {
+		return nil, err
+	}
+	return ir, nil
+}
+
+// LoadAccessToken get the information of the active access token from the cache or the introspection endpoint,
+// the active tokens of another token type (e.g. the refresh tokens) are invalid
+func (c *IntrospectionClient) LoadAccessToken(ctx context.Context, access string) (oauth2.TokenInfo, error) {
+	key := cacheKey(access)
+	now := c.now()
+	if ti, ok := c.load(key, now); ok {
+		return ti, nil
+	}
+
+	ir, err := c.Introspect(ctx, access)
+	if err != nil {
+		return nil, err
+	} else if !ir.Active || (ir.TokenType != "" && !strings.EqualFold(ir.TokenType, c.tokenType)) {
+		// the introspection endpoint may find a refresh token despite the access_token hint,
+		// the token_type is optional (RFC 7662 section 2.2)
+		return nil, errors.ErrInvalidAccessToken
+	}
+
+	ti := models.NewToken()
+	ti.SetAccess(access)
+	ti.SetClientID(ir.ClientID)
+	ti.SetUserID(ir.Sub)
+	ti.SetScope(oauth2.ParseScope(ir.Scope))
+	ti.SetResource(ir.Aud)
+	ti.SetAuthorizationDetails(ir.AuthorizationDetails)
+
+	createAt := now
+	if ir.Iat > 0 {
+		createAt = time.Unix(ir.Iat, 0)
+	}
+	ti.SetAccessCreateAt(createAt)
+	if ir.Exp > 0 {
+		exp := time.Unix(ir.Exp, 0)
+		if !now.Before(exp) {
+			return nil, errors.ErrExpiredAccessToken
+		}
+		ti.SetAccessExpiresIn(exp.Sub(createAt))
+	}
+
+	c.store(key, ti, now)
+	return ti, nil
+}
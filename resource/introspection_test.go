@@ -0,0 +1,159 @@
+package resource_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gavv/httpexpect"
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/errors"
+	"github.com/go-oauth2/oauth2/v4/resource"
+	"github.com/go-oauth2/oauth2/v4/server"
+)
+
+func TestIntrospectionClient(t *testing.T) {
+	manager := newManager(t)
+	srv := server.NewDefaultServer(manager)
+	var calls int32
+	tsrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if err := srv.HandleIntrospectionRequest(w, r); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer tsrv.Close()
+
+	ctx := context.Background()
+	var elapsed int64
+	client := resource.NewIntrospectionClient(tsrv.URL, "111111", "11111111", resource.WithClock(func() time.Time {
+		return time.Now().Add(time.Duration(atomic.LoadInt64(&elapsed)))
+	}))
+
+	ti, err := manager.GenerateAccessToken(ctx, oauth2.ClientCredentials, &oauth2.TokenGenerateRequest{
+		ClientID:       "111111",
+		ClientSecret:   "11111111",
+		Scope:          oauth2.ParseScope("read"),
+		AccessTokenExp: time.Second * 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rti, err := client.LoadAccessToken(ctx, ti.GetAccess())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rti.GetAccess() != ti.GetAccess() || rti.GetClientID() != "111111" || rti.GetScope().String() != "read" ||
+		rti.GetAccessExpiresIn() <= 0 || rti.GetAccessExpiresIn() > time.Second*2 {
+		t.Fatalf("unexpected token information: %#v", rti)
+	}
+
+	// the active token is cached
+	rti.SetScope(oauth2.ParseScope("write"))
+	rti, err = client.LoadAccessToken(ctx, ti.GetAccess())
+	if err != nil {
+		t.Fatal(err)
+	} else if rti.GetScope().String() != "read" {
+		t.Fatalf("the cached token has been modified: %#v", rti)
+	} else if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("unexpected introspection calls %d", n)
+	}
+
+	// the inactive tokens aren't cached
+	for i := 0; i < 2; i++ {
+		if _, err := client.LoadAccessToken(ctx, "unknown"); !errors.Is(err, errors.ErrInvalidAccessToken) {
+			t.Fatalf("unexpected error of the unknown token: %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Fatalf("unexpected introspection calls %d", n)
+	}
+
+	// the cache doesn't outlive the token
+	atomic.StoreInt64(&elapsed, int64(time.Millisecond*2100))
+	if _, err := client.LoadAccessToken(ctx, ti.GetAccess()); !errors.Is(err, errors.ErrExpiredAccessToken) {
+		t.Fatalf("unexpected error of the expired token: %v", err)
+	} else if n := atomic.LoadInt32(&calls); n != 4 {
+		t.Fatalf("unexpected introspection calls %d", n)
+	}
+
+	// the resource server must authenticate
+	invalidClient := resource.NewIntrospectionClient(tsrv.URL, "111111", "invalid")
+	if _, err := invalidClient.LoadAccessToken(ctx, ti.GetAccess()); err == nil {
+		t.Fatal("the invalid client has introspected the token")
+	}
+}
+
+func TestIntrospectionTokenType(t *testing.T) {
+	var tokenType string
+	tsrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data := map[string]interface{}{"active": true, "client_id": "111111"}
+		if tokenType != "" {
+			data["token_type"] = tokenType
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(data)
+	}))
+	defer tsrv.Close()
+
+	ctx := context.Background()
+	client := resource.NewIntrospectionClient(tsrv.URL, "111111", "11111111", resource.WithCacheTTL(0))
+	tokenType = "refresh_token"
+	if _, err := client.LoadAccessToken(ctx, "token"); !errors.Is(err, errors.ErrInvalidAccessToken) {
+		t.Fatalf("unexpected error of the refresh token: %v", err)
+	}
+
+	// the token_type is optional
+	for _, v := range []string{"bearer", ""} {
+		tokenType = v
+		if _, err := client.LoadAccessToken(ctx, "token"); err != nil {
+			t.Fatalf("unexpected error of the token type %q: %v", v, err)
+		}
+	}
+
+	tokenType = "DPoP"
+	client = resource.NewIntrospectionClient(tsrv.URL, "111111", "11111111", resource.WithTokenType("DPoP"))
+	if _, err := client.LoadAccessToken(ctx, "token"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIntrospectionMiddleware(t *testing.T) {
+	manager := newManager(t)
+	srv := server.NewDefaultServer(manager)
+	asrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := srv.HandleIntrospectionRequest(w, r); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer asrv.Close()
+
+	m := resource.New(resource.NewIntrospectionClient(asrv.URL, "111111", "11111111", resource.WithCacheTTL(0)))
+	rsrv := httptest.NewServer(m.RequireScope("read")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(resource.ClientIDFromContext(r.Context())))
+	})))
+	defer rsrv.Close()
+	e := httpexpect.New(t, rsrv.URL)
+
+	e.GET("/").
+		WithHeader("Authorization", "Bearer "+generateToken(t, manager, "read")).
+		Expect().
+		Status(http.StatusOK).
+		Body().Equal("111111")
+
+	e.GET("/").
+		WithHeader("Authorization", "Bearer "+generateToken(t, manager, "write")).
+		Expect().
+		Status(http.StatusForbidden)
+
+	e.GET("/").
+		WithHeader("Authorization", "Bearer unknown").
+		Expect().
+		Status(http.StatusUnauthorized).
+		Header("WWW-Authenticate").Contains(`error="invalid_token"`)
+}
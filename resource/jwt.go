@@ -0,0 +1,55 @@
+package resource
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/errors"
+	"github.com/go-oauth2/oauth2/v4/generates"
+	"github.com/go-oauth2/oauth2/v4/models"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// NewJWTLoader create the loader validating the JWT access tokens of generates.JWTAccessGenerate
+// with the key function, the options should check the issuer and the audience of the resource server
+func NewJWTLoader(keyFunc jwt.Keyfunc, opts ...jwt.ParserOption) *JWTLoader {
+	return &JWTLoader{
+		keyFunc: keyFunc,
+		opts:    append([]jwt.ParserOption{jwt.WithExpirationRequired()}, opts...),
+	}
+}
+
+// JWTLoader load the token information from the claims of the JWT access tokens,
+// it doesn't know whether the token has been revoked
+type JWTLoader struct {
+	keyFunc jwt.Keyfunc
+	opts    []jwt.ParserOption
+}
+
+// LoadAccessToken validate the JWT access token and get its information
+func (l *JWTLoader) LoadAccessToken(ctx context.Context, access string) (oauth2.TokenInfo, error) {
+	claims := &generates.JWTAccessClaims{}
+	if _, err := jwt.ParseWithClaims(access, claims, l.keyFunc, l.opts...); err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, errors.ErrExpiredAccessToken
+		}
+		return nil, errors.ErrInvalidAccessToken
+	}
+
+	ti := models.NewToken()
+	ti.SetAccess(access)
+	ti.SetClientID(claims.ClientID)
+	ti.SetUserID(claims.Subject)
+	ti.SetScope(oauth2.ParseScope(claims.Scope))
+	ti.SetResource(claims.Audience)
+	ti.SetAuthorizationDetails(claims.AuthorizationDetails)
+
+	createAt := time.Now()
+	if claims.IssuedAt != nil {
+		createAt = claims.IssuedAt.Time
+	}
+	ti.SetAccessCreateAt(createAt)
+	ti.SetAccessExpiresIn(claims.ExpiresAt.Sub(createAt))
+	return ti, nil
+}
@@ -0,0 +1,65 @@
+package resource_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/errors"
+	"github.com/go-oauth2/oauth2/v4/generates"
+	"github.com/go-oauth2/oauth2/v4/models"
+	"github.com/go-oauth2/oauth2/v4/resource"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestJWTLoader(t *testing.T) {
+	key := []byte("00000000")
+	gen := generates.NewJWTAccessGenerate("", key, jwt.SigningMethodHS512)
+	gen.Issuer = "https://as.example.com"
+
+	generate := func(createAt time.Time) string {
+		access, _, err := gen.Token(context.Background(), &oauth2.GenerateBasic{
+			Client: &models.Client{ID: "111111"},
+			UserID: "000000",
+			TokenInfo: &models.Token{
+				Scope:           "read write",
+				AccessCreateAt:  createAt,
+				AccessExpiresIn: time.Hour,
+				Resource:        []string{"https://api.example.com"},
+			},
+		}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return access
+	}
+
+	loader := resource.NewJWTLoader(func(token *jwt.Token) (interface{}, error) {
+		return key, nil
+	}, jwt.WithIssuer("https://as.example.com"), jwt.WithAudience("https://api.example.com"))
+
+	access := generate(time.Now())
+	ti, err := loader.LoadAccessToken(context.Background(), access)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ti.GetAccess() != access || ti.GetClientID() != "111111" || ti.GetUserID() != "000000" ||
+		ti.GetScope().String() != "read write" || ti.GetAccessExpiresIn() != time.Hour {
+		t.Fatalf("unexpected token information: %#v", ti)
+	}
+	if rti, ok := ti.(oauth2.ResourceTokenInfo); !ok || len(rti.GetResource()) != 1 {
+		t.Fatalf("unexpected resource: %#v", ti)
+	}
+
+	if _, err := loader.LoadAccessToken(context.Background(), generate(time.Now().Add(-2*time.Hour))); !errors.Is(err, errors.ErrExpiredAccessToken) {
+		t.Fatalf("unexpected error of the expired token: %v", err)
+	}
+
+	otherLoader := resource.NewJWTLoader(func(token *jwt.Token) (interface{}, error) {
+		return []byte("11111111"), nil
+	})
+	if _, err := otherLoader.LoadAccessToken(context.Background(), access); !errors.Is(err, errors.ErrInvalidAccessToken) {
+		t.Fatalf("unexpected error of the invalid signature: %v", err)
+	}
+}
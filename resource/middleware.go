@@ -0,0 +1,119 @@
+package resource
+
+import (
+	"net/http"
+
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/errors"
+	"github.com/go-oauth2/oauth2/v4/server"
+)
+
+// Option the middleware option
+type Option func(*Middleware)
+
+// WithAccessTokenResolveHandler set the access token resolution of the request,
+// server.AccessTokenDefaultResolveHandler is used by default
+func WithAccessTokenResolveHandler(handler server.AccessTokenResolveHandler) Option {
+	return func(m *Middleware) {
+		m.resolveHandler = handler
+	}
+}
+
+// WithChallenge set the WWW-Authenticate challenge of the errors, e.g. the realm,
+// the scope is set by RequireScope
+func WithChallenge(challenge server.ResourceChallenge) Option {
+	return func(m *Middleware) {
+		m.challenge = challenge
+	}
+}
+
+// WithErrorHandler set the error response handler, server.WriteResourceError is used by default
+func WithErrorHandler(handler func(w http.ResponseWriter, r *http.Request, err error, challenge *server.ResourceChallenge)) Option {
+	return func(m *Middleware) {
+		m.errorHandler = handler
+	}
+}
+
+// WithScopeRegistry let RequireScope accept the parameterized scope tokens (e.g. "read:repo/123")
+// for the scope names the registry marks as parameterized when the access token has the bare name
+func WithScopeRegistry(registry *server.ScopeRegistry) Option {
+	return func(m *Middleware) {
+		m.parameterized = registry.IsParameterized
+	}
+}
+
+// New create the middleware validating the access tokens with the loader
+func New(loader TokenLoader, opts ...Option) *Middleware {
+	m := &Middleware{
+		loader:         loader,
+		resolveHandler: server.AccessTokenDefaultResolveHandler,
+		errorHandler: func(w http.ResponseWriter, r *http.Request, err error, challenge *server.ResourceChallenge) {
+			_ = server.WriteResourceError(w, err, challenge)
+		},
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Middleware the net/http middleware of the protected resources
+type Middleware struct {
+	loader         TokenLoader
+	resolveHandler server.AccessTokenResolveHandler
+	challenge      server.ResourceChallenge
+	errorHandler   func(w http.ResponseWriter, r *http.Request, err error, challenge *server.ResourceChallenge)
+	parameterized  func(name string) bool
+}
+
+func (m *Middleware) writeError(w http.ResponseWriter, r *http.Request, err error, scope oauth2.Scope) {
+	challenge := m.challenge
+	if len(scope) > 0 {
+		challenge.Scope = scope.String()
+	}
+	m.errorHandler(w, r, err, &challenge)
+}
+
+// Handler validate the access token of the request and store its information in the request context,
+// the requests without a valid access token are rejected
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		access, ok := m.resolveHandler(r)
+		if !ok {
+			m.writeError(w, r, errors.ErrMissingAccessToken, nil)
+			return
+		}
+
+		ti, err := m.loader.LoadAccessToken(r.Context(), access)
+		if err != nil {
+			m.writeError(w, r, err, nil)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithTokenInfo(r.Context(), ti)))
+	})
+}
+
+// RequireScope reject the requests whose access token doesn't have all the scope tokens exactly,
+// see WithScopeRegistry for the parameterized scopes,
+// it validates the access token if the Handler hasn't done it yet
+func (m *Middleware) RequireScope(scope ...string) func(http.Handler) http.Handler {
+	required := oauth2.Scope(scope)
+	return func(next http.Handler) http.Handler {
+		check := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ti, _ := TokenInfoFromContext(r.Context())
+			if !ti.GetScope().Contains(required, m.parameterized) {
+				m.writeError(w, r, errors.ErrInsufficientScope, required)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := TokenInfoFromContext(r.Context()); ok {
+				check.ServeHTTP(w, r)
+				return
+			}
+			m.Handler(check).ServeHTTP(w, r)
+		})
+	}
+}
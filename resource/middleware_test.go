@@ -0,0 +1,143 @@
+package resource_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect"
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/manage"
+	"github.com/go-oauth2/oauth2/v4/models"
+	"github.com/go-oauth2/oauth2/v4/resource"
+	"github.com/go-oauth2/oauth2/v4/server"
+	"github.com/go-oauth2/oauth2/v4/store"
+)
+
+func newManager(t *testing.T) *manage.Manager {
+	manager := manage.NewDefaultManager()
+	manager.MustTokenStorage(store.NewMemoryTokenStore())
+	clientStore := store.NewClientStore()
+	clientStore.Set("111111", &models.Client{ID: "111111", Secret: "11111111"})
+	manager.MapClientStorage(clientStore)
+	return manager
+}
+
+func generateToken(t *testing.T, manager *manage.Manager, scope string) string {
+	ti, err := manager.GenerateAccessToken(context.Background(), oauth2.ClientCredentials, &oauth2.TokenGenerateRequest{
+		ClientID:     "111111",
+		ClientSecret: "11111111",
+		Scope:        oauth2.ParseScope(scope),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ti.GetAccess()
+}
+
+func TestMiddleware(t *testing.T) {
+	manager := newManager(t)
+	m := resource.New(manager, resource.WithChallenge(server.ResourceChallenge{Realm: "photos"}))
+
+	mux := http.NewServeMux()
+	mux.Handle("/profile", m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ti, ok := resource.TokenInfoFromContext(r.Context())
+		if !ok || ti.GetAccess() == "" {
+			t.Error("the token information isn't in the context")
+		}
+		_, _ = w.Write([]byte(resource.ClientIDFromContext(r.Context()) + " " + resource.ScopeFromContext(r.Context()).String()))
+	})))
+	mux.Handle("/photos", m.RequireScope("photos:read")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("photos"))
+	})))
+	tsrv := httptest.NewServer(mux)
+	defer tsrv.Close()
+	e := httpexpect.New(t, tsrv.URL)
+
+	e.GET("/profile").
+		Expect().
+		Status(http.StatusUnauthorized).
+		Header("WWW-Authenticate").Equal(`Bearer realm="photos"`)
+
+	e.GET("/profile").
+		WithHeader("Authorization", "Bearer invalid").
+		Expect().
+		Status(http.StatusUnauthorized).
+		Header("WWW-Authenticate").Contains(`error="invalid_token"`)
+
+	access := generateToken(t, manager, "profile photos:read")
+	e.GET("/profile").
+		WithHeader("Authorization", "Bearer "+access).
+		Expect().
+		Status(http.StatusOK).
+		Body().Equal("111111 profile photos:read")
+
+	e.GET("/photos").
+		WithHeader("Authorization", "Bearer "+access).
+		Expect().
+		Status(http.StatusOK).
+		Body().Equal("photos")
+
+	res := e.GET("/photos").
+		WithHeader("Authorization", "Bearer "+generateToken(t, manager, "profile")).
+		Expect().
+		Status(http.StatusForbidden)
+	res.Header("WWW-Authenticate").Equal(`Bearer realm="photos", error="insufficient_scope", error_description="The request requires higher privileges than provided by the access token", scope="photos:read"`)
+	res.JSON().Object().ValueEqual("error", "insufficient_scope")
+}
+
+func TestRequireParameterizedScope(t *testing.T) {
+	manager := newManager(t)
+	registry := server.NewScopeRegistry(&server.ScopeDefinition{Name: "read:repo", Parameterized: true})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("repo"))
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("/exact", resource.New(manager).RequireScope("read:repo/123")(handler))
+	mux.Handle("/registry", resource.New(manager, resource.WithScopeRegistry(registry)).RequireScope("read:repo/123")(handler))
+	mux.Handle("/unregistered", resource.New(manager, resource.WithScopeRegistry(registry)).RequireScope("write:repo/123")(handler))
+	tsrv := httptest.NewServer(mux)
+	defer tsrv.Close()
+	e := httpexpect.New(t, tsrv.URL)
+
+	access := generateToken(t, manager, "read:repo write:repo")
+	e.GET("/exact").
+		WithHeader("Authorization", "Bearer "+access).
+		Expect().
+		Status(http.StatusForbidden)
+
+	e.GET("/registry").
+		WithHeader("Authorization", "Bearer "+access).
+		Expect().
+		Status(http.StatusOK).
+		Body().Equal("repo")
+
+	e.GET("/unregistered").
+		WithHeader("Authorization", "Bearer "+access).
+		Expect().
+		Status(http.StatusForbidden)
+
+	e.GET("/exact").
+		WithHeader("Authorization", "Bearer "+generateToken(t, manager, "read:repo/123")).
+		Expect().
+		Status(http.StatusOK)
+}
+
+func TestContextAccessors(t *testing.T) {
+	ctx := context.Background()
+	if _, ok := resource.TokenInfoFromContext(ctx); ok {
+		t.Fatal("unexpected token information")
+	}
+	if resource.ClientIDFromContext(ctx) != "" || resource.UserIDFromContext(ctx) != "" || resource.ScopeFromContext(ctx) != nil {
+		t.Fatal("unexpected token information")
+	}
+
+	ti := &models.Token{ClientID: "111111", UserID: "000000", Scope: "read write"}
+	ctx = resource.WithTokenInfo(ctx, ti)
+	if resource.ClientIDFromContext(ctx) != "111111" || resource.UserIDFromContext(ctx) != "000000" ||
+		!resource.ScopeFromContext(ctx).Has("write") {
+		t.Fatal("unexpected token information")
+	}
+}
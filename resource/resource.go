@@ -0,0 +1,66 @@
+// Package resource protects the resource server endpoints with the access tokens
+// issued by the authorization server:
+//
+//	m := resource.New(manager) // or NewJWTLoader(keyFunc), NewIntrospectionClient(endpoint, id, secret)
+//	http.Handle("/photos", m.Handler(m.RequireScope("photos:read")(photosHandler)))
+//
+// The handlers get the token information with TokenInfoFromContext,
+// the invalid requests are answered with the RFC 6750 error challenges.
+package resource
+
+import (
+	"context"
+
+	"github.com/go-oauth2/oauth2/v4"
+)
+
+// TokenLoader load the token information of the access token, it's implemented by
+// oauth2.Manager (store-backed), JWTLoader and IntrospectionClient
+type TokenLoader interface {
+	LoadAccessToken(ctx context.Context, access string) (oauth2.TokenInfo, error)
+}
+
+// TokenLoaderFunc the function adapter of the token loader
+type TokenLoaderFunc func(ctx context.Context, access string) (oauth2.TokenInfo, error)
+
+// LoadAccessToken call f(ctx, access)
+func (f TokenLoaderFunc) LoadAccessToken(ctx context.Context, access string) (oauth2.TokenInfo, error) {
+	return f(ctx, access)
+}
+
+type tokenInfoKey struct{}
+
+// WithTokenInfo returns the context carrying the token information of the request
+func WithTokenInfo(ctx context.Context, ti oauth2.TokenInfo) context.Context {
+	return context.WithValue(ctx, tokenInfoKey{}, ti)
+}
+
+// TokenInfoFromContext get the token information of the request from the context
+func TokenInfoFromContext(ctx context.Context) (oauth2.TokenInfo, bool) {
+	ti, ok := ctx.Value(tokenInfoKey{}).(oauth2.TokenInfo)
+	return ti, ok
+}
+
+// ClientIDFromContext get the client ID of the access token from the context
+func ClientIDFromContext(ctx context.Context) string {
+	if ti, ok := TokenInfoFromContext(ctx); ok {
+		return ti.GetClientID()
+	}
+	return ""
+}
+
+// UserIDFromContext get the user ID of the access token from the context
+func UserIDFromContext(ctx context.Context) string {
+	if ti, ok := TokenInfoFromContext(ctx); ok {
+		return ti.GetUserID()
+	}
+	return ""
+}
+
+// ScopeFromContext get the scope of the access token from the context
+func ScopeFromContext(ctx context.Context) oauth2.Scope {
+	if ti, ok := TokenInfoFromContext(ctx); ok {
+		return ti.GetScope()
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package oauth2
+
+import (
+	"strings"
+)
+
+// ScopeParamSeparator separates the name of a parameterized scope from its parameter, e.g. read:repo/123
+const ScopeParamSeparator = "/"
+
+// Scope the parsed set of scope tokens, in the requested order without duplicates
+type Scope []string
+
+// ParseScope parse the space-delimited scope
+func ParseScope(s string) Scope {
+	var scope Scope
+	for _, v := range strings.Fields(s) {
+		if !scope.Has(v) {
+			scope = append(scope, v)
+		}
+	}
+	return scope
+}
+
+// String the space-delimited scope
+func (s Scope) String() string {
+	return strings.Join(s, " ")
+}
+
+// Has whether the scope contains exactly the token
+func (s Scope) Has(token string) bool {
+	for _, v := range s {
+		if v == token {
+			return true
+		}
+	}
+	return false
+}
+
+// Covers whether the token is granted by the scope, a parameterized token like read:repo/123
+// is also covered by its name read:repo if parameterized reports the name accepts a parameter
+// (e.g. the server ScopeRegistry), a nil parameterized only matches the exact tokens
+func (s Scope) Covers(token string, parameterized func(name string) bool) bool {
+	if s.Has(token) {
+		return true
+	}
+	if name := ScopeName(token); name != token && parameterized != nil && parameterized(name) {
+		return s.Has(name)
+	}
+	return false
+}
+
+// Contains whether every token of the other scope is covered by the scope, see Covers
+func (s Scope) Contains(other Scope, parameterized func(name string) bool) bool {
+	for _, v := range other {
+		if !s.Covers(v, parameterized) {
+			return false
+		}
+	}
+	return true
+}
+
+// Union the tokens of the scope followed by the tokens of the other scope it doesn't have
+func (s Scope) Union(other Scope) Scope {
+	scope := append(Scope{}, s...)
+	for _, v := range other {
+		if !scope.Has(v) {
+			scope = append(scope, v)
+		}
+	}
+	return scope
+}
+
+// Difference the tokens of the scope the other scope doesn't have
+func (s Scope) Difference(other Scope) Scope {
+	var scope Scope
+	for _, v := range s {
+		if !other.Has(v) {
+			scope = append(scope, v)
+		}
+	}
+	return scope
+}
+
+// Intersect the tokens of the scope the other scope has too
+func (s Scope) Intersect(other Scope) Scope {
+	var scope Scope
+	for _, v := range s {
+		if other.Has(v) {
+			scope = append(scope, v)
+		}
+	}
+	return scope
+}
+
+// ScopeName the name of the scope token without its parameter
+func ScopeName(token string) string {
+	if i := strings.Index(token, ScopeParamSeparator); i > 0 {
+		return token[:i]
+	}
+	return token
+}
@@ -0,0 +1,63 @@
+package oauth2_test
+
+import (
+	"testing"
+
+	"github.com/go-oauth2/oauth2/v4"
+)
+
+func TestParseScope(t *testing.T) {
+	scope := oauth2.ParseScope("  read write  read ")
+	if len(scope) != 2 || scope.String() != "read write" {
+		t.Fatalf("unexpected scope: %q", scope)
+	}
+	if oauth2.ParseScope("").String() != "" {
+		t.Fatal("empty scope not empty")
+	}
+}
+
+func TestScopeCovers(t *testing.T) {
+	parameterized := func(name string) bool { return name == "read:repo" }
+	scope := oauth2.ParseScope("read:repo write")
+	if !scope.Covers("read:repo/123", parameterized) || !scope.Covers("write", parameterized) {
+		t.Fatal("not covered")
+	}
+	if scope.Covers("read:repository", parameterized) || scope.Covers("read", parameterized) {
+		t.Fatal("unexpectedly covered")
+	}
+	if !scope.Contains(oauth2.ParseScope("read:repo/1 write"), parameterized) ||
+		scope.Contains(oauth2.ParseScope("admin"), parameterized) {
+		t.Fatal("invalid contains")
+	}
+	if oauth2.ParseScope("read:repo/1").Covers("read:repo", parameterized) {
+		t.Fatal("parameterized scope covers its name")
+	}
+	if oauth2.ScopeName("read:repo/1") != "read:repo" || oauth2.ScopeName("read") != "read" {
+		t.Fatal("invalid scope name")
+	}
+
+	// only the parameterized names cover their parameters
+	if scope.Covers("write/admin", parameterized) || scope.Covers("read:repo/123", nil) {
+		t.Fatal("the name that isn't parameterized covers a parameter")
+	}
+	if oauth2.ParseScope("https://api.example.com").Covers("https://api.example.com/admin", parameterized) {
+		t.Fatal("the URL scope covers its sub-path")
+	}
+}
+
+func TestScopeSetOperations(t *testing.T) {
+	a := oauth2.ParseScope("read write")
+	b := oauth2.ParseScope("write admin")
+	if v := a.Union(b).String(); v != "read write admin" {
+		t.Fatalf("unexpected union: %s", v)
+	}
+	if v := a.Difference(b).String(); v != "read" {
+		t.Fatalf("unexpected difference: %s", v)
+	}
+	if v := a.Intersect(b).String(); v != "write" {
+		t.Fatalf("unexpected intersection: %s", v)
+	}
+	if a.String() != "read write" {
+		t.Fatal("the scope was modified")
+	}
+}
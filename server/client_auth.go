@@ -0,0 +1,261 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/x509"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/errors"
+	"github.com/go-oauth2/oauth2/v4/models"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// the client authentication methods of the token endpoint
+// https://www.iana.org/assignments/oauth-parameters/oauth-parameters.xhtml#token-endpoint-auth-method
+const (
+	AuthMethodClientSecretBasic = "client_secret_basic"
+	AuthMethodClientSecretPost  = "client_secret_post"
+	AuthMethodClientSecretJWT   = "client_secret_jwt"
+	AuthMethodPrivateKeyJWT     = "private_key_jwt"
+	AuthMethodTLSClientAuth     = "tls_client_auth"
+	AuthMethodNone              = "none"
+)
+
+// ClientAssertionTypeJWTBearer the client_assertion_type of the JWT client assertions (RFC 7523)
+const ClientAssertionTypeJWTBearer = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+// DefaultAssertionMaxLifetime the maximum lifetime of the client assertions
+const DefaultAssertionMaxLifetime = time.Minute * 5
+
+// NewClientAuthenticator create the client authenticator supporting the secret methods and none,
+// the audience is the aud the client assertions must have, usually the token endpoint URL,
+// set the handlers to support private_key_jwt and tls_client_auth
+func NewClientAuthenticator(manager oauth2.Manager, audience string) *ClientAuthenticator {
+	return &ClientAuthenticator{Manager: manager, Audience: audience}
+}
+
+// ClientAuthenticator authenticate the client with the method of the request:
+// client_secret_basic, client_secret_post, client_secret_jwt, private_key_jwt, tls_client_auth or none.
+// The requests using more than one method are rejected and the method has to be
+// the token_endpoint_auth_method registered by the client (oauth2.ClientAuthMethodInfo),
+// the clients without one use the secret methods or none if they're public
+type ClientAuthenticator struct {
+	Manager oauth2.Manager
+	// the aud of the client assertions, usually the token endpoint URL, the assertions are rejected if empty
+	Audience string
+	// the maximum time between now and the exp of the client assertions, DefaultAssertionMaxLifetime if 0
+	AssertionMaxLifetime time.Duration
+	// get the key verifying the private_key_jwt assertion of the client, e.g. from its JWKS
+	AssertionKeyHandler func(ctx context.Context, cli oauth2.ClientInfo, token *jwt.Token) (interface{}, error)
+	// verify the TLS client certificate is the one registered by the client (RFC 8705)
+	CertificateHandler func(ctx context.Context, cli oauth2.ClientInfo, cert *x509.Certificate) error
+
+	// the jti of the used client assertions until they expire, they're local to the server instance
+	mu          sync.Mutex
+	assertions  map[string]time.Time
+	lastCleanup time.Time
+}
+
+// SupportedMethods the token endpoint authentication methods supported by the authenticator
+func (a *ClientAuthenticator) SupportedMethods() []string {
+	methods := []string{AuthMethodClientSecretBasic, AuthMethodClientSecretPost, AuthMethodClientSecretJWT}
+	if a.AssertionKeyHandler != nil {
+		methods = append(methods, AuthMethodPrivateKeyJWT)
+	}
+	if a.CertificateHandler != nil {
+		methods = append(methods, AuthMethodTLSClientAuth)
+	}
+	return append(methods, AuthMethodNone)
+}
+
+// Authenticate authenticate the client of the request,
+// it's the ClientAuthenticationHandler of the server
+func (a *ClientAuthenticator) Authenticate(r *http.Request) (oauth2.ClientInfo, string, error) {
+	ctx := r.Context()
+	clientID := r.FormValue("client_id")
+
+	// the client must not use more than one authentication method
+	// https://tools.ietf.org/html/rfc6749#section-2.3
+	used := 0
+	username, password, isBasic := r.BasicAuth()
+	if isBasic {
+		used++
+	}
+	if r.Form.Get("client_secret") != "" {
+		used++
+	}
+	if r.Form.Get("client_assertion") != "" || r.Form.Get("client_assertion_type") != "" {
+		used++
+	}
+	if used > 1 {
+		return nil, "", errors.ErrInvalidRequest
+	}
+
+	switch {
+	case isBasic:
+		if clientID != "" && clientID != username {
+			return nil, "", errors.ErrInvalidRequest
+		}
+		cli, err := a.verifySecret(ctx, username, password)
+		if err != nil {
+			return nil, "", err
+		}
+		return a.checkMethod(cli, AuthMethodClientSecretBasic)
+	case r.Form.Get("client_secret") != "":
+		cli, err := a.verifySecret(ctx, clientID, r.Form.Get("client_secret"))
+		if err != nil {
+			return nil, "", err
+		}
+		return a.checkMethod(cli, AuthMethodClientSecretPost)
+	case used == 1:
+		return a.verifyAssertion(ctx, clientID, r.Form.Get("client_assertion_type"), r.Form.Get("client_assertion"))
+	}
+
+	if clientID == "" {
+		return nil, "", errors.ErrInvalidClient
+	}
+	cli, err := a.Manager.GetClient(ctx, clientID)
+	if err != nil {
+		return nil, "", errors.ErrInvalidClient
+	}
+
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 && a.CertificateHandler != nil &&
+		registeredAuthMethod(cli) == AuthMethodTLSClientAuth {
+		if err := a.CertificateHandler(ctx, cli, r.TLS.PeerCertificates[0]); err != nil {
+			return nil, "", errors.ErrInvalidClient
+		}
+		return cli, AuthMethodTLSClientAuth, nil
+	}
+	return a.checkMethod(cli, AuthMethodNone)
+}
+
+// registeredAuthMethod the token endpoint authentication method registered by the client
+func registeredAuthMethod(cli oauth2.ClientInfo) string {
+	if mi, ok := cli.(oauth2.ClientAuthMethodInfo); ok {
+		return mi.GetTokenEndpointAuthMethod()
+	}
+	return ""
+}
+
+// checkMethod check the client uses its registered authentication method
+func (a *ClientAuthenticator) checkMethod(cli oauth2.ClientInfo, method string) (oauth2.ClientInfo, string, error) {
+	registered := registeredAuthMethod(cli)
+	switch {
+	case registered != "":
+		if registered != method {
+			return nil, "", errors.ErrInvalidClient
+		}
+	case cli.IsPublic():
+		if method != AuthMethodNone {
+			return nil, "", errors.ErrInvalidClient
+		}
+	case method != AuthMethodClientSecretBasic && method != AuthMethodClientSecretPost:
+		return nil, "", errors.ErrInvalidClient
+	}
+	return cli, method, nil
+}
+
+func (a *ClientAuthenticator) verifySecret(ctx context.Context, clientID, secret string) (oauth2.ClientInfo, error) {
+	if clientID == "" || secret == "" {
+		return nil, errors.ErrInvalidClient
+	}
+	cli, err := a.Manager.GetClient(ctx, clientID)
+	if err != nil {
+		return nil, errors.ErrInvalidClient
+	}
+
+	if v, ok := cli.(oauth2.ClientPasswordVerifier); ok {
+		if v.VerifyPassword(secret) {
+			return cli, nil
+		}
+	} else if subtle.ConstantTimeCompare([]byte(cli.GetSecret()), []byte(secret)) == 1 {
+		return cli, nil
+	}
+	return nil, errors.ErrInvalidClient
+}
+
+// useAssertion record the jti of the client assertion, it fails if the assertion was already used
+func (a *ClientAuthenticator) useAssertion(clientID, jti string, exp time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	if a.assertions == nil {
+		a.assertions = make(map[string]time.Time)
+	}
+	if now.Sub(a.lastCleanup) >= time.Minute {
+		a.lastCleanup = now
+		for key, v := range a.assertions {
+			if v.Before(now) {
+				delete(a.assertions, key)
+			}
+		}
+	}
+
+	key := clientID + " " + jti
+	if v, ok := a.assertions[key]; ok && !v.Before(now) {
+		return false
+	}
+	a.assertions[key] = exp
+	return true
+}
+
+// verifyAssertion verify the JWT client assertion, client_secret_jwt is signed with the client secret,
+// the assertion must have a jti, expire within AssertionMaxLifetime and can't be used twice
+// https://tools.ietf.org/html/rfc7523#section-2.2
+func (a *ClientAuthenticator) verifyAssertion(ctx context.Context, clientID, assertionType, assertion string) (oauth2.ClientInfo, string, error) {
+	if assertionType != ClientAssertionTypeJWTBearer || assertion == "" || a.Audience == "" {
+		return nil, "", errors.ErrInvalidClient
+	}
+
+	// the subject of the assertion is the client
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(assertion, claims); err != nil {
+		return nil, "", errors.ErrInvalidClient
+	}
+	sub, _ := claims.GetSubject()
+	if sub == "" || (clientID != "" && clientID != sub) {
+		return nil, "", errors.ErrInvalidClient
+	}
+	cli, err := a.Manager.GetClient(ctx, sub)
+	if err != nil {
+		return nil, "", errors.ErrInvalidClient
+	}
+
+	method := AuthMethodPrivateKeyJWT
+	claims = jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(assertion, claims, func(token *jwt.Token) (interface{}, error) {
+		if strings.HasPrefix(token.Method.Alg(), "HS") {
+			method = AuthMethodClientSecretJWT
+			// the hashed secrets can't be the HMAC keys
+			if secret := cli.GetSecret(); secret == "" || models.IsHashedSecret(secret) {
+				return nil, errors.ErrInvalidClient
+			}
+			return []byte(cli.GetSecret()), nil
+		}
+		if a.AssertionKeyHandler == nil {
+			return nil, errors.ErrInvalidClient
+		}
+		return a.AssertionKeyHandler(ctx, cli, token)
+	}, jwt.WithIssuer(sub), jwt.WithSubject(sub), jwt.WithAudience(a.Audience), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, "", errors.ErrInvalidClient
+	}
+
+	maxLifetime := a.AssertionMaxLifetime
+	if maxLifetime <= 0 {
+		maxLifetime = DefaultAssertionMaxLifetime
+	}
+	exp, _ := claims.GetExpirationTime()
+	jti, _ := claims["jti"].(string)
+	if jti == "" || exp == nil || exp.After(time.Now().Add(maxLifetime)) || !a.useAssertion(sub, jti, exp.Time) {
+		return nil, "", errors.ErrInvalidClient
+	}
+
+	return a.checkMethod(cli, method)
+}
@@ -0,0 +1,275 @@
+package server_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gavv/httpexpect"
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/errors"
+	"github.com/go-oauth2/oauth2/v4/models"
+	"github.com/go-oauth2/oauth2/v4/server"
+	"github.com/go-oauth2/oauth2/v4/store"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var assertionID int
+
+func clientAssertion(t *testing.T, clientID, audience string, method jwt.SigningMethod, key interface{}) string {
+	assertionID++
+	return signAssertion(t, method, key, jwt.RegisteredClaims{
+		Issuer:    clientID,
+		Subject:   clientID,
+		Audience:  jwt.ClaimStrings{audience},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		ID:        fmt.Sprintf("jti-%d", assertionID),
+	})
+}
+
+func signAssertion(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.RegisteredClaims) string {
+	assertion, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return assertion
+}
+
+func TestClientAuthenticator(t *testing.T) {
+	audience := "https://as.example.com/token"
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cs := store.NewClientStore()
+	cs.Set("basic", &models.Client{ID: "basic", Secret: "secret"})
+	cs.Set("post", &models.Client{ID: "post", Secret: "secret", TokenEndpointAuthMethod: server.AuthMethodClientSecretPost})
+	cs.Set("public", &models.Client{ID: "public", Public: true})
+	cs.Set("hs", &models.Client{ID: "hs", Secret: "0123456789abcdef0123456789abcdef", TokenEndpointAuthMethod: server.AuthMethodClientSecretJWT})
+	cs.Set("es", &models.Client{ID: "es", TokenEndpointAuthMethod: server.AuthMethodPrivateKeyJWT})
+	cs.Set("mtls", &models.Client{ID: "mtls", TokenEndpointAuthMethod: server.AuthMethodTLSClientAuth})
+	hashed, err := models.DefaultSecretHasher.Hash("0123456789abcdef0123456789abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs.Set("hashed", &models.Client{ID: "hashed", Secret: hashed, TokenEndpointAuthMethod: server.AuthMethodClientSecretJWT})
+	manager.MapClientStorage(cs)
+
+	a := server.NewClientAuthenticator(manager, audience)
+	a.AssertionKeyHandler = func(ctx context.Context, cli oauth2.ClientInfo, token *jwt.Token) (interface{}, error) {
+		if cli.GetID() != "es" {
+			return nil, errors.ErrInvalidClient
+		}
+		return &privateKey.PublicKey, nil
+	}
+	a.CertificateHandler = func(ctx context.Context, cli oauth2.ClientInfo, cert *x509.Certificate) error {
+		if cert.Subject.CommonName != cli.GetID() {
+			return errors.ErrInvalidClient
+		}
+		return nil
+	}
+
+	authenticate := func(form url.Values, setup func(r *http.Request)) (string, string, error) {
+		r := httptest.NewRequest("POST", "/token", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if setup != nil {
+			setup(r)
+		}
+		cli, method, err := a.Authenticate(r)
+		if err != nil {
+			return "", "", err
+		}
+		return cli.GetID(), method, nil
+	}
+	basicAuth := func(id, secret string) func(r *http.Request) {
+		return func(r *http.Request) {
+			r.SetBasicAuth(id, secret)
+		}
+	}
+
+	replayed := clientAssertion(t, "es", audience, jwt.SigningMethodES256, privateKey)
+	if _, _, err := authenticate(url.Values{
+		"client_assertion_type": {server.ClientAssertionTypeJWTBearer},
+		"client_assertion":      {replayed},
+	}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		form   url.Values
+		setup  func(r *http.Request)
+		id     string
+		method string
+		err    error
+	}{
+		{"basic", url.Values{}, basicAuth("basic", "secret"), "basic", server.AuthMethodClientSecretBasic, nil},
+		{"post without registration", url.Values{"client_id": {"basic"}, "client_secret": {"secret"}}, nil, "basic", server.AuthMethodClientSecretPost, nil},
+		{"invalid secret", url.Values{}, basicAuth("basic", "invalid"), "", "", errors.ErrInvalidClient},
+		{"without credentials", url.Values{"client_id": {"basic"}}, nil, "", "", errors.ErrInvalidClient},
+		{"several methods", url.Values{"client_secret": {"secret"}}, basicAuth("basic", "secret"), "", "", errors.ErrInvalidRequest},
+		{"post", url.Values{"client_id": {"post"}, "client_secret": {"secret"}}, nil, "post", server.AuthMethodClientSecretPost, nil},
+		{"unregistered method", url.Values{}, basicAuth("post", "secret"), "", "", errors.ErrInvalidClient},
+		{"none", url.Values{"client_id": {"public"}}, nil, "public", server.AuthMethodNone, nil},
+		{"client_secret_jwt", url.Values{
+			"client_assertion_type": {server.ClientAssertionTypeJWTBearer},
+			"client_assertion":      {clientAssertion(t, "hs", audience, jwt.SigningMethodHS256, []byte("0123456789abcdef0123456789abcdef"))},
+		}, nil, "hs", server.AuthMethodClientSecretJWT, nil},
+		{"private_key_jwt", url.Values{
+			"client_id":             {"es"},
+			"client_assertion_type": {server.ClientAssertionTypeJWTBearer},
+			"client_assertion":      {clientAssertion(t, "es", audience, jwt.SigningMethodES256, privateKey)},
+		}, nil, "es", server.AuthMethodPrivateKeyJWT, nil},
+		{"invalid audience", url.Values{
+			"client_assertion_type": {server.ClientAssertionTypeJWTBearer},
+			"client_assertion":      {clientAssertion(t, "es", "https://other.example.com", jwt.SigningMethodES256, privateKey)},
+		}, nil, "", "", errors.ErrInvalidClient},
+		{"another client_id", url.Values{
+			"client_id":             {"hs"},
+			"client_assertion_type": {server.ClientAssertionTypeJWTBearer},
+			"client_assertion":      {clientAssertion(t, "es", audience, jwt.SigningMethodES256, privateKey)},
+		}, nil, "", "", errors.ErrInvalidClient},
+		{"assertion with basic", url.Values{
+			"client_assertion_type": {server.ClientAssertionTypeJWTBearer},
+			"client_assertion":      {clientAssertion(t, "es", audience, jwt.SigningMethodES256, privateKey)},
+		}, basicAuth("basic", "secret"), "", "", errors.ErrInvalidRequest},
+		{"client_secret_jwt with hashed secret", url.Values{
+			"client_assertion_type": {server.ClientAssertionTypeJWTBearer},
+			"client_assertion":      {clientAssertion(t, "hashed", audience, jwt.SigningMethodHS256, []byte(hashed))},
+		}, nil, "", "", errors.ErrInvalidClient},
+		{"replayed assertion", url.Values{
+			"client_assertion_type": {server.ClientAssertionTypeJWTBearer},
+			"client_assertion":      {replayed},
+		}, nil, "", "", errors.ErrInvalidClient},
+		{"assertion without jti", url.Values{
+			"client_assertion_type": {server.ClientAssertionTypeJWTBearer},
+			"client_assertion": {signAssertion(t, jwt.SigningMethodES256, privateKey, jwt.RegisteredClaims{
+				Issuer:    "es",
+				Subject:   "es",
+				Audience:  jwt.ClaimStrings{audience},
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			})},
+		}, nil, "", "", errors.ErrInvalidClient},
+		{"long lived assertion", url.Values{
+			"client_assertion_type": {server.ClientAssertionTypeJWTBearer},
+			"client_assertion": {signAssertion(t, jwt.SigningMethodES256, privateKey, jwt.RegisteredClaims{
+				Issuer:    "es",
+				Subject:   "es",
+				Audience:  jwt.ClaimStrings{audience},
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+				ID:        "long",
+			})},
+		}, nil, "", "", errors.ErrInvalidClient},
+		{"tls_client_auth", url.Values{"client_id": {"mtls"}}, func(r *http.Request) {
+			r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "mtls"}}}}
+		}, "mtls", server.AuthMethodTLSClientAuth, nil},
+		{"invalid certificate", url.Values{"client_id": {"mtls"}}, func(r *http.Request) {
+			r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "other"}}}}
+		}, "", "", errors.ErrInvalidClient},
+		{"tls_client_auth without certificate", url.Values{"client_id": {"mtls"}}, nil, "", "", errors.ErrInvalidClient},
+	}
+	for _, c := range cases {
+		id, method, err := authenticate(c.form, c.setup)
+		if !errors.Is(err, c.err) || (c.err == nil && err != nil) || id != c.id || method != c.method {
+			t.Errorf("%s: unexpected authentication %s %s %v", c.name, id, method, err)
+		}
+	}
+
+	// the assertions are rejected without an audience
+	a.Audience = ""
+	if _, _, err := authenticate(url.Values{
+		"client_assertion_type": {server.ClientAssertionTypeJWTBearer},
+		"client_assertion":      {clientAssertion(t, "es", audience, jwt.SigningMethodES256, privateKey)},
+	}, nil); !errors.Is(err, errors.ErrInvalidClient) {
+		t.Errorf("unexpected authentication without audience: %v", err)
+	}
+}
+
+func TestClientAuthenticationHandler(t *testing.T) {
+	tsrv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testServer(t, w, r)
+	}))
+	defer tsrv.Close()
+	e := httpexpect.New(t, tsrv.URL)
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	cs := store.NewClientStore()
+	cs.Set(clientID, &models.Client{ID: clientID, Secret: string(key), TokenEndpointAuthMethod: server.AuthMethodClientSecretJWT})
+	manager.MapClientStorage(cs)
+
+	srv = server.NewDefaultServer(manager)
+	a := server.NewClientAuthenticator(manager, tsrv.URL+"/token")
+	srv.SetClientAuthenticationHandler(a.Authenticate)
+
+	e.POST("/token").
+		WithFormField("grant_type", "client_credentials").
+		WithFormField("client_assertion_type", server.ClientAssertionTypeJWTBearer).
+		WithFormField("client_assertion", clientAssertion(t, clientID, tsrv.URL+"/token", jwt.SigningMethodHS256, key)).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().ContainsKey("access_token")
+
+	// the client has to use its registered method
+	e.POST("/token").
+		WithFormField("grant_type", "client_credentials").
+		WithBasicAuth(clientID, string(key)).
+		Expect().
+		Status(http.StatusUnauthorized).
+		JSON().Object().ValueEqual("error", "invalid_client")
+}
+
+func TestClientInfoHandlerAuthMethod(t *testing.T) {
+	tsrv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testServer(t, w, r)
+	}))
+	defer tsrv.Close()
+	e := httpexpect.New(t, tsrv.URL)
+
+	cs := store.NewClientStore()
+	cs.Set(clientID, &models.Client{ID: clientID, Secret: clientSecret, TokenEndpointAuthMethod: server.AuthMethodClientSecretPost})
+	cs.Set("jwt", &models.Client{ID: "jwt", Secret: clientSecret, TokenEndpointAuthMethod: server.AuthMethodClientSecretJWT})
+	manager.MapClientStorage(cs)
+
+	// the clients identified by the ClientInfoHandler use their registered method too
+	srv = server.NewDefaultServer(manager)
+	srv.SetClientInfoHandler(func(r *http.Request) (string, string, error) {
+		if id, secret, ok := r.BasicAuth(); ok {
+			return id, secret, nil
+		}
+		return server.ClientFormHandler(r)
+	})
+
+	e.POST("/token").
+		WithFormField("grant_type", "client_credentials").
+		WithFormField("client_id", clientID).
+		WithFormField("client_secret", clientSecret).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().ContainsKey("access_token")
+
+	e.POST("/token").
+		WithFormField("grant_type", "client_credentials").
+		WithBasicAuth(clientID, clientSecret).
+		Expect().
+		Status(http.StatusUnauthorized).
+		JSON().Object().ValueEqual("error", "invalid_client")
+
+	e.POST("/token").
+		WithFormField("grant_type", "client_credentials").
+		WithBasicAuth("jwt", clientSecret).
+		Expect().
+		Status(http.StatusUnauthorized).
+		JSON().Object().ValueEqual("error", "invalid_client")
+}
@@ -15,6 +15,9 @@ type Config struct {
 	AllowedGrantTypes           []oauth2.GrantType    // allow the grant type
 	AllowedCodeChallengeMethods []oauth2.CodeChallengeMethod
 	ForcePKCE                   bool
+	StrictErrorStatus           bool   // respond with the error status codes of RFC 6749 instead of the legacy ones
+	Issuer                      string // the issuer identifier of the authorization responses, JWTs and metadata (RFC 9207)
+	OAuth21                     bool   // enforce the OAuth 2.1 profile whatever the allowed types and methods
 }
 
 // NewConfig create to configuration instance
@@ -35,16 +38,41 @@ func NewConfig() *Config {
 	}
 }
 
+// NewOAuth21Config create to configuration instance of the OAuth 2.1 profile:
+// the authorization code grant with S256 PKCE, no implicit and password grants,
+// exact redirect URI matching, no access tokens in the query and no GET token requests
+// https://datatracker.ietf.org/doc/html/draft-ietf-oauth-v2-1
+func NewOAuth21Config() *Config {
+	return &Config{
+		TokenType:            "Bearer",
+		AllowedResponseTypes: []oauth2.ResponseType{oauth2.Code},
+		AllowedGrantTypes: []oauth2.GrantType{
+			oauth2.AuthorizationCode,
+			oauth2.ClientCredentials,
+			oauth2.Refreshing,
+		},
+		AllowedCodeChallengeMethods: []oauth2.CodeChallengeMethod{
+			oauth2.CodeChallengeS256,
+		},
+		ForcePKCE: true,
+		OAuth21:   true,
+	}
+}
+
 // AuthorizeRequest authorization request
 type AuthorizeRequest struct {
-	ResponseType        oauth2.ResponseType
-	ClientID            string
-	Scope               string
-	RedirectURI         string
-	State               string
-	UserID              string
-	CodeChallenge       string
-	CodeChallengeMethod oauth2.CodeChallengeMethod
-	AccessTokenExp      time.Duration
-	Request             *http.Request
+	ResponseType         oauth2.ResponseType
+	ClientID             string
+	Scope                oauth2.Scope
+	RedirectURI          string
+	State                string
+	UserID               string
+	CodeChallenge        string
+	CodeChallengeMethod  oauth2.CodeChallengeMethod
+	AccessTokenExp       time.Duration
+	Resource             []string
+	AuthorizationDetails []oauth2.AuthorizationDetail
+	ResponseMode         oauth2.ResponseMode
+	Nonce                string
+	Request              *http.Request
 }
@@ -0,0 +1,237 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/errors"
+)
+
+// GrantHandler the token request handling of a grant type.
+// The client is authenticated already only if tgr.ClientAuthenticated is set by the ClientAuthenticationHandler,
+// the ClientInfoHandler only identifies the client: Issue must authenticate it, the manager does it
+// in GenerateAccessToken and RefreshAccessToken
+type GrantHandler interface {
+	// parse the grant parameters of the token request
+	ParseRequest(r *http.Request, tgr *oauth2.TokenGenerateRequest) error
+
+	// validate the token request before issuing the token, e.g. the requested scope
+	Validate(ctx context.Context, tgr *oauth2.TokenGenerateRequest) error
+
+	// authenticate the client and issue the access token, usually with Manager.GenerateAccessToken
+	Issue(ctx context.Context, tgr *oauth2.TokenGenerateRequest) (oauth2.TokenInfo, error)
+}
+
+// the built-in grant handlers
+func (s *Server) defaultGrantHandlers() map[oauth2.GrantType]GrantHandler {
+	return map[oauth2.GrantType]GrantHandler{
+		oauth2.AuthorizationCode:   &authorizationCodeGrant{s},
+		oauth2.PasswordCredentials: &passwordGrant{s},
+		oauth2.ClientCredentials:   &clientCredentialsGrant{s},
+		oauth2.Refreshing:          &refreshingGrant{s},
+	}
+}
+
+func (s *Server) grantHandler(gt oauth2.GrantType) (GrantHandler, bool) {
+	handlers := s.GrantHandlers
+	if handlers == nil {
+		handlers = s.defaultGrantHandlers()
+	}
+	gh, ok := handlers[gt]
+	return gh, ok
+}
+
+// ValidationTokenScope validate the requested scope with the scope registry and the ClientScopeHandler,
+// the registry replaces the scope with the resolved one
+func (s *Server) ValidationTokenScope(tgr *oauth2.TokenGenerateRequest) error {
+	if sr := s.ScopeRegistry; sr != nil {
+		scope, err := sr.Resolve(tgr.ClientID, tgr.Scope)
+		if err != nil {
+			return err
+		}
+		tgr.Scope = scope
+	}
+
+	if fn := s.ClientScopeHandler; fn != nil {
+		allowed, err := fn(tgr)
+		if err != nil {
+			return err
+		} else if !allowed {
+			return errors.ErrInvalidScope
+		}
+	}
+	return nil
+}
+
+// authorization code grant
+// https://tools.ietf.org/html/rfc6749#section-4.1.3
+type authorizationCodeGrant struct {
+	s *Server
+}
+
+func (g *authorizationCodeGrant) ParseRequest(r *http.Request, tgr *oauth2.TokenGenerateRequest) error {
+	tgr.RedirectURI = r.FormValue("redirect_uri")
+	tgr.Code = r.FormValue("code")
+	if tgr.RedirectURI == "" ||
+		tgr.Code == "" {
+		return errors.ErrInvalidRequest
+	}
+	tgr.CodeVerifier = r.FormValue("code_verifier")
+	return nil
+}
+
+func (g *authorizationCodeGrant) Validate(ctx context.Context, tgr *oauth2.TokenGenerateRequest) error {
+	return nil
+}
+
+func (g *authorizationCodeGrant) Issue(ctx context.Context, tgr *oauth2.TokenGenerateRequest) (oauth2.TokenInfo, error) {
+	ti, err := g.s.Manager.GenerateAccessToken(ctx, oauth2.AuthorizationCode, tgr)
+	if err != nil {
+		switch {
+		case errors.Is(err, errors.ErrInvalidAuthorizeCode),
+			errors.Is(err, errors.ErrInvalidCodeChallenge),
+			errors.Is(err, errors.ErrMissingCodeChallenge):
+			return nil, errors.ErrInvalidGrant
+		default:
+			return nil, err
+		}
+	}
+	return ti, nil
+}
+
+// resource owner password credentials grant
+// https://tools.ietf.org/html/rfc6749#section-4.3.2
+type passwordGrant struct {
+	s *Server
+}
+
+func (g *passwordGrant) ParseRequest(r *http.Request, tgr *oauth2.TokenGenerateRequest) error {
+	tgr.Scope = oauth2.ParseScope(r.FormValue("scope"))
+	username, password := r.FormValue("username"), r.FormValue("password")
+	if username == "" || password == "" {
+		return errors.ErrInvalidRequest
+	}
+
+	userID, err := g.s.PasswordAuthorizationHandler(r.Context(), tgr.ClientID, username, password)
+	if err != nil {
+		return err
+	} else if userID == "" {
+		return errors.ErrInvalidGrant
+	}
+	tgr.UserID = userID
+	return nil
+}
+
+func (g *passwordGrant) Validate(ctx context.Context, tgr *oauth2.TokenGenerateRequest) error {
+	return g.s.ValidationTokenScope(tgr)
+}
+
+func (g *passwordGrant) Issue(ctx context.Context, tgr *oauth2.TokenGenerateRequest) (oauth2.TokenInfo, error) {
+	return g.s.Manager.GenerateAccessToken(ctx, oauth2.PasswordCredentials, tgr)
+}
+
+// client credentials grant
+// https://tools.ietf.org/html/rfc6749#section-4.4.2
+type clientCredentialsGrant struct {
+	s *Server
+}
+
+func (g *clientCredentialsGrant) ParseRequest(r *http.Request, tgr *oauth2.TokenGenerateRequest) error {
+	tgr.Scope = oauth2.ParseScope(r.FormValue("scope"))
+	return nil
+}
+
+func (g *clientCredentialsGrant) Validate(ctx context.Context, tgr *oauth2.TokenGenerateRequest) error {
+	return g.s.ValidationTokenScope(tgr)
+}
+
+func (g *clientCredentialsGrant) Issue(ctx context.Context, tgr *oauth2.TokenGenerateRequest) (oauth2.TokenInfo, error) {
+	return g.s.Manager.GenerateAccessToken(ctx, oauth2.ClientCredentials, tgr)
+}
+
+// refreshing an access token
+// https://tools.ietf.org/html/rfc6749#section-6
+type refreshingGrant struct {
+	s *Server
+}
+
+func (g *refreshingGrant) ParseRequest(r *http.Request, tgr *oauth2.TokenGenerateRequest) (err error) {
+	tgr.Refresh, err = g.s.RefreshTokenResolveHandler(r)
+	tgr.Scope = oauth2.ParseScope(r.FormValue("scope"))
+	return err
+}
+
+// loadRefreshToken load the refresh token of the request, it must be issued to the client of the request
+func (g *refreshingGrant) loadRefreshToken(ctx context.Context, tgr *oauth2.TokenGenerateRequest) (oauth2.TokenInfo, error) {
+	rti, err := g.s.Manager.LoadRefreshToken(ctx, tgr.Refresh)
+	if err != nil {
+		if errors.Is(err, errors.ErrInvalidRefreshToken) || errors.Is(err, errors.ErrExpiredRefreshToken) {
+			return nil, errors.ErrInvalidGrant
+		}
+		return nil, err
+	} else if rti.GetClientID() != tgr.ClientID {
+		return nil, errors.ErrInvalidGrant
+	}
+	return rti, nil
+}
+
+func (g *refreshingGrant) Validate(ctx context.Context, tgr *oauth2.TokenGenerateRequest) error {
+	// check scope, the scope registry replaces the refreshing scope handler
+	if sr := g.s.ScopeRegistry; len(tgr.Scope) > 0 && sr != nil {
+		rti, err := g.loadRefreshToken(ctx, tgr)
+		if err != nil {
+			return err
+		}
+
+		scope, err := sr.CheckRefresh(rti.GetScope(), tgr.Scope)
+		if err != nil {
+			return err
+		}
+		tgr.Scope = scope
+	} else if scopeFn := g.s.RefreshingScopeHandler; len(tgr.Scope) > 0 && scopeFn != nil {
+		rti, err := g.loadRefreshToken(ctx, tgr)
+		if err != nil {
+			return err
+		}
+
+		allowed, err := scopeFn(tgr, rti.GetScope().String())
+		if err != nil {
+			return err
+		} else if !allowed {
+			return errors.ErrInvalidScope
+		}
+	}
+
+	if validationFn := g.s.RefreshingValidationHandler; validationFn != nil {
+		rti, err := g.loadRefreshToken(ctx, tgr)
+		if err != nil {
+			return err
+		}
+		allowed, err := validationFn(rti)
+		if err != nil {
+			return err
+		} else if !allowed {
+			return errors.ErrInvalidScope
+		}
+	}
+	return nil
+}
+
+func (g *refreshingGrant) Issue(ctx context.Context, tgr *oauth2.TokenGenerateRequest) (oauth2.TokenInfo, error) {
+	// OAuth 2.1 requires the rotation of the refresh tokens of the public clients
+	if g.s.Config.OAuth21 {
+		if cli, err := g.s.Manager.GetClient(ctx, tgr.ClientID); err == nil && cli.IsPublic() {
+			tgr.RotateRefresh = true
+		}
+	}
+
+	ti, err := g.s.Manager.RefreshAccessToken(ctx, tgr)
+	if err != nil {
+		if errors.Is(err, errors.ErrInvalidRefreshToken) || errors.Is(err, errors.ErrExpiredRefreshToken) {
+			return nil, errors.ErrInvalidGrant
+		}
+		return nil, err
+	}
+	return ti, nil
+}
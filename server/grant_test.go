@@ -0,0 +1,111 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gavv/httpexpect"
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/errors"
+	"github.com/go-oauth2/oauth2/v4/manage"
+	"github.com/go-oauth2/oauth2/v4/server"
+)
+
+const otpGrant = oauth2.GrantType("urn:example:params:oauth:grant-type:otp")
+
+// otpGrantHandler exchange a one-time password of the user for an access token
+type otpGrantHandler struct {
+	manager oauth2.Manager
+	otp     string
+}
+
+func (h *otpGrantHandler) ParseRequest(r *http.Request, tgr *oauth2.TokenGenerateRequest) error {
+	tgr.UserID = r.FormValue("username")
+	tgr.Code = r.FormValue("otp")
+	tgr.Scope = oauth2.ParseScope(r.FormValue("scope"))
+	if tgr.UserID == "" || tgr.Code == "" {
+		return errors.ErrInvalidRequest
+	}
+	return nil
+}
+
+func (h *otpGrantHandler) Validate(ctx context.Context, tgr *oauth2.TokenGenerateRequest) error {
+	if tgr.Code != h.otp {
+		return errors.ErrInvalidGrant
+	}
+	// the one-time password isn't an authorization code
+	tgr.Code = ""
+	return nil
+}
+
+func (h *otpGrantHandler) Issue(ctx context.Context, tgr *oauth2.TokenGenerateRequest) (oauth2.TokenInfo, error) {
+	return h.manager.GenerateAccessToken(ctx, otpGrant, tgr)
+}
+
+func TestGrantHandler(t *testing.T) {
+	tsrv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testServer(t, w, r)
+	}))
+	defer tsrv.Close()
+	e := httpexpect.New(t, tsrv.URL)
+
+	manager.MapClientStorage(clientStore("", false))
+	manager.SetGrantTokenCfg(otpGrant, &manage.Config{AccessTokenExp: time.Minute * 10})
+	srv = server.NewDefaultServer(manager)
+
+	// not registered yet
+	e.POST("/token").
+		WithFormField("grant_type", string(otpGrant)).
+		WithBasicAuth(clientID, clientSecret).
+		Expect().
+		JSON().Object().Value("error").Equal("unsupported_grant_type")
+
+	srv.SetGrantHandler(otpGrant, &otpGrantHandler{manager: manager, otp: "246810"})
+	srv.SetClientScopeHandler(func(tgr *oauth2.TokenGenerateRequest) (allowed bool, err error) {
+		return tgr.Scope.String() != "admin", nil
+	})
+
+	resObj := e.POST("/token").
+		WithFormField("grant_type", string(otpGrant)).
+		WithFormField("username", "000000").
+		WithFormField("otp", "246810").
+		WithBasicAuth(clientID, clientSecret).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+	resObj.Value("expires_in").Equal(600)
+	resObj.NotContainsKey("refresh_token")
+
+	ti, err := manager.LoadAccessToken(context.Background(), resObj.Value("access_token").String().Raw())
+	if err != nil {
+		t.Fatal(err)
+	} else if ti.GetUserID() != "000000" {
+		t.Errorf("unexpected user %s", ti.GetUserID())
+	}
+
+	e.POST("/token").
+		WithFormField("grant_type", string(otpGrant)).
+		WithFormField("username", "000000").
+		WithFormField("otp", "000000").
+		WithBasicAuth(clientID, clientSecret).
+		Expect().
+		JSON().Object().Value("error").Equal("invalid_grant")
+
+	e.POST("/token").
+		WithFormField("grant_type", string(otpGrant)).
+		WithFormField("username", "000000").
+		WithBasicAuth(clientID, clientSecret).
+		Expect().
+		JSON().Object().Value("error").Equal("invalid_request")
+
+	// the built-in grants use the same registry
+	e.POST("/token").
+		WithFormField("grant_type", "client_credentials").
+		WithFormField("scope", "admin").
+		WithBasicAuth(clientID, clientSecret).
+		Expect().
+		JSON().Object().Value("error").Equal("invalid_scope")
+}
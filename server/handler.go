@@ -8,12 +8,17 @@ import (
 
 	"github.com/go-oauth2/oauth2/v4"
 	"github.com/go-oauth2/oauth2/v4/errors"
+	"github.com/google/uuid"
 )
 
 type (
 	// ClientInfoHandler get client info from request
 	ClientInfoHandler func(r *http.Request) (clientID, clientSecret string, err error)
 
+	// ClientAuthenticationHandler authenticate the client of the request with one of the authentication methods,
+	// it replaces the ClientInfoHandler, see ClientAuthenticator
+	ClientAuthenticationHandler func(r *http.Request) (cli oauth2.ClientInfo, method string, err error)
+
 	// ClientAuthorizedHandler check the client allows to use this authorization grant type
 	ClientAuthorizedHandler func(clientID string, grant oauth2.GrantType) (allowed bool, err error)
 
@@ -23,9 +28,27 @@ type (
 	// UserAuthorizationHandler get user id from request authorization
 	UserAuthorizationHandler func(w http.ResponseWriter, r *http.Request) (userID string, err error)
 
+	// UserConsentHandler ask the user to consent to the scope not consented yet,
+	// it returns false without error when the consent page has been rendered.
+	// the handler can narrow req.Scope to the scope the user has accepted
+	UserConsentHandler func(w http.ResponseWriter, r *http.Request, req *AuthorizeRequest, scope oauth2.Scope) (consented bool, err error)
+
+	// ConsentRequiredHandler decide whether the user has to consent to the authorization request,
+	// consent is nil when the user hasn't consented yet or the consent has expired
+	ConsentRequiredHandler func(ctx context.Context, req *AuthorizeRequest, consent oauth2.ConsentInfo, missingScope oauth2.Scope) (required bool, err error)
+
 	// PasswordAuthorizationHandler get user id from username and password
 	PasswordAuthorizationHandler func(ctx context.Context, clientID, username, password string) (userID string, err error)
 
+	// AuthorizationDetailValidator validate an authorization details object of the registered type
+	AuthorizationDetailValidator func(ctx context.Context, clientID string, detail oauth2.AuthorizationDetail) error
+
+	// RateLimitKeyHandler get the rate limited keys of the token request
+	RateLimitKeyHandler func(r *http.Request) []string
+
+	// CorrelationIDHandler get the correlation ID of the events from the request
+	CorrelationIDHandler func(r *http.Request) string
+
 	// RefreshingScopeHandler check the scope of the refreshing token
 	RefreshingScopeHandler func(tgr *oauth2.TokenGenerateRequest, oldScope string) (allowed bool, err error)
 
@@ -58,6 +81,13 @@ type (
 
 	// Handler to fetch the access token from the request
 	AccessTokenResolveHandler func(r *http.Request) (string, bool)
+
+	// ResponseTypeHandler issue the authorization response parameters of the response type
+	ResponseTypeHandler func(ctx context.Context, req *AuthorizeRequest) (data map[string]interface{}, err error)
+
+	// IDTokenHandler issue the ID token of the id_token response types,
+	// ti is the authorization code issued with it or nil
+	IDTokenHandler func(ctx context.Context, req *AuthorizeRequest, ti oauth2.TokenInfo) (idToken string, err error)
 )
 
 // ClientFormHandler get client data from form
@@ -119,3 +149,34 @@ func AccessTokenCookieResolveHandler(r *http.Request) (string, bool) {
 
 	return c.Value, true
 }
+
+// the maximum length of the correlation ID taken from the request headers
+const maxCorrelationIDLen = 128
+
+// CorrelationIDHeaderHandler get the correlation ID from the X-Request-Id or X-Correlation-Id header,
+// a random ID is generated when there is none or it's longer than 128 characters or
+// contains characters other than the letters, digits and "-._:"
+func CorrelationIDHeaderHandler(r *http.Request) string {
+	if v := r.Header.Get("X-Request-Id"); isCorrelationID(v) {
+		return v
+	} else if v := r.Header.Get("X-Correlation-Id"); isCorrelationID(v) {
+		return v
+	}
+	return uuid.Must(uuid.NewRandom()).String()
+}
+
+// isCorrelationID check the correlation ID can be written to the events and the logs as is
+func isCorrelationID(v string) bool {
+	if v == "" || len(v) > maxCorrelationIDLen {
+		return false
+	}
+	for i := 0; i < len(v); i++ {
+		switch c := v[i]; {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '.', c == '_', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -119,3 +119,22 @@ func TestAccessTokenCookieHandler(t *testing.T) {
 		So(token, ShouldBeEmpty)
 	})
 }
+
+func TestCorrelationIDHeaderHandler(t *testing.T) {
+	Convey("Request Has Valid Request ID", t, func() {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.Header.Set("X-Request-Id", "req-1.a_b:c")
+		So(CorrelationIDHeaderHandler(r), ShouldEqual, "req-1.a_b:c")
+	})
+
+	Convey("Request Has Invalid Request ID", t, func() {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.Header.Set("X-Request-Id", "req-1\n{\"forged\":true}")
+		r.Header.Set("X-Correlation-Id", "corr-1")
+		So(CorrelationIDHeaderHandler(r), ShouldEqual, "corr-1")
+
+		r.Header.Set("X-Correlation-Id", strings.Repeat("a", 129))
+		id := CorrelationIDHeaderHandler(r)
+		So(id, ShouldHaveLength, 36)
+	})
+}
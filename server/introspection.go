@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/errors"
+)
+
+// authenticateClient verify the credentials of the confidential client calling a protected endpoint,
+// the clients without a registered secret can't authenticate
+func (s *Server) authenticateClient(ctx context.Context, r *http.Request) (oauth2.ClientInfo, error) {
+	if fn := s.ClientAuthenticationHandler; fn != nil {
+		cli, method, err := fn(r)
+		if err == nil && method != AuthMethodNone && !cli.IsPublic() {
+			return cli, nil
+		}
+		s.emit(r, &oauth2.Event{Type: oauth2.EventClientAuthFailed, ClientID: r.FormValue("client_id")})
+		return nil, errors.ErrInvalidClient
+	}
+
+	clientID, clientSecret, err := s.ClientInfoHandler(r)
+	if err != nil {
+		s.emit(r, &oauth2.Event{Type: oauth2.EventClientAuthFailed, Error: err.Error()})
+		return nil, err
+	}
+
+	cli, err := s.Manager.GetClient(ctx, clientID)
+	if err == nil && !cli.IsPublic() {
+		if v, ok := cli.(oauth2.ClientPasswordVerifier); ok {
+			if v.VerifyPassword(clientSecret) {
+				return cli, nil
+			}
+		} else if cli.GetSecret() != "" && subtle.ConstantTimeCompare([]byte(cli.GetSecret()), []byte(clientSecret)) == 1 {
+			return cli, nil
+		}
+	}
+	s.emit(r, &oauth2.Event{Type: oauth2.EventClientAuthFailed, ClientID: clientID})
+	return nil, errors.ErrInvalidClient
+}
+
+// loadIntrospectionToken load the active access or refresh token, starting with the hinted type
+func (s *Server) loadIntrospectionToken(ctx context.Context, token, hint string) (oauth2.TokenInfo, bool) {
+	if hint == "refresh_token" {
+		if ti, err := s.Manager.LoadRefreshToken(ctx, token); err == nil {
+			return ti, false
+		}
+	}
+	if ti, err := s.Manager.LoadAccessToken(ctx, token); err == nil {
+		return ti, true
+	}
+	if hint != "refresh_token" {
+		if ti, err := s.Manager.LoadRefreshToken(ctx, token); err == nil {
+			return ti, false
+		}
+	}
+	return nil, false
+}
+
+// GetIntrospectionData get the introspection response data of the token information
+func (s *Server) GetIntrospectionData(ti oauth2.TokenInfo, isAccess bool) map[string]interface{} {
+	data := map[string]interface{}{
+		"active":    true,
+		"client_id": ti.GetClientID(),
+	}
+
+	if isAccess {
+		data["token_type"] = s.Config.TokenType
+		data["iat"] = ti.GetAccessCreateAt().Unix()
+		if exp := ti.GetAccessExpiresIn(); exp > 0 {
+			data["exp"] = ti.GetAccessCreateAt().Add(exp).Unix()
+		}
+	} else {
+		// the refresh tokens are found even with the access_token hint (RFC 7662 section 2.1),
+		// their token type tells the resource servers they aren't access tokens
+		data["token_type"] = "refresh_token"
+		data["iat"] = ti.GetRefreshCreateAt().Unix()
+		if exp := ti.GetRefreshExpiresIn(); exp > 0 {
+			data["exp"] = ti.GetRefreshCreateAt().Add(exp).Unix()
+		}
+	}
+
+	if scope := ti.GetScope(); len(scope) > 0 {
+		data["scope"] = scope.String()
+	}
+
+	if userID := ti.GetUserID(); userID != "" {
+		data["sub"] = userID
+	}
+
+	if v := s.Config.Issuer; v != "" {
+		data["iss"] = v
+	}
+
+	if rti, ok := ti.(oauth2.ResourceTokenInfo); ok && len(rti.GetResource()) > 0 {
+		data["aud"] = rti.GetResource()
+	}
+
+	if dti, ok := ti.(oauth2.AuthorizationDetailsTokenInfo); ok && len(dti.GetAuthorizationDetails()) > 0 {
+		data["authorization_details"] = dti.GetAuthorizationDetails()
+	}
+
+	return data
+}
+
+// HandleIntrospectionRequest the token introspection request handling
+// https://tools.ietf.org/html/rfc7662
+func (s *Server) HandleIntrospectionRequest(w http.ResponseWriter, r *http.Request) error {
+	r = s.withCorrelationID(r)
+	ctx := r.Context()
+
+	if r.Method != "POST" {
+		return s.tokenError(w, errors.ErrInvalidRequest)
+	}
+
+	if _, err := s.authenticateClient(ctx, r); err != nil {
+		return s.tokenError(w, err)
+	}
+
+	token := r.FormValue("token")
+	if token == "" {
+		return s.tokenError(w, errors.ErrInvalidRequest)
+	}
+
+	ti, isAccess := s.loadIntrospectionToken(ctx, token, r.FormValue("token_type_hint"))
+	if ti == nil {
+		return s.token(w, map[string]interface{}{"active": false}, nil)
+	}
+
+	return s.token(w, s.GetIntrospectionData(ti, isAccess), nil)
+}
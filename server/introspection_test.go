@@ -0,0 +1,118 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect"
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/models"
+	"github.com/go-oauth2/oauth2/v4/server"
+	"github.com/go-oauth2/oauth2/v4/store"
+)
+
+// plainClient the client information compared with the registered secret
+type plainClient struct {
+	oauth2.ClientInfo
+}
+
+func TestIntrospection(t *testing.T) {
+	tsrv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := srv.HandleIntrospectionRequest(w, r); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer tsrv.Close()
+	e := httpexpect.New(t, tsrv.URL)
+
+	manager.MapClientStorage(clientStore("", false))
+	srv = server.NewDefaultServer(manager)
+
+	ti, err := manager.GenerateAccessToken(context.Background(), oauth2.PasswordCredentials, &oauth2.TokenGenerateRequest{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		UserID:       "000000",
+		Scope:        oauth2.ParseScope("read"),
+		AuthorizationDetails: []oauth2.AuthorizationDetail{
+			{"type": "payment_initiation", "amount": "1"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obj := e.POST("/").
+		WithFormField("token", ti.GetAccess()).
+		WithBasicAuth(clientID, clientSecret).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+	obj.ValueEqual("active", true)
+	obj.ValueEqual("client_id", clientID)
+	obj.ValueEqual("sub", "000000")
+	obj.ValueEqual("scope", "read")
+	obj.ValueEqual("token_type", "Bearer")
+	obj.Value("exp").Number().Gt(0)
+	obj.Value("authorization_details").Array().Element(0).Object().ValueEqual("type", "payment_initiation")
+
+	obj = e.POST("/").
+		WithFormField("token", ti.GetRefresh()).
+		WithFormField("token_type_hint", "refresh_token").
+		WithBasicAuth(clientID, clientSecret).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+	obj.ValueEqual("active", true)
+	obj.ValueEqual("token_type", "refresh_token")
+	obj.NotContainsKey("iss")
+
+	e.POST("/").
+		WithFormField("token", ti.GetRefresh()).
+		WithFormField("token_type_hint", "access_token").
+		WithBasicAuth(clientID, clientSecret).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().
+		ValueEqual("token_type", "refresh_token")
+
+	srv.SetIssuer("https://as.example.com")
+	e.POST("/").
+		WithFormField("token", ti.GetAccess()).
+		WithBasicAuth(clientID, clientSecret).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().ValueEqual("iss", "https://as.example.com")
+
+	e.POST("/").
+		WithFormField("token", "unknown").
+		WithBasicAuth(clientID, clientSecret).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().ValueEqual("active", false).NotContainsKey("client_id")
+
+	e.POST("/").
+		WithFormField("token", ti.GetAccess()).
+		WithBasicAuth(clientID, "invalid").
+		Expect().
+		Status(http.StatusUnauthorized).
+		JSON().Object().ValueEqual("error", "invalid_client")
+
+	e.GET("/").
+		WithQuery("token", ti.GetAccess()).
+		WithBasicAuth(clientID, clientSecret).
+		Expect().
+		Status(http.StatusBadRequest)
+
+	// the confidential client without a registered secret can't authenticate
+	cs := store.NewClientStore()
+	cs.Set("222222", plainClient{&models.Client{ID: "222222"}})
+	manager.MapClientStorage(cs)
+	e.POST("/").
+		WithFormField("token", ti.GetAccess()).
+		WithBasicAuth("222222", "").
+		Expect().
+		Status(http.StatusUnauthorized).
+		JSON().Object().ValueEqual("error", "invalid_client")
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/errors"
+)
+
+// GetMetadata get the authorization server metadata of the server config,
+// the additional metadata (e.g. the endpoint URLs) overrides the generated values
+// https://tools.ietf.org/html/rfc8414#section-2
+func (s *Server) GetMetadata() map[string]interface{} {
+	data := make(map[string]interface{})
+	if v := s.Config.Issuer; v != "" {
+		data["issuer"] = v
+		data["authorization_response_iss_parameter_supported"] = true
+	}
+
+	responseTypes := make([]string, 0, len(s.Config.AllowedResponseTypes))
+	for _, rt := range s.Config.AllowedResponseTypes {
+		if !s.CheckResponseType(rt) {
+			continue
+		}
+		responseTypes = append(responseTypes, rt.String())
+	}
+	data["response_types_supported"] = responseTypes
+
+	responseModes := []string{
+		oauth2.ResponseModeQuery.String(),
+		oauth2.ResponseModeFragment.String(),
+		oauth2.ResponseModeFormPost.String(),
+	}
+	if cfg := s.JARM; cfg != nil {
+		responseModes = append(responseModes,
+			oauth2.ResponseModeJWT.String(),
+			oauth2.ResponseModeQueryJWT.String(),
+			oauth2.ResponseModeFragmentJWT.String(),
+			oauth2.ResponseModeFormPostJWT.String(),
+		)
+		if cfg.SigningMethod != nil {
+			data["authorization_signing_alg_values_supported"] = []string{cfg.SigningMethod.Alg()}
+		}
+	}
+	data["response_modes_supported"] = responseModes
+
+	grantTypes := make([]string, 0, len(s.Config.AllowedGrantTypes)+1)
+	for _, gt := range s.Config.AllowedGrantTypes {
+		if !s.CheckGrantType(gt) {
+			continue
+		}
+		grantTypes = append(grantTypes, gt.String())
+	}
+	if s.CheckResponseType(oauth2.Token) {
+		grantTypes = append(grantTypes, "implicit")
+	}
+	data["grant_types_supported"] = grantTypes
+
+	methods := make([]string, 0, len(s.Config.AllowedCodeChallengeMethods))
+	for _, ccm := range s.Config.AllowedCodeChallengeMethods {
+		if !s.CheckCodeChallengeMethod(ccm) {
+			continue
+		}
+		methods = append(methods, ccm.String())
+	}
+	data["code_challenge_methods_supported"] = methods
+
+	for k, v := range s.Metadata {
+		data[k] = v
+	}
+	return data
+}
+
+// HandleMetadataRequest the authorization server metadata request handling,
+// usually served at /.well-known/oauth-authorization-server
+func (s *Server) HandleMetadataRequest(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "GET" {
+		return s.tokenError(w, errors.ErrInvalidRequest)
+	}
+	return s.token(w, s.GetMetadata(), nil)
+}
@@ -0,0 +1,153 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect"
+	"github.com/go-oauth2/oauth2/v4/errors"
+	"github.com/go-oauth2/oauth2/v4/generates"
+	"github.com/go-oauth2/oauth2/v4/manage"
+	"github.com/go-oauth2/oauth2/v4/server"
+	"github.com/go-oauth2/oauth2/v4/store"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestMetadata(t *testing.T) {
+	tsrv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := srv.HandleMetadataRequest(w, r); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer tsrv.Close()
+	e := httpexpect.New(t, tsrv.URL)
+
+	srv = server.NewDefaultServer(manager)
+
+	obj := e.GET("/.well-known/oauth-authorization-server").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+	obj.NotContainsKey("issuer")
+	obj.NotContainsKey("authorization_response_iss_parameter_supported")
+	obj.Value("response_types_supported").Array().Equal([]string{"code", "token"})
+	obj.Value("response_modes_supported").Array().Equal([]string{"query", "fragment", "form_post"})
+	obj.Value("grant_types_supported").Array().Equal([]string{
+		"authorization_code", "password", "client_credentials", "refresh_token", "implicit",
+	})
+	obj.Value("code_challenge_methods_supported").Array().Equal([]string{"plain", "S256"})
+
+	srv.SetIssuer("https://as.example.com")
+	srv.SetJARM(&server.JARMConfig{SigningMethod: jwt.SigningMethodHS256, SigningKey: []byte("00000000")})
+	srv.SetMetadata(map[string]interface{}{
+		"authorization_endpoint": "https://as.example.com/authorize",
+		"token_endpoint":         "https://as.example.com/token",
+	})
+
+	obj = e.GET("/.well-known/oauth-authorization-server").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+	obj.Value("issuer").Equal("https://as.example.com")
+	obj.Value("authorization_response_iss_parameter_supported").Boolean().True()
+	obj.Value("authorization_endpoint").Equal("https://as.example.com/authorize")
+	obj.Value("token_endpoint").Equal("https://as.example.com/token")
+	obj.Value("response_modes_supported").Array().Contains("query.jwt", "fragment.jwt", "form_post.jwt", "jwt")
+	obj.Value("authorization_signing_alg_values_supported").Array().Equal([]string{"HS256"})
+
+	e.POST("/.well-known/oauth-authorization-server").
+		Expect().
+		Status(http.StatusBadRequest)
+}
+
+func TestAuthorizationResponseIssuer(t *testing.T) {
+	tsrv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testServer(t, w, r)
+	}))
+	defer tsrv.Close()
+	e := newRedirectExpect(t, tsrv.URL)
+
+	redirectURI := "http://localhost/oauth2"
+	manager.MapClientStorage(clientStore("http://localhost", false))
+	srv = server.NewDefaultServer(manager)
+	srv.SetIssuer("https://as.example.com")
+	srv.SetUserAuthorizationHandler(func(w http.ResponseWriter, r *http.Request) (userID string, err error) {
+		return "000000", nil
+	})
+
+	authorize := func(responseType, responseMode string) string {
+		return e.GET("/authorize").
+			WithQuery("response_type", responseType).
+			WithQuery("response_mode", responseMode).
+			WithQuery("client_id", clientID).
+			WithQuery("redirect_uri", redirectURI).
+			WithQuery("state", "123").
+			Expect().
+			Status(http.StatusFound).
+			Header("Location").Raw()
+	}
+
+	values := redirectValues(t, authorize("code", ""), false)
+	if values.Get("code") == "" || values.Get("iss") != "https://as.example.com" {
+		t.Errorf("unexpected code response: %v", values)
+	}
+
+	values = redirectValues(t, authorize("token", ""), true)
+	if values.Get("access_token") == "" || values.Get("iss") != "https://as.example.com" {
+		t.Errorf("unexpected token response: %v", values)
+	}
+
+	// the issuer of the JWT secured responses is in the response JWT
+	key := []byte("00000000")
+	srv.SetJARM(&server.JARMConfig{SigningMethod: jwt.SigningMethodHS256, SigningKey: key})
+	values = redirectValues(t, authorize("code", "query.jwt"), false)
+	if values.Has("iss") {
+		t.Errorf("unexpected query.jwt response: %v", values)
+	}
+	_, err := jwt.Parse(values.Get("response"), func(token *jwt.Token) (interface{}, error) {
+		return key, nil
+	}, jwt.WithIssuer("https://as.example.com"))
+	if err != nil {
+		t.Errorf("invalid response JWT: %v", err)
+	}
+
+	// the error responses identify the issuer too
+	srv.SetUserAuthorizationHandler(func(w http.ResponseWriter, r *http.Request) (userID string, err error) {
+		return "", errors.ErrAccessDenied
+	})
+	values = redirectValues(t, authorize("code", ""), false)
+	if values.Get("error") != "access_denied" || values.Get("iss") != "https://as.example.com" || values.Get("state") != "123" {
+		t.Errorf("unexpected error response: %v", values)
+	}
+}
+
+func TestJWTAccessTokenIssuer(t *testing.T) {
+	tsrv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testServer(t, w, r)
+	}))
+	defer tsrv.Close()
+	e := httpexpect.New(t, tsrv.URL)
+
+	key := []byte("00000000")
+	jmanager := manage.NewDefaultManager()
+	jmanager.MustTokenStorage(store.NewMemoryTokenStore())
+	jmanager.MapClientStorage(clientStore("", false))
+	jmanager.MapAccessGenerate(generates.NewJWTAccessGenerate("", key, jwt.SigningMethodHS256))
+	srv = server.NewDefaultServer(jmanager)
+	srv.SetIssuer("https://as.example.com")
+
+	access := e.POST("/token").
+		WithFormField("grant_type", "client_credentials").
+		WithBasicAuth(clientID, clientSecret).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("access_token").String().Raw()
+
+	_, err := jwt.Parse(access, func(token *jwt.Token) (interface{}, error) {
+		return key, nil
+	}, jwt.WithIssuer("https://as.example.com"))
+	if err != nil {
+		t.Errorf("invalid access token issuer: %v", err)
+	}
+}
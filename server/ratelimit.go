@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RateLimiter limit the token requests and lock out the keys after repeated failures
+type RateLimiter interface {
+	// Allow reports whether a request of the key is allowed, otherwise how long to wait
+	Allow(ctx context.Context, key string) (allowed bool, retryAfter time.Duration, err error)
+	// Failure record a failed authentication of the key
+	Failure(ctx context.Context, key string) error
+	// Success reset the failures of the key
+	Success(ctx context.Context, key string) error
+}
+
+// DefaultRateLimitKeys the rate limited keys of the token request: the client IP,
+// the client ID and the resource owner username from the client IP,
+// so the failures from an IP can't lock out the client or the user everywhere
+func DefaultRateLimitKeys(r *http.Request) []string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	keys := []string{"ip:" + ip}
+
+	if clientID, _, ok := r.BasicAuth(); ok && clientID != "" {
+		keys = append(keys, "client:"+ip+":"+clientID)
+	} else if clientID := r.FormValue("client_id"); clientID != "" {
+		keys = append(keys, "client:"+ip+":"+clientID)
+	}
+	if username := r.FormValue("username"); username != "" {
+		keys = append(keys, "user:"+ip+":"+username)
+	}
+	return keys
+}
+
+// RateLimitConfig the in-memory rate limiter configuration
+type RateLimitConfig struct {
+	// the requests added to the bucket of a key per second, must be positive
+	Rate float64
+	// the bucket size, the number of requests allowed at once
+	Burst int
+	// the failures after which the key is locked out, 0 disables the lockout
+	MaxFailures int
+	// the lockout duration
+	LockoutDuration time.Duration
+}
+
+// DefaultRateLimitConfig the default rate limiter configuration
+var DefaultRateLimitConfig = &RateLimitConfig{Rate: 1, Burst: 10, MaxFailures: 5, LockoutDuration: time.Minute * 15}
+
+type rateLimitEntry struct {
+	tokens      float64
+	updatedAt   time.Time
+	failures    int
+	lockedUntil time.Time
+}
+
+// NewMemoryRateLimiter create to the in-memory token bucket rate limiter
+func NewMemoryRateLimiter(cfg *RateLimitConfig) *MemoryRateLimiter {
+	if cfg == nil {
+		cfg = DefaultRateLimitConfig
+	}
+	return &MemoryRateLimiter{
+		cfg:     cfg,
+		entries: make(map[string]*rateLimitEntry),
+	}
+}
+
+// MemoryRateLimiter in-memory token bucket rate limiter with the failure lockout,
+// the limits are local to the server instance
+type MemoryRateLimiter struct {
+	sync.Mutex
+	cfg       *RateLimitConfig
+	entries   map[string]*rateLimitEntry
+	lastSweep time.Time
+}
+
+// entry get the entry of the key with the refilled bucket
+func (l *MemoryRateLimiter) entry(key string, now time.Time) *rateLimitEntry {
+	e, ok := l.entries[key]
+	if !ok {
+		e = &rateLimitEntry{tokens: float64(l.cfg.Burst), updatedAt: now}
+		l.entries[key] = e
+		return e
+	}
+	e.tokens = math.Min(float64(l.cfg.Burst), e.tokens+now.Sub(e.updatedAt).Seconds()*l.cfg.Rate)
+	e.updatedAt = now
+	return e
+}
+
+// sweep remove the entries back to their initial state
+func (l *MemoryRateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for key, e := range l.entries {
+		full := e.tokens+now.Sub(e.updatedAt).Seconds()*l.cfg.Rate >= float64(l.cfg.Burst)
+		if full && e.failures == 0 && !now.Before(e.lockedUntil) {
+			delete(l.entries, key)
+		}
+	}
+}
+
+// Allow reports whether a request of the key is allowed, otherwise how long to wait
+func (l *MemoryRateLimiter) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+	e := l.entry(key, now)
+	if now.Before(e.lockedUntil) {
+		return false, e.lockedUntil.Sub(now), nil
+	}
+	if e.tokens < 1 {
+		return false, time.Duration((1 - e.tokens) / l.cfg.Rate * float64(time.Second)), nil
+	}
+	e.tokens--
+	return true, 0, nil
+}
+
+// Failure record a failed authentication of the key, the key is locked out after MaxFailures
+func (l *MemoryRateLimiter) Failure(ctx context.Context, key string) error {
+	l.Lock()
+	defer l.Unlock()
+
+	now := time.Now()
+	e := l.entry(key, now)
+	e.failures++
+	if l.cfg.MaxFailures > 0 && e.failures >= l.cfg.MaxFailures {
+		e.failures = 0
+		e.lockedUntil = now.Add(l.cfg.LockoutDuration)
+	}
+	return nil
+}
+
+// Success reset the failures of the key
+func (l *MemoryRateLimiter) Success(ctx context.Context, key string) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if e, ok := l.entries[key]; ok {
+		e.failures = 0
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gavv/httpexpect"
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/errors"
+	"github.com/go-oauth2/oauth2/v4/server"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMemoryRateLimiter(t *testing.T) {
+	Convey("Test memory rate limiter", t, func() {
+		ctx := context.Background()
+
+		Convey("Test token bucket", func() {
+			l := server.NewMemoryRateLimiter(&server.RateLimitConfig{Rate: 0.5, Burst: 2})
+			for i := 0; i < 2; i++ {
+				allowed, _, err := l.Allow(ctx, "ip:1")
+				So(err, ShouldBeNil)
+				So(allowed, ShouldBeTrue)
+			}
+			allowed, retryAfter, err := l.Allow(ctx, "ip:1")
+			So(err, ShouldBeNil)
+			So(allowed, ShouldBeFalse)
+			So(retryAfter, ShouldBeGreaterThan, time.Second)
+			So(retryAfter, ShouldBeLessThanOrEqualTo, time.Second*2)
+
+			allowed, _, _ = l.Allow(ctx, "ip:2")
+			So(allowed, ShouldBeTrue)
+		})
+
+		Convey("Test failure lockout", func() {
+			l := server.NewMemoryRateLimiter(&server.RateLimitConfig{Rate: 100, Burst: 100, MaxFailures: 3, LockoutDuration: time.Minute})
+			l.Failure(ctx, "user:admin")
+			l.Failure(ctx, "user:admin")
+			l.Success(ctx, "user:admin")
+			l.Failure(ctx, "user:admin")
+			l.Failure(ctx, "user:admin")
+			allowed, _, _ := l.Allow(ctx, "user:admin")
+			So(allowed, ShouldBeTrue)
+
+			l.Failure(ctx, "user:admin")
+			allowed, retryAfter, _ := l.Allow(ctx, "user:admin")
+			So(allowed, ShouldBeFalse)
+			So(retryAfter, ShouldBeGreaterThan, time.Second*59)
+		})
+	})
+}
+
+func TestDefaultRateLimitKeys(t *testing.T) {
+	Convey("Test the client and the user are limited from the client IP", t, func() {
+		r := httptest.NewRequest("POST", "/token", nil)
+		r.RemoteAddr = "10.0.0.1:1234"
+		r.Form = map[string][]string{"client_id": {"1"}, "username": {"admin"}}
+		So(server.DefaultRateLimitKeys(r), ShouldResemble, []string{"ip:10.0.0.1", "client:10.0.0.1:1", "user:10.0.0.1:admin"})
+	})
+}
+
+func TestPasswordCredentialsRateLimit(t *testing.T) {
+	tsrv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testServer(t, w, r)
+	}))
+	defer tsrv.Close()
+	e := httpexpect.New(t, tsrv.URL)
+
+	manager.MapClientStorage(clientStore("", false))
+	srv = server.NewDefaultServer(manager)
+	srv.SetAllowedGrantType(oauth2.PasswordCredentials)
+	srv.SetPasswordAuthorizationHandler(func(_ context.Context, clientID, username, password string) (string, error) {
+		if username == "admin" && password == "123456" {
+			return "000000", nil
+		}
+		return "", errors.ErrInvalidGrant
+	})
+	srv.SetRateLimiter(server.NewMemoryRateLimiter(&server.RateLimitConfig{
+		Rate:            100,
+		Burst:           100,
+		MaxFailures:     2,
+		LockoutDuration: time.Minute,
+	}))
+	srv.SetRateLimitKeyHandler(func(r *http.Request) []string {
+		return []string{"user:" + r.FormValue("username")}
+	})
+
+	login := func(password string) *httpexpect.Response {
+		return e.POST("/token").
+			WithFormField("grant_type", "password").
+			WithFormField("username", "admin").
+			WithFormField("password", password).
+			WithBasicAuth(clientID, clientSecret).
+			Expect()
+	}
+
+	login("123456").Status(http.StatusOK)
+	login("invalid").Status(http.StatusUnauthorized)
+	login("invalid").Status(http.StatusUnauthorized)
+
+	res := login("123456")
+	res.Status(http.StatusTooManyRequests)
+	res.Header("Retry-After").Equal("60")
+	res.JSON().Object().ValueEqual("error", "temporarily_unavailable")
+
+	// the keys after the denied one aren't charged
+	counter := &countingRateLimiter{RateLimiter: server.NewMemoryRateLimiter(nil)}
+	srv.SetRateLimiter(counter)
+	srv.SetRateLimitKeyHandler(func(r *http.Request) []string {
+		return []string{"denied", "user:" + r.FormValue("username")}
+	})
+	login("123456").Status(http.StatusTooManyRequests)
+	if len(counter.keys) != 1 || counter.keys[0] != "denied" {
+		t.Fatalf("unexpected charged keys: %v", counter.keys)
+	}
+}
+
+func TestRefreshingRateLimit(t *testing.T) {
+	tsrv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testServer(t, w, r)
+	}))
+	defer tsrv.Close()
+	e := httpexpect.New(t, tsrv.URL)
+
+	manager.MapClientStorage(clientStore("", false))
+	srv = server.NewDefaultServer(manager)
+	srv.SetAllowedGrantType(oauth2.PasswordCredentials, oauth2.Refreshing)
+	srv.SetPasswordAuthorizationHandler(func(_ context.Context, clientID, username, password string) (string, error) {
+		return "000000", nil
+	})
+	srv.SetRateLimiter(server.NewMemoryRateLimiter(&server.RateLimitConfig{
+		Rate:            100,
+		Burst:           100,
+		MaxFailures:     2,
+		LockoutDuration: time.Minute,
+	}))
+	srv.SetRateLimitKeyHandler(func(r *http.Request) []string {
+		id, _, _ := r.BasicAuth()
+		return []string{"client:" + id}
+	})
+
+	refresh := e.POST("/token").
+		WithFormField("grant_type", "password").
+		WithFormField("username", "admin").
+		WithFormField("password", "123456").
+		WithBasicAuth(clientID, clientSecret).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("refresh_token").String().Raw()
+	refreshing := func(token, secret string) *httpexpect.Response {
+		return e.POST("/token").
+			WithFormField("grant_type", "refresh_token").
+			WithFormField("refresh_token", token).
+			WithBasicAuth(clientID, secret).
+			Expect()
+	}
+
+	// the invalid grants aren't failed authentications
+	for i := 0; i < 3; i++ {
+		refreshing("invalid", clientSecret).Status(http.StatusUnauthorized).
+			JSON().Object().ValueEqual("error", "invalid_grant")
+	}
+
+	refreshing(refresh, "invalid").Status(http.StatusUnauthorized).
+		JSON().Object().ValueEqual("error", "invalid_client")
+	refreshing(refresh, "invalid").Status(http.StatusUnauthorized)
+	refreshing(refresh, clientSecret).Status(http.StatusTooManyRequests)
+}
+
+// countingRateLimiter deny the "denied" key and record the charged keys
+type countingRateLimiter struct {
+	server.RateLimiter
+	keys []string
+}
+
+func (l *countingRateLimiter) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	l.keys = append(l.keys, key)
+	if key == "denied" {
+		return false, time.Second, nil
+	}
+	return l.RateLimiter.Allow(ctx, key)
+}
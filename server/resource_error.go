@@ -0,0 +1,137 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-oauth2/oauth2/v4/errors"
+)
+
+// the authentication schemes of the resource server challenges
+const (
+	SchemeBearer = "Bearer"
+	SchemeDPoP   = "DPoP"
+)
+
+// ResourceChallenge the WWW-Authenticate challenge of the protected resource
+// https://tools.ietf.org/html/rfc6750#section-3
+type ResourceChallenge struct {
+	// the authentication scheme, Bearer by default
+	Scheme string
+	Realm  string
+	// the scope necessary to access the resource
+	Scope string
+	// the URL of the protected resource metadata (RFC 9728)
+	ResourceMetadata string
+	// the supported DPoP proof signing algorithms (RFC 9449)
+	Algs []string
+}
+
+// String the WWW-Authenticate header value of the challenge with the error parameters
+func (c *ResourceChallenge) String(re *errors.Response) string {
+	scheme := c.Scheme
+	if scheme == "" {
+		scheme = SchemeBearer
+	}
+
+	var params []string
+	add := func(key, value string) {
+		if value != "" {
+			params = append(params, key+"="+quoteParam(value))
+		}
+	}
+	add("realm", c.Realm)
+	if re != nil && re.Error != nil {
+		add("error", re.Error.Error())
+		add("error_description", re.Description)
+		add("error_uri", re.URI)
+	}
+	add("scope", c.Scope)
+	add("resource_metadata", c.ResourceMetadata)
+	if scheme == SchemeDPoP {
+		add("algs", strings.Join(c.Algs, " "))
+	}
+
+	if len(params) == 0 {
+		return scheme
+	}
+	return scheme + " " + strings.Join(params, ", ")
+}
+
+func quoteParam(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `"`, `\"`)
+	return `"` + v + `"`
+}
+
+// GetResourceErrorData get the RFC 6750 error response of the protected resource,
+// re is nil for errors.ErrMissingAccessToken, so the response has no error code
+func GetResourceErrorData(err error) (re *errors.Response) {
+	var oe *errors.OAuthError
+	switch {
+	case errors.As(err, &oe):
+		re = oe.Response()
+		if oe.StatusCode == 0 {
+			if v, ok := errors.RFCStatusCodes[re.Error]; ok {
+				re.StatusCode = v
+			}
+		}
+		return re
+	case errors.Is(err, errors.ErrMissingAccessToken):
+		return nil
+	case errors.Is(err, errors.ErrInvalidAccessToken),
+		errors.Is(err, errors.ErrExpiredAccessToken):
+		err = errors.ErrInvalidToken
+	}
+
+	if _, ok := errors.Descriptions[err]; !ok {
+		err = errors.ErrServerError
+	}
+	return &errors.Response{
+		Error:       err,
+		Description: errors.Descriptions[err],
+		StatusCode:  errors.RFCStatusCodes[err],
+	}
+}
+
+// WriteResourceError write the error response of the protected resource with the WWW-Authenticate
+// challenges (e.g. of ValidationBearerToken errors), a Bearer challenge is sent if none is given.
+// Pass errors.ErrMissingAccessToken for the requests without an access token,
+// the challenge has no error code then (RFC 6750 section 3.1)
+func WriteResourceError(w http.ResponseWriter, err error, challenges ...*ResourceChallenge) error {
+	re := GetResourceErrorData(err)
+	status := http.StatusUnauthorized
+	if re != nil {
+		status = re.StatusCode
+	}
+
+	// the challenges tell how to authenticate, they're not sent with the server errors
+	if status == http.StatusBadRequest || status == http.StatusUnauthorized || status == http.StatusForbidden {
+		if len(challenges) == 0 {
+			challenges = []*ResourceChallenge{{}}
+		}
+		for _, c := range challenges {
+			w.Header().Add("WWW-Authenticate", c.String(re))
+		}
+	}
+
+	if re == nil {
+		w.WriteHeader(status)
+		return nil
+	}
+
+	data := map[string]interface{}{
+		"error": re.Error.Error(),
+	}
+	if v := re.Description; v != "" {
+		data["error_description"] = v
+	}
+	if v := re.URI; v != "" {
+		data["error_uri"] = v
+	}
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(data)
+}
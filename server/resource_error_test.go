@@ -0,0 +1,71 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-oauth2/oauth2/v4/errors"
+	"github.com/go-oauth2/oauth2/v4/server"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWriteResourceError(t *testing.T) {
+	Convey("Test resource server error responses", t, func() {
+		Convey("Missing access token", func() {
+			srv := server.NewDefaultServer(manager)
+			_, err := srv.ValidationBearerToken(httptest.NewRequest("GET", "/", nil))
+			So(err, ShouldEqual, errors.ErrInvalidAccessToken)
+
+			err = errors.ErrMissingAccessToken
+			So(errors.Is(err, errors.ErrInvalidAccessToken), ShouldBeTrue)
+			w := httptest.NewRecorder()
+			So(server.WriteResourceError(w, err, &server.ResourceChallenge{Realm: "example"}), ShouldBeNil)
+			So(w.Code, ShouldEqual, http.StatusUnauthorized)
+			So(w.Header().Get("WWW-Authenticate"), ShouldEqual, `Bearer realm="example"`)
+			So(w.Body.Len(), ShouldEqual, 0)
+		})
+
+		Convey("Invalid access token", func() {
+			w := httptest.NewRecorder()
+			So(server.WriteResourceError(w, errors.ErrExpiredAccessToken, &server.ResourceChallenge{
+				ResourceMetadata: "https://rs.example.com/.well-known/oauth-protected-resource",
+			}), ShouldBeNil)
+			So(w.Code, ShouldEqual, http.StatusUnauthorized)
+			So(w.Header().Get("WWW-Authenticate"), ShouldEqual,
+				`Bearer error="invalid_token", error_description="`+errors.Descriptions[errors.ErrInvalidToken]+`", `+
+					`resource_metadata="https://rs.example.com/.well-known/oauth-protected-resource"`)
+			So(w.Body.String(), ShouldContainSubstring, `"error":"invalid_token"`)
+		})
+
+		Convey("Insufficient scope with Bearer and DPoP challenges", func() {
+			w := httptest.NewRecorder()
+			err := errors.NewOAuthError(errors.ErrInsufficientScope, `The "write" scope is required`)
+			So(server.WriteResourceError(w, err,
+				&server.ResourceChallenge{Scope: "read write"},
+				&server.ResourceChallenge{Scheme: server.SchemeDPoP, Scope: "read write", Algs: []string{"ES256", "PS256"}},
+			), ShouldBeNil)
+			So(w.Code, ShouldEqual, http.StatusForbidden)
+			So(w.Header().Values("WWW-Authenticate"), ShouldResemble, []string{
+				`Bearer error="insufficient_scope", error_description="The \"write\" scope is required", scope="read write"`,
+				`DPoP error="insufficient_scope", error_description="The \"write\" scope is required", scope="read write", algs="ES256 PS256"`,
+			})
+		})
+
+		Convey("Unknown error code", func() {
+			w := httptest.NewRecorder()
+			So(server.WriteResourceError(w, errors.NewOAuthError(errors.New("custom_error"), "")), ShouldBeNil)
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+			So(w.Body.String(), ShouldContainSubstring, `"error":"custom_error"`)
+		})
+
+		Convey("Server error", func() {
+			w := httptest.NewRecorder()
+			So(server.WriteResourceError(w, errors.New("database unavailable")), ShouldBeNil)
+			So(w.Code, ShouldEqual, http.StatusInternalServerError)
+			So(w.Header().Get("WWW-Authenticate"), ShouldBeEmpty)
+			So(w.Body.String(), ShouldContainSubstring, `"error":"server_error"`)
+		})
+	})
+}
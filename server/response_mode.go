@@ -0,0 +1,138 @@
+package server
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/errors"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JARMConfig the signing of the JWT secured authorization responses (JARM)
+// https://openid.net/specs/oauth-v2-jarm.html
+type JARMConfig struct {
+	// the iss claim, the issuer of the server config by default
+	Issuer        string
+	KeyID         string
+	SigningMethod jwt.SigningMethod
+	// the key of the signing method, e.g. *ecdsa.PrivateKey for ES256 or []byte for HS256
+	SigningKey interface{}
+	// the lifetime of the response JWT, 10 minutes by default
+	Expiration time.Duration
+}
+
+// Validate check the signing method and the signing key are set and the key can sign with the method
+func (cfg *JARMConfig) Validate() error {
+	if cfg.SigningMethod == nil || cfg.SigningKey == nil {
+		return errors.New("the JARM signing method and signing key are required")
+	}
+	if _, err := cfg.SigningMethod.Sign("jarm", cfg.SigningKey); err != nil {
+		return fmt.Errorf("the JARM signing key can't sign with %s: %w", cfg.SigningMethod.Alg(), err)
+	}
+	return nil
+}
+
+// validationResponseMode check the requested response mode is supported and safe for the response type
+func (s *Server) validationResponseMode(rt oauth2.ResponseType, rm oauth2.ResponseMode) error {
+	if rm.IsJWT() && s.JARM == nil {
+		return errors.ErrInvalidRequest
+	}
+
+	switch rm.Base() {
+	case "", oauth2.ResponseModeFragment, oauth2.ResponseModeFormPost:
+		return nil
+	case oauth2.ResponseModeQuery:
+		// the tokens must not be exposed in the query
+		if rt.Has("token") || rt.Has("id_token") {
+			return errors.ErrInvalidRequest
+		}
+		return nil
+	}
+	return errors.ErrInvalidRequest
+}
+
+// GetJARMResponse sign the authorization response parameters and the state into the response JWT
+func (s *Server) GetJARMResponse(req *AuthorizeRequest, data map[string]interface{}) (string, error) {
+	cfg := s.JARM
+	if cfg == nil || cfg.SigningMethod == nil || cfg.SigningKey == nil {
+		return "", errors.New("the JWT secured authorization response isn't configured")
+	}
+
+	exp := cfg.Expiration
+	if exp <= 0 {
+		exp = time.Minute * 10
+	}
+	claims := jwt.MapClaims{
+		"aud": req.ClientID,
+		"exp": time.Now().Add(exp).Unix(),
+	}
+	if v := cfg.Issuer; v != "" {
+		claims["iss"] = v
+	} else if v := s.Config.Issuer; v != "" {
+		claims["iss"] = v
+	}
+	if req.State != "" {
+		claims["state"] = req.State
+	}
+	for k, v := range data {
+		claims[k] = v
+	}
+
+	token := jwt.NewWithClaims(cfg.SigningMethod, claims)
+	if cfg.KeyID != "" {
+		token.Header["kid"] = cfg.KeyID
+	}
+	return token.SignedString(cfg.SigningKey)
+}
+
+var formPostTemplate = template.Must(template.New("form_post").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Submit This Form</title></head>
+<body onload="javascript:document.forms[0].submit()">
+<form method="post" action="{{.Action}}">
+{{- range .Params}}
+<input type="hidden" name="{{.Name}}" value="{{.Value}}"/>
+{{- end}}
+<noscript><button type="submit">Continue</button></noscript>
+</form>
+</body>
+</html>
+`))
+
+// formPost respond with the auto-submitting form posting the response parameters to the redirect URI
+// https://openid.net/specs/oauth-v2-form-post-response-mode-1_0.html
+func (s *Server) formPost(w http.ResponseWriter, req *AuthorizeRequest, data map[string]interface{}) error {
+	values, err := s.responseValues(req, data)
+	if err != nil {
+		return err
+	}
+
+	type param struct {
+		Name, Value string
+	}
+	var params []param
+	for k, vs := range values {
+		for _, v := range vs {
+			params = append(params, param{Name: k, Value: v})
+		}
+	}
+	sort.Slice(params, func(i, j int) bool {
+		return params[i].Name < params[j].Name
+	})
+
+	w.Header().Set("Content-Type", "text/html;charset=UTF-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	return formPostTemplate.Execute(w, struct {
+		Action string
+		Params []param
+	}{
+		Action: req.RedirectURI,
+		Params: params,
+	})
+}
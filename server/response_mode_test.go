@@ -0,0 +1,136 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect"
+	"github.com/go-oauth2/oauth2/v4/errors"
+	"github.com/go-oauth2/oauth2/v4/server"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestResponseModes(t *testing.T) {
+	tsrv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := srv.HandleAuthorizeRequest(w, r); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}))
+	defer tsrv.Close()
+	e := newRedirectExpect(t, tsrv.URL)
+
+	redirectURI := "http://localhost/oauth2"
+	manager.MapClientStorage(clientStore("http://localhost", false))
+	srv = server.NewDefaultServer(manager)
+	srv.SetUserAuthorizationHandler(func(w http.ResponseWriter, r *http.Request) (userID string, err error) {
+		return "000000", nil
+	})
+
+	authorize := func(responseType, responseMode string) *httpexpect.Response {
+		return e.GET("/authorize").
+			WithQuery("response_type", responseType).
+			WithQuery("response_mode", responseMode).
+			WithQuery("client_id", clientID).
+			WithQuery("redirect_uri", redirectURI).
+			WithQuery("state", "123").
+			Expect()
+	}
+
+	// the code in the fragment
+	values := redirectValues(t, authorize("code", "fragment").Status(http.StatusFound).Header("Location").Raw(), true)
+	if values.Get("code") == "" || values.Get("state") != "123" {
+		t.Errorf("unexpected fragment response: %v", values)
+	}
+
+	// the tokens must not be returned in the query
+	authorize("token", "query").Status(http.StatusBadRequest).Body().Contains("invalid_request")
+
+	// the JWT secured responses need the signing key
+	authorize("code", "query.jwt").Status(http.StatusBadRequest).Body().Contains("invalid_request")
+
+	// the invalid response modes aren't honoured for the error
+	res := e.GET("/authorize").
+		WithQuery("response_type", "code").
+		WithQuery("response_mode", "unknown").
+		WithQuery("response_mode", "form_post").
+		WithQuery("client_id", clientID).
+		WithQuery("redirect_uri", "https://evil.example/cb").
+		Expect().
+		Status(http.StatusBadRequest)
+	res.Header("Location").Empty()
+	res.Body().NotContains("evil.example")
+	authorize("code", "unknown").Status(http.StatusBadRequest).Header("Location").Empty()
+
+	// form_post
+	res = authorize("code", "form_post").Status(http.StatusOK)
+	res.Header("Content-Type").Equal("text/html;charset=UTF-8")
+	res.Header("Cache-Control").Equal("no-store")
+	body := res.Body()
+	body.Contains(`<form method="post" action="http://localhost/oauth2">`)
+	body.Contains(`<input type="hidden" name="code" value="`)
+	body.Contains(`<input type="hidden" name="state" value="123"/>`)
+
+	// the invalid configs are rejected
+	if err := srv.SetJARM(&server.JARMConfig{SigningMethod: jwt.SigningMethodHS256}); err == nil {
+		t.Fatal("the config without a signing key is accepted")
+	}
+	if err := srv.SetJARM(&server.JARMConfig{SigningMethod: jwt.SigningMethodES256, SigningKey: []byte("00000000")}); err == nil {
+		t.Fatal("the config with a signing key of another method is accepted")
+	}
+	if srv.JARM != nil {
+		t.Fatal("the invalid config is set")
+	}
+
+	key := []byte("00000000")
+	if err := srv.SetJARM(&server.JARMConfig{
+		Issuer:        "https://as.example.com",
+		KeyID:         "jarm",
+		SigningMethod: jwt.SigningMethodHS256,
+		SigningKey:    key,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	parseResponse := func(response string) jwt.MapClaims {
+		claims := jwt.MapClaims{}
+		token, err := jwt.ParseWithClaims(response, claims, func(token *jwt.Token) (interface{}, error) {
+			if token.Header["kid"] != "jarm" {
+				t.Errorf("unexpected kid %v", token.Header["kid"])
+			}
+			return key, nil
+		}, jwt.WithAudience(clientID), jwt.WithIssuer("https://as.example.com"), jwt.WithExpirationRequired())
+		if err != nil || !token.Valid {
+			t.Fatalf("invalid response JWT %s: %v", response, err)
+		}
+		return claims
+	}
+
+	values = redirectValues(t, authorize("code", "query.jwt").Status(http.StatusFound).Header("Location").Raw(), false)
+	if len(values) != 1 {
+		t.Errorf("unexpected query.jwt response: %v", values)
+	}
+	claims := parseResponse(values.Get("response"))
+	if claims["code"] == "" || claims["state"] != "123" {
+		t.Errorf("unexpected query.jwt claims: %v", claims)
+	}
+
+	// jwt uses the default response mode of the response type
+	values = redirectValues(t, authorize("token", "jwt").Status(http.StatusFound).Header("Location").Raw(), true)
+	claims = parseResponse(values.Get("response"))
+	if claims["access_token"] == "" || claims["token_type"] != "Bearer" || claims["state"] != "123" {
+		t.Errorf("unexpected fragment.jwt claims: %v", claims)
+	}
+
+	res = authorize("code", "form_post.jwt").Status(http.StatusOK)
+	res.Body().Contains(`<input type="hidden" name="response" value="`).NotContains(`name="state"`)
+
+	// the errors are secured too
+	srv.SetUserAuthorizationHandler(func(w http.ResponseWriter, r *http.Request) (userID string, err error) {
+		return "", errors.ErrAccessDenied
+	})
+	values = redirectValues(t, authorize("token", "fragment.jwt").Status(http.StatusFound).Header("Location").Raw(), true)
+	claims = parseResponse(values.Get("response"))
+	if claims["error"] != "access_denied" || claims["state"] != "123" {
+		t.Errorf("unexpected error claims: %v", claims)
+	}
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/errors"
+)
+
+// ResponseTypeDefinition the authorization response of a response type
+type ResponseTypeDefinition struct {
+	// the response mode used when the request doesn't specify one,
+	// the tokens must not be returned in the query
+	DefaultResponseMode oauth2.ResponseMode
+	// issue the authorization response parameters
+	Handler ResponseTypeHandler
+}
+
+// the built-in response types
+func (s *Server) defaultResponseTypes() map[oauth2.ResponseType]*ResponseTypeDefinition {
+	return map[oauth2.ResponseType]*ResponseTypeDefinition{
+		oauth2.Code:        {DefaultResponseMode: oauth2.ResponseModeQuery, Handler: s.codeResponse},
+		oauth2.Token:       {DefaultResponseMode: oauth2.ResponseModeFragment, Handler: s.tokenResponse},
+		oauth2.IDToken:     {DefaultResponseMode: oauth2.ResponseModeFragment, Handler: s.idTokenResponse},
+		oauth2.None:        {DefaultResponseMode: oauth2.ResponseModeQuery, Handler: s.noneResponse},
+		oauth2.CodeToken:   {DefaultResponseMode: oauth2.ResponseModeFragment, Handler: s.codeTokenResponse},
+		oauth2.CodeIDToken: {DefaultResponseMode: oauth2.ResponseModeFragment, Handler: s.codeIDTokenResponse},
+	}
+}
+
+func (s *Server) responseType(rt oauth2.ResponseType) (*ResponseTypeDefinition, bool) {
+	types := s.ResponseTypes
+	if types == nil {
+		types = s.defaultResponseTypes()
+	}
+	def, ok := types[rt]
+	return def, ok
+}
+
+// GetResponseMode get the response mode of the authorization response,
+// the default response mode of the response type is used when the request doesn't specify one
+// or only asks for a JWT secured response (jwt)
+func (s *Server) GetResponseMode(req *AuthorizeRequest) oauth2.ResponseMode {
+	if req.ResponseMode != "" && req.ResponseMode != oauth2.ResponseModeJWT {
+		return req.ResponseMode
+	}
+
+	mode := oauth2.ResponseModeQuery
+	if def, ok := s.responseType(req.ResponseType); ok && def.DefaultResponseMode != "" {
+		mode = def.DefaultResponseMode
+	}
+	if req.ResponseMode == oauth2.ResponseModeJWT {
+		mode += ".jwt"
+	}
+	return mode
+}
+
+// GetAuthorizeResponse issue the authorization response parameters of the request response type
+func (s *Server) GetAuthorizeResponse(ctx context.Context, req *AuthorizeRequest) (map[string]interface{}, error) {
+	def, ok := s.responseType(req.ResponseType)
+	if !ok || def.Handler == nil {
+		return nil, errors.ErrUnsupportedResponseType
+	}
+	return def.Handler(ctx, req)
+}
+
+func (s *Server) codeResponse(ctx context.Context, req *AuthorizeRequest) (map[string]interface{}, error) {
+	ti, err := s.getAuthorizeToken(ctx, req, oauth2.Code)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetAuthorizeData(oauth2.Code, ti), nil
+}
+
+func (s *Server) tokenResponse(ctx context.Context, req *AuthorizeRequest) (map[string]interface{}, error) {
+	ti, err := s.getAuthorizeToken(ctx, req, oauth2.Token)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetAuthorizeData(oauth2.Token, ti), nil
+}
+
+func (s *Server) idTokenResponse(ctx context.Context, req *AuthorizeRequest) (map[string]interface{}, error) {
+	idToken, err := s.getIDToken(ctx, req, nil)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"id_token": idToken}, nil
+}
+
+// none returns only the state
+// https://openid.net/specs/oauth-v2-multiple-response-types-1_0.html#none
+func (s *Server) noneResponse(ctx context.Context, req *AuthorizeRequest) (map[string]interface{}, error) {
+	return map[string]interface{}{}, nil
+}
+
+func (s *Server) codeTokenResponse(ctx context.Context, req *AuthorizeRequest) (map[string]interface{}, error) {
+	data, err := s.codeResponse(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	tokenData, err := s.tokenResponse(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range tokenData {
+		data[k] = v
+	}
+	return data, nil
+}
+
+func (s *Server) codeIDTokenResponse(ctx context.Context, req *AuthorizeRequest) (map[string]interface{}, error) {
+	ti, err := s.getAuthorizeToken(ctx, req, oauth2.Code)
+	if err != nil {
+		return nil, err
+	}
+	idToken, err := s.getIDToken(ctx, req, ti)
+	if err != nil {
+		return nil, err
+	}
+	data := s.GetAuthorizeData(oauth2.Code, ti)
+	data["id_token"] = idToken
+	return data, nil
+}
+
+func (s *Server) getIDToken(ctx context.Context, req *AuthorizeRequest, ti oauth2.TokenInfo) (string, error) {
+	fn := s.IDTokenHandler
+	if fn == nil {
+		return "", errors.ErrUnsupportedResponseType
+	}
+	return fn(ctx, req, ti)
+}
@@ -0,0 +1,156 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gavv/httpexpect"
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/errors"
+	"github.com/go-oauth2/oauth2/v4/server"
+)
+
+// newRedirectExpect create the expect instance that doesn't follow the redirects,
+// so the fragment of the redirect URI can be checked
+func newRedirectExpect(t *testing.T, baseURL string) *httpexpect.Expect {
+	return httpexpect.WithConfig(httpexpect.Config{
+		BaseURL: baseURL,
+		Client: &http.Client{
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		Reporter: httpexpect.NewAssertReporter(t),
+	})
+}
+
+func redirectValues(t *testing.T, location string, fragment bool) url.Values {
+	u, err := url.Parse(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fragment {
+		return u.Query()
+	}
+	if u.RawQuery != "" {
+		t.Errorf("unexpected query in %s", location)
+	}
+	values, err := url.ParseQuery(u.Fragment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return values
+}
+
+func TestResponseTypes(t *testing.T) {
+	tsrv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testServer(t, w, r)
+	}))
+	defer tsrv.Close()
+	e := newRedirectExpect(t, tsrv.URL)
+
+	redirectURI := "http://localhost/oauth2"
+	manager.MapClientStorage(clientStore("http://localhost", false))
+	srv = server.NewDefaultServer(manager)
+	srv.SetAllowedResponseType(oauth2.Code, oauth2.Token, oauth2.IDToken, oauth2.None, oauth2.CodeToken, oauth2.CodeIDToken)
+	srv.SetUserAuthorizationHandler(func(w http.ResponseWriter, r *http.Request) (userID string, err error) {
+		return "000000", nil
+	})
+
+	authorize := func(responseType string, nonce string) string {
+		return e.GET("/authorize").
+			WithQuery("response_type", responseType).
+			WithQuery("client_id", clientID).
+			WithQuery("redirect_uri", redirectURI).
+			WithQuery("state", "123").
+			WithQuery("nonce", nonce).
+			Expect().
+			Status(http.StatusFound).
+			Header("Location").Raw()
+	}
+
+	// without the ID token handler
+	values := redirectValues(t, authorize("code id_token", "n-0S6_WzA2Mj"), true)
+	if values.Get("error") != "unsupported_response_type" {
+		t.Errorf("unexpected response: %v", values)
+	}
+
+	var idTokenCode string
+	srv.SetIDTokenHandler(func(ctx context.Context, req *server.AuthorizeRequest, ti oauth2.TokenInfo) (string, error) {
+		if ti != nil {
+			idTokenCode = ti.GetCode()
+		}
+		return "id-token." + req.Nonce, nil
+	})
+
+	// the hybrid flow responds in the fragment
+	values = redirectValues(t, authorize("id_token code", "n-0S6_WzA2Mj"), true)
+	if values.Get("code") == "" || values.Get("code") != idTokenCode ||
+		values.Get("id_token") != "id-token.n-0S6_WzA2Mj" ||
+		values.Get("state") != "123" {
+		t.Errorf("unexpected code id_token response: %v", values)
+	}
+
+	values = redirectValues(t, authorize("code token", ""), true)
+	if values.Get("code") == "" || values.Get("access_token") == "" || values.Get("token_type") != "Bearer" {
+		t.Errorf("unexpected code token response: %v", values)
+	}
+
+	values = redirectValues(t, authorize("id_token", "n-0S6_WzA2Mj"), true)
+	if values.Get("id_token") != "id-token.n-0S6_WzA2Mj" || values.Get("access_token") != "" {
+		t.Errorf("unexpected id_token response: %v", values)
+	}
+
+	// the nonce is required with the ID token, the error isn't redirected before the redirect URI is checked
+	r := httptest.NewRequest(http.MethodGet, "/authorize?"+url.Values{
+		"response_type": {"id_token"},
+		"client_id":     {clientID},
+		"redirect_uri":  {"https://evil.example/cb"},
+	}.Encode(), nil)
+	if req, err := srv.ValidationAuthorizeRequest(r); req != nil || !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("unexpected id_token request without nonce: %v, %v", req, err)
+	}
+
+	// none returns only the state in the query
+	values = redirectValues(t, authorize("none", ""), false)
+	if len(values) != 1 || values.Get("state") != "123" {
+		t.Errorf("unexpected none response: %v", values)
+	}
+
+	values = redirectValues(t, authorize("code", ""), false)
+	if values.Get("code") == "" {
+		t.Errorf("unexpected code response: %v", values)
+	}
+}
+
+func TestCustomResponseType(t *testing.T) {
+	tsrv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testServer(t, w, r)
+	}))
+	defer tsrv.Close()
+	e := newRedirectExpect(t, tsrv.URL)
+
+	manager.MapClientStorage(clientStore("http://localhost", false))
+	srv = server.NewDefaultServer(manager)
+	srv.SetUserAuthorizationHandler(func(w http.ResponseWriter, r *http.Request) (userID string, err error) {
+		return "000000", nil
+	})
+	srv.SetResponseType("urn:example:ticket", oauth2.ResponseModeFragment,
+		func(ctx context.Context, req *server.AuthorizeRequest) (map[string]interface{}, error) {
+			return map[string]interface{}{"ticket": "ticket-" + req.UserID}, nil
+		})
+
+	location := e.GET("/authorize").
+		WithQuery("response_type", "urn:example:ticket").
+		WithQuery("client_id", clientID).
+		WithQuery("redirect_uri", "http://localhost/oauth2").
+		Expect().
+		Status(http.StatusFound).
+		Header("Location").Raw()
+	if values := redirectValues(t, location, true); values.Get("ticket") != "ticket-000000" {
+		t.Errorf("unexpected response: %v", values)
+	}
+}
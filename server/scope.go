@@ -0,0 +1,142 @@
+package server
+
+import (
+	"sync"
+
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/errors"
+)
+
+// ScopeDefinition the definition of a known scope
+type ScopeDefinition struct {
+	// the scope token, or the name of a parameterized scope
+	Name string
+	// the human readable description, e.g. displayed on the consent page
+	Description string
+	// granted when the request doesn't specify a scope
+	Default bool
+	// accepts a parameter, e.g. read:repo/123 for the read:repo scope
+	Parameterized bool
+}
+
+// NewScopeRegistry create to the scope registry
+func NewScopeRegistry(defs ...*ScopeDefinition) *ScopeRegistry {
+	r := &ScopeRegistry{
+		scopes:  make(map[string]*ScopeDefinition),
+		clients: make(map[string]oauth2.Scope),
+	}
+	r.Register(defs...)
+	return r
+}
+
+// ScopeRegistry the known scopes of the server and the scopes allowed to each client
+type ScopeRegistry struct {
+	sync.RWMutex
+	order   []string
+	scopes  map[string]*ScopeDefinition
+	clients map[string]oauth2.Scope
+}
+
+// Register add or replace the scope definitions
+func (sr *ScopeRegistry) Register(defs ...*ScopeDefinition) {
+	sr.Lock()
+	defer sr.Unlock()
+
+	for _, def := range defs {
+		if _, ok := sr.scopes[def.Name]; !ok {
+			sr.order = append(sr.order, def.Name)
+		}
+		sr.scopes[def.Name] = def
+	}
+}
+
+// SetClientScope restrict the client to the scope, a scope name also allows its parameterized tokens
+func (sr *ScopeRegistry) SetClientScope(clientID string, scope oauth2.Scope) {
+	sr.Lock()
+	defer sr.Unlock()
+
+	sr.clients[clientID] = scope
+}
+
+// Lookup get the definition of the scope token
+func (sr *ScopeRegistry) Lookup(token string) (*ScopeDefinition, bool) {
+	sr.RLock()
+	defer sr.RUnlock()
+
+	if def, ok := sr.scopes[token]; ok {
+		return def, true
+	}
+	if name := oauth2.ScopeName(token); name != token {
+		if def, ok := sr.scopes[name]; ok && def.Parameterized {
+			return def, true
+		}
+	}
+	return nil, false
+}
+
+// IsParameterized whether the scope name is registered as accepting a parameter
+func (sr *ScopeRegistry) IsParameterized(name string) bool {
+	sr.RLock()
+	defer sr.RUnlock()
+
+	def, ok := sr.scopes[name]
+	return ok && def.Parameterized
+}
+
+// Describe get the definitions of the scope tokens, e.g. to render the consent page
+func (sr *ScopeRegistry) Describe(scope oauth2.Scope) []*ScopeDefinition {
+	var defs []*ScopeDefinition
+	for _, token := range scope {
+		if def, ok := sr.Lookup(token); ok {
+			defs = append(defs, def)
+		}
+	}
+	return defs
+}
+
+// Defaults the default scope of the client
+func (sr *ScopeRegistry) Defaults(clientID string) oauth2.Scope {
+	sr.RLock()
+	defer sr.RUnlock()
+
+	allowed, restricted := sr.clients[clientID]
+	var scope oauth2.Scope
+	for _, name := range sr.order {
+		if sr.scopes[name].Default && (!restricted || allowed.Has(name)) {
+			scope = append(scope, name)
+		}
+	}
+	return scope
+}
+
+// Resolve check the scope requested by the client, the default scope
+// is used when no scope is requested
+func (sr *ScopeRegistry) Resolve(clientID string, requested oauth2.Scope) (oauth2.Scope, error) {
+	if len(requested) == 0 {
+		return sr.Defaults(clientID), nil
+	}
+
+	sr.RLock()
+	allowed, restricted := sr.clients[clientID]
+	sr.RUnlock()
+
+	for _, token := range requested {
+		if _, ok := sr.Lookup(token); !ok {
+			return nil, errors.ErrInvalidScope
+		} else if restricted && !allowed.Covers(token, sr.IsParameterized) {
+			return nil, errors.ErrInvalidScope
+		}
+	}
+	return requested, nil
+}
+
+// CheckRefresh check the scope requested when refreshing is within the originally granted scope,
+// the granted scope is used when no scope is requested
+func (sr *ScopeRegistry) CheckRefresh(granted, requested oauth2.Scope) (oauth2.Scope, error) {
+	if len(requested) == 0 {
+		return granted, nil
+	} else if !granted.Contains(requested, sr.IsParameterized) {
+		return nil, errors.ErrInvalidScope
+	}
+	return requested, nil
+}
@@ -0,0 +1,144 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect"
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/errors"
+	"github.com/go-oauth2/oauth2/v4/server"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestScopeRegistry(t *testing.T) {
+	Convey("Test scope registry", t, func() {
+		sr := server.NewScopeRegistry(
+			&server.ScopeDefinition{Name: "profile", Description: "Read your profile", Default: true},
+			&server.ScopeDefinition{Name: "email", Default: true},
+			&server.ScopeDefinition{Name: "read:repo", Parameterized: true},
+			&server.ScopeDefinition{Name: "admin"},
+		)
+
+		Convey("Resolve the requested scope", func() {
+			scope, err := sr.Resolve("1", oauth2.ParseScope("profile read:repo/123"))
+			So(err, ShouldBeNil)
+			So(scope.String(), ShouldEqual, "profile read:repo/123")
+
+			_, err = sr.Resolve("1", oauth2.ParseScope("unknown"))
+			So(err, ShouldEqual, errors.ErrInvalidScope)
+			_, err = sr.Resolve("1", oauth2.ParseScope("admin/1"))
+			So(err, ShouldEqual, errors.ErrInvalidScope)
+
+			scope, err = sr.Resolve("1", oauth2.ParseScope(""))
+			So(err, ShouldBeNil)
+			So(scope.String(), ShouldEqual, "profile email")
+		})
+
+		Convey("Restrict the client scope", func() {
+			sr.SetClientScope("1", oauth2.ParseScope("profile read:repo"))
+
+			_, err := sr.Resolve("1", oauth2.ParseScope("admin"))
+			So(err, ShouldEqual, errors.ErrInvalidScope)
+			_, err = sr.Resolve("1", oauth2.ParseScope("read:repo/1"))
+			So(err, ShouldBeNil)
+			_, err = sr.Resolve("2", oauth2.ParseScope("admin"))
+			So(err, ShouldBeNil)
+
+			scope, err := sr.Resolve("1", oauth2.ParseScope(""))
+			So(err, ShouldBeNil)
+			So(scope.String(), ShouldEqual, "profile")
+		})
+
+		Convey("Describe the scope", func() {
+			defs := sr.Describe(oauth2.ParseScope("profile read:repo/1 unknown"))
+			So(len(defs), ShouldEqual, 2)
+			So(defs[0].Description, ShouldEqual, "Read your profile")
+			So(defs[1].Name, ShouldEqual, "read:repo")
+		})
+
+		Convey("Downscope when refreshing", func() {
+			scope, err := sr.CheckRefresh(oauth2.ParseScope("profile read:repo"), oauth2.ParseScope("read:repo/1"))
+			So(err, ShouldBeNil)
+			So(scope.String(), ShouldEqual, "read:repo/1")
+
+			scope, err = sr.CheckRefresh(oauth2.ParseScope("profile email"), oauth2.ParseScope(""))
+			So(err, ShouldBeNil)
+			So(scope.String(), ShouldEqual, "profile email")
+
+			_, err = sr.CheckRefresh(oauth2.ParseScope("profile"), oauth2.ParseScope("profile admin"))
+			So(err, ShouldEqual, errors.ErrInvalidScope)
+
+			// only the parameterized names cover their parameters
+			_, err = sr.CheckRefresh(oauth2.ParseScope("profile"), oauth2.ParseScope("profile/admin"))
+			So(err, ShouldEqual, errors.ErrInvalidScope)
+			So(sr.IsParameterized("read:repo"), ShouldBeTrue)
+			So(sr.IsParameterized("profile"), ShouldBeFalse)
+		})
+	})
+}
+
+func TestTokenRequestWithScopeRegistry(t *testing.T) {
+	tsrv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testServer(t, w, r)
+	}))
+	defer tsrv.Close()
+	e := httpexpect.New(t, tsrv.URL)
+
+	manager.MapClientStorage(clientStore("", false))
+	srv = server.NewDefaultServer(manager)
+	srv.SetAllowedGrantType(oauth2.ClientCredentials, oauth2.PasswordCredentials, oauth2.Refreshing)
+	srv.SetPasswordAuthorizationHandler(func(_ context.Context, clientID, username, password string) (string, error) {
+		return "000000", nil
+	})
+	srv.SetScopeRegistry(server.NewScopeRegistry(
+		&server.ScopeDefinition{Name: "read", Default: true},
+		&server.ScopeDefinition{Name: "write"},
+	))
+
+	e.POST("/token").
+		WithFormField("grant_type", "client_credentials").
+		WithFormField("scope", "read admin").
+		WithBasicAuth(clientID, clientSecret).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().Object().Value("error").Equal("invalid_scope")
+
+	e.POST("/token").
+		WithFormField("grant_type", "client_credentials").
+		WithBasicAuth(clientID, clientSecret).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("scope").Equal("read")
+
+	refresh := e.POST("/token").
+		WithFormField("grant_type", "password").
+		WithFormField("username", "admin").
+		WithFormField("password", "123456").
+		WithFormField("scope", "read write").
+		WithBasicAuth(clientID, clientSecret).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("refresh_token").String().Raw()
+
+	e.POST("/token").
+		WithFormField("grant_type", "refresh_token").
+		WithFormField("refresh_token", refresh).
+		WithFormField("scope", "write admin").
+		WithBasicAuth(clientID, clientSecret).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().Object().Value("error").Equal("invalid_scope")
+
+	e.POST("/token").
+		WithFormField("grant_type", "refresh_token").
+		WithFormField("refresh_token", refresh).
+		WithFormField("scope", "write").
+		WithBasicAuth(clientID, clientSecret).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("scope").Equal("write")
+}
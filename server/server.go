@@ -4,8 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
+	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/go-oauth2/oauth2/v4"
@@ -26,8 +29,11 @@ func NewServer(cfg *Config, manager oauth2.Manager) *Server {
 
 	// default handlers
 	srv.ClientInfoHandler = ClientBasicHandler
+	srv.CorrelationIDHandler = CorrelationIDHeaderHandler
 	srv.RefreshTokenResolveHandler = RefreshTokenFormResolveHandler
 	srv.AccessTokenResolveHandler = AccessTokenDefaultResolveHandler
+	srv.GrantHandlers = srv.defaultGrantHandlers()
+	srv.ResponseTypes = srv.defaultResponseTypes()
 
 	srv.UserAuthorizationHandler = func(w http.ResponseWriter, r *http.Request) (string, error) {
 		return "", errors.ErrAccessDenied
@@ -44,9 +50,12 @@ type Server struct {
 	Config                       *Config
 	Manager                      oauth2.Manager
 	ClientInfoHandler            ClientInfoHandler
+	ClientAuthenticationHandler  ClientAuthenticationHandler
 	ClientAuthorizedHandler      ClientAuthorizedHandler
 	ClientScopeHandler           ClientScopeHandler
 	UserAuthorizationHandler     UserAuthorizationHandler
+	UserConsentHandler           UserConsentHandler
+	ConsentRequiredHandler       ConsentRequiredHandler
 	PasswordAuthorizationHandler PasswordAuthorizationHandler
 	RefreshingValidationHandler  RefreshingValidationHandler
 	PreRedirectErrorHandler      PreRedirectErrorHandler
@@ -59,6 +68,51 @@ type Server struct {
 	ResponseTokenHandler         ResponseTokenHandler
 	RefreshTokenResolveHandler   RefreshTokenResolveHandler
 	AccessTokenResolveHandler    AccessTokenResolveHandler
+	ScopeRegistry                *ScopeRegistry
+	AuthorizationDetailTypes     map[string]AuthorizationDetailValidator
+	RateLimiter                  RateLimiter
+	RateLimitKeyHandler          RateLimitKeyHandler
+	CorrelationIDHandler         CorrelationIDHandler
+	Subscribers                  []oauth2.EventSubscriber
+	GrantHandlers                map[oauth2.GrantType]GrantHandler
+	ResponseTypes                map[oauth2.ResponseType]*ResponseTypeDefinition
+	IDTokenHandler               IDTokenHandler
+	JARM                         *JARMConfig
+	Metadata                     map[string]interface{}
+}
+
+// withCorrelationID set the correlation ID of the events in the request context
+func (s *Server) withCorrelationID(r *http.Request) *http.Request {
+	fn := s.CorrelationIDHandler
+	if fn == nil || oauth2.CorrelationIDFromContext(r.Context()) != "" {
+		return r
+	}
+	return r.WithContext(oauth2.WithCorrelationID(r.Context(), fn(r)))
+}
+
+// withIssuer returns the context carrying the issuer identifier for the token generators
+func (s *Server) withIssuer(ctx context.Context) context.Context {
+	if v := s.Config.Issuer; v != "" && oauth2.IssuerFromContext(ctx) == "" {
+		return oauth2.WithIssuer(ctx, v)
+	}
+	return ctx
+}
+
+// emit the server event to the subscribers
+func (s *Server) emit(r *http.Request, e *oauth2.Event) {
+	if len(s.Subscribers) == 0 {
+		return
+	}
+	e.Time = time.Now()
+	e.CorrelationID = oauth2.CorrelationIDFromContext(r.Context())
+	if ip, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		e.IP = ip
+	} else {
+		e.IP = r.RemoteAddr
+	}
+	for _, sub := range s.Subscribers {
+		sub.HandleEvent(r.Context(), e)
+	}
 }
 
 func (s *Server) handleError(w http.ResponseWriter, req *AuthorizeRequest, err error) error {
@@ -70,15 +124,33 @@ func (s *Server) handleError(w http.ResponseWriter, req *AuthorizeRequest, err e
 }
 
 func (s *Server) redirectError(w http.ResponseWriter, req *AuthorizeRequest, err error) error {
-	if req == nil {
+	// never send the error to a redirect URI the client hasn't registered
+	if req == nil || errors.Is(err, errors.ErrInvalidRedirectURI) {
 		return err
 	}
 
 	data, _, _ := s.GetErrorData(err)
+	var oe *errors.OAuthError
+	if errors.As(err, &oe) && oe.State != "" {
+		data["state"] = oe.State
+	}
 	return s.redirect(w, req, data)
 }
 
 func (s *Server) redirect(w http.ResponseWriter, req *AuthorizeRequest, data map[string]interface{}) error {
+	mode := s.GetResponseMode(req)
+	if mode.IsJWT() {
+		response, err := s.GetJARMResponse(req, data)
+		if err != nil {
+			return err
+		}
+		data = map[string]interface{}{"response": response}
+	}
+
+	if mode.Base() == oauth2.ResponseModeFormPost {
+		return s.formPost(w, req, data)
+	}
+
 	uri, err := s.GetRedirectURI(req, data)
 	if err != nil {
 		return err
@@ -115,6 +187,34 @@ func (s *Server) token(w http.ResponseWriter, data map[string]interface{}, heade
 	return json.NewEncoder(w).Encode(data)
 }
 
+// responseValues get the authorization response parameters with the state and the issuer,
+// they're in the response JWT of the JWT secured responses
+func (s *Server) responseValues(req *AuthorizeRequest, data map[string]interface{}) (url.Values, error) {
+	values := make(url.Values)
+	if !s.GetResponseMode(req).IsJWT() {
+		if req.State != "" {
+			values.Set("state", req.State)
+		}
+		// identify the authorization server to defend the clients against mix-up attacks
+		if v := s.Config.Issuer; v != "" {
+			values.Set("iss", v)
+		}
+	}
+
+	for k, v := range data {
+		if details, ok := v.([]oauth2.AuthorizationDetail); ok {
+			jv, err := json.Marshal(details)
+			if err != nil {
+				return nil, err
+			}
+			values.Set(k, string(jv))
+			continue
+		}
+		values.Set(k, fmt.Sprint(v))
+	}
+	return values, nil
+}
+
 // GetRedirectURI get redirect uri
 func (s *Server) GetRedirectURI(req *AuthorizeRequest, data map[string]interface{}) (string, error) {
 	u, err := url.Parse(req.RedirectURI)
@@ -123,18 +223,18 @@ func (s *Server) GetRedirectURI(req *AuthorizeRequest, data map[string]interface
 	}
 
 	q := u.Query()
-	if req.State != "" {
-		q.Set("state", req.State)
+	values, err := s.responseValues(req, data)
+	if err != nil {
+		return "", err
 	}
-
-	for k, v := range data {
-		q.Set(k, fmt.Sprint(v))
+	for k, v := range values {
+		q[k] = v
 	}
 
-	switch req.ResponseType {
-	case oauth2.Code:
+	switch s.GetResponseMode(req).Base() {
+	case oauth2.ResponseModeQuery:
 		u.RawQuery = q.Encode()
-	case oauth2.Token:
+	case oauth2.ResponseModeFragment:
 		u.RawQuery = ""
 		fragment, err := url.QueryUnescape(q.Encode())
 		if err != nil {
@@ -148,6 +248,10 @@ func (s *Server) GetRedirectURI(req *AuthorizeRequest, data map[string]interface
 
 // CheckResponseType check allows response type
 func (s *Server) CheckResponseType(rt oauth2.ResponseType) bool {
+	// OAuth 2.1 removes the implicit grant
+	if s.Config.OAuth21 && rt.Has(oauth2.Token.String()) {
+		return false
+	}
 	for _, art := range s.Config.AllowedResponseTypes {
 		if art == rt {
 			return true
@@ -156,8 +260,47 @@ func (s *Server) CheckResponseType(rt oauth2.ResponseType) bool {
 	return false
 }
 
+// getClient get the client information, nil if the client can't be loaded,
+// the manager reports the error when issuing the token
+func (s *Server) getClient(ctx context.Context, clientID string) oauth2.ClientInfo {
+	cli, err := s.Manager.GetClient(ctx, clientID)
+	if err != nil {
+		return nil
+	}
+	return cli
+}
+
+// GetClientPolicy get the authorization policy of the client (see oauth2.ClientPolicyInfo),
+// nil if the client has no policy, the nil policy allows everything the server allows
+func GetClientPolicy(cli oauth2.ClientInfo) *oauth2.ClientPolicy {
+	if pi, ok := cli.(oauth2.ClientPolicyInfo); ok {
+		return pi.GetPolicy()
+	}
+	return nil
+}
+
+// IsPKCERequired whether the client has to use PKCE, the public clients always have to,
+// the client policy overrides Config.ForcePKCE but not the OAuth 2.1 profile
+func (s *Server) IsPKCERequired(cli oauth2.ClientInfo) bool {
+	if s.Config.OAuth21 || (cli != nil && cli.IsPublic()) {
+		return true
+	}
+	if policy := GetClientPolicy(cli); policy != nil {
+		switch policy.PKCE {
+		case oauth2.PKCERequired:
+			return true
+		case oauth2.PKCEOptional:
+			return false
+		}
+	}
+	return s.Config.ForcePKCE
+}
+
 // CheckCodeChallengeMethod checks for allowed code challenge method
 func (s *Server) CheckCodeChallengeMethod(ccm oauth2.CodeChallengeMethod) bool {
+	if s.Config.OAuth21 && ccm != oauth2.CodeChallengeS256 {
+		return false
+	}
 	for _, c := range s.Config.AllowedCodeChallengeMethods {
 		if c == ccm {
 			return true
@@ -166,6 +309,17 @@ func (s *Server) CheckCodeChallengeMethod(ccm oauth2.CodeChallengeMethod) bool {
 	return false
 }
 
+// validationExactRedirectURI check the redirect URI is exactly the one registered by the client
+func (s *Server) validationExactRedirectURI(ctx context.Context, clientID, redirectURI string) error {
+	cli, err := s.Manager.GetClient(ctx, clientID)
+	if err != nil {
+		return err
+	} else if cli.GetDomain() != redirectURI {
+		return errors.ErrInvalidRedirectURI
+	}
+	return nil
+}
+
 // ValidationAuthorizeRequest the authorization request validation
 func (s *Server) ValidationAuthorizeRequest(r *http.Request) (*AuthorizeRequest, error) {
 	redirectURI := r.FormValue("redirect_uri")
@@ -175,15 +329,23 @@ func (s *Server) ValidationAuthorizeRequest(r *http.Request) (*AuthorizeRequest,
 		return nil, errors.ErrInvalidRequest
 	}
 
-	resType := oauth2.ResponseType(r.FormValue("response_type"))
-	if resType.String() == "" {
+	cli := s.getClient(r.Context(), clientID)
+	policy := GetClientPolicy(cli)
+	resType := oauth2.ParseResponseType(r.FormValue("response_type"))
+	if _, ok := s.responseType(resType); !ok {
 		return nil, errors.ErrUnsupportedResponseType
-	} else if allowed := s.CheckResponseType(resType); !allowed {
+	} else if allowed := s.CheckResponseType(resType) && policy.AllowsResponseType(resType); !allowed {
 		return nil, errors.ErrUnauthorizedClient
 	}
 
+	if s.Config.OAuth21 && redirectURI != "" {
+		if err := s.validationExactRedirectURI(r.Context(), clientID, redirectURI); err != nil {
+			return nil, err
+		}
+	}
+
 	cc := r.FormValue("code_challenge")
-	if cc == "" && s.Config.ForcePKCE {
+	if cc == "" && resType.Has("code") && s.IsPKCERequired(cli) {
 		return nil, errors.ErrCodeChallengeRquired
 	}
 	if cc != "" && (len(cc) < 43 || len(cc) > 128) {
@@ -197,6 +359,8 @@ func (s *Server) ValidationAuthorizeRequest(r *http.Request) (*AuthorizeRequest,
 	}
 	if ccm != "" && !s.CheckCodeChallengeMethod(ccm) {
 		return nil, errors.ErrUnsupportedCodeChallengeMethod
+	} else if cc != "" && !policy.AllowsCodeChallengeMethod(ccm) {
+		return nil, errors.ErrUnsupportedCodeChallengeMethod
 	}
 
 	req := &AuthorizeRequest{
@@ -204,20 +368,84 @@ func (s *Server) ValidationAuthorizeRequest(r *http.Request) (*AuthorizeRequest,
 		ResponseType:        resType,
 		ClientID:            clientID,
 		State:               r.FormValue("state"),
-		Scope:               r.FormValue("scope"),
+		Scope:               oauth2.ParseScope(r.FormValue("scope")),
 		Request:             r,
 		CodeChallenge:       cc,
 		CodeChallengeMethod: ccm,
+		Resource:            r.Form["resource"],
+		Nonce:               r.FormValue("nonce"),
+	}
+
+	if v := oauth2.ResponseMode(r.FormValue("response_mode")); v != "" {
+		// the response mode isn't honoured for the errors of an invalid request,
+		// they aren't redirected before the redirect URI is checked
+		if len(r.Form["response_mode"]) > 1 {
+			return nil, errors.ErrInvalidRequest
+		} else if err := s.validationResponseMode(resType, v); err != nil {
+			return nil, err
+		}
+		req.ResponseMode = v
 	}
+
+	// the nonce is required when the ID token is returned from the authorization endpoint
+	// https://openid.net/specs/openid-connect-core-1_0.html#ImplicitAuthRequest
+	if resType.Has("id_token") && req.Nonce == "" {
+		return nil, errors.ErrInvalidRequest
+	}
+
+	if sr := s.ScopeRegistry; sr != nil {
+		scope, err := sr.Resolve(clientID, req.Scope)
+		if err != nil {
+			return nil, err
+		}
+		req.Scope = scope
+	}
+
+	details, err := s.ValidationAuthorizationDetails(r.Context(), clientID, r.FormValue("authorization_details"))
+	if err != nil {
+		return nil, err
+	}
+	req.AuthorizationDetails = details
 	return req, nil
 }
 
+// ValidationAuthorizationDetails parse the authorization_details parameter and validate its objects
+// against the registered types
+func (s *Server) ValidationAuthorizationDetails(ctx context.Context, clientID, v string) ([]oauth2.AuthorizationDetail, error) {
+	if v == "" {
+		return nil, nil
+	}
+
+	details, err := oauth2.ParseAuthorizationDetails(v)
+	if err != nil {
+		return nil, errors.ErrInvalidAuthorizationDetails
+	}
+	for _, detail := range details {
+		validator, ok := s.AuthorizationDetailTypes[detail.Type()]
+		if !ok {
+			return nil, errors.ErrInvalidAuthorizationDetails
+		} else if validator != nil {
+			if err := validator(ctx, clientID, detail); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return details, nil
+}
+
 // GetAuthorizeToken get authorization token(code)
 func (s *Server) GetAuthorizeToken(ctx context.Context, req *AuthorizeRequest) (oauth2.TokenInfo, error) {
+	return s.getAuthorizeToken(ctx, req, req.ResponseType)
+}
+
+// getAuthorizeToken get the authorization code or the access token of the response type
+func (s *Server) getAuthorizeToken(ctx context.Context, req *AuthorizeRequest, rt oauth2.ResponseType) (oauth2.TokenInfo, error) {
+	ctx = s.withIssuer(ctx)
+
 	// check the client allows the grant type
 	if fn := s.ClientAuthorizedHandler; fn != nil {
 		gt := oauth2.AuthorizationCode
-		if req.ResponseType == oauth2.Token {
+		if rt == oauth2.Token {
 			gt = oauth2.Implicit
 		}
 
@@ -230,12 +458,23 @@ func (s *Server) GetAuthorizeToken(ctx context.Context, req *AuthorizeRequest) (
 	}
 
 	tgr := &oauth2.TokenGenerateRequest{
-		ClientID:       req.ClientID,
-		UserID:         req.UserID,
-		RedirectURI:    req.RedirectURI,
-		Scope:          req.Scope,
-		AccessTokenExp: req.AccessTokenExp,
-		Request:        req.Request,
+		ClientID:             req.ClientID,
+		UserID:               req.UserID,
+		RedirectURI:          req.RedirectURI,
+		Scope:                req.Scope,
+		AccessTokenExp:       req.AccessTokenExp,
+		Resource:             req.Resource,
+		AuthorizationDetails: req.AuthorizationDetails,
+		Request:              req.Request,
+	}
+
+	// check the authorized scope, it may have been changed by the AuthorizeScopeHandler
+	if sr := s.ScopeRegistry; sr != nil {
+		scope, err := sr.Resolve(tgr.ClientID, tgr.Scope)
+		if err != nil {
+			return nil, err
+		}
+		tgr.Scope = scope
 	}
 
 	// check the client allows the authorized scope
@@ -251,7 +490,7 @@ func (s *Server) GetAuthorizeToken(ctx context.Context, req *AuthorizeRequest) (
 	tgr.CodeChallenge = req.CodeChallenge
 	tgr.CodeChallengeMethod = req.CodeChallengeMethod
 
-	return s.Manager.GenerateAuthToken(ctx, req.ResponseType, tgr)
+	return s.Manager.GenerateAuthToken(ctx, rt, tgr)
 }
 
 // GetAuthorizeData get authorization response data
@@ -266,6 +505,7 @@ func (s *Server) GetAuthorizeData(rt oauth2.ResponseType, ti oauth2.TokenInfo) m
 
 // HandleAuthorizeRequest the authorization request handling
 func (s *Server) HandleAuthorizeRequest(w http.ResponseWriter, r *http.Request) error {
+	r = s.withCorrelationID(r)
 	ctx := r.Context()
 
 	req, err := s.ValidationAuthorizeRequest(r)
@@ -288,7 +528,7 @@ func (s *Server) HandleAuthorizeRequest(w http.ResponseWriter, r *http.Request)
 		if err != nil {
 			return err
 		} else if scope != "" {
-			req.Scope = scope
+			req.Scope = oauth2.ParseScope(scope)
 		}
 	}
 
@@ -301,7 +541,17 @@ func (s *Server) HandleAuthorizeRequest(w http.ResponseWriter, r *http.Request)
 		req.AccessTokenExp = exp
 	}
 
-	ti, err := s.GetAuthorizeToken(ctx, req)
+	// user consent
+	if s.UserConsentHandler != nil {
+		consented, err := s.checkUserConsent(w, r, req)
+		if err != nil {
+			return s.handleError(w, req, err)
+		} else if !consented {
+			return nil
+		}
+	}
+
+	data, err := s.GetAuthorizeResponse(ctx, req)
 	if err != nil {
 		return s.handleError(w, req, err)
 	}
@@ -315,72 +565,149 @@ func (s *Server) HandleAuthorizeRequest(w http.ResponseWriter, r *http.Request)
 		req.RedirectURI = client.GetDomain()
 	}
 
-	return s.redirect(w, req, s.GetAuthorizeData(req.ResponseType, ti))
+	return s.redirect(w, req, data)
+}
+
+// checkUserConsent ask the user to consent when there is no valid consent or new scope is requested,
+// and add the consented scope to the user consent
+func (s *Server) checkUserConsent(w http.ResponseWriter, r *http.Request, req *AuthorizeRequest) (bool, error) {
+	ctx := r.Context()
+	cm, ok := s.Manager.(oauth2.ConsentManager)
+	if !ok {
+		return false, errors.New("the manager doesn't support the user consent")
+	}
+
+	consent, missing, err := cm.CheckConsent(ctx, req.UserID, req.ClientID, req.Scope)
+	if err != nil {
+		return false, err
+	}
+
+	required := consent == nil || len(missing) > 0
+	if fn := s.ConsentRequiredHandler; fn != nil {
+		required, err = fn(ctx, req, consent, missing)
+		if err != nil {
+			return false, err
+		}
+	}
+	if !required {
+		return true, nil
+	}
+
+	consented, err := s.UserConsentHandler(w, r, req, missing)
+	if err != nil || !consented {
+		return false, err
+	}
+
+	if _, err := cm.GrantConsent(ctx, req.UserID, req.ClientID, req.Scope); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// publicClient get the public client identified by the client_id only,
+// nil if the request has client credentials or the client isn't public
+func (s *Server) publicClient(r *http.Request) oauth2.ClientInfo {
+	clientID := r.FormValue("client_id")
+	if clientID == "" || r.Header.Get("Authorization") != "" || r.FormValue("client_secret") != "" {
+		return nil
+	}
+	if cli := s.getClient(r.Context(), clientID); cli != nil && cli.IsPublic() {
+		return cli
+	}
+	return nil
+}
+
+// tokenClient get the client of the token request, it's authenticated by the ClientAuthenticationHandler
+// or identified by the ClientInfoHandler, then the manager verifies the client secret.
+// The clients identified by the ClientInfoHandler must use their registered token endpoint
+// authentication method too: client_secret_basic, client_secret_post or none
+func (s *Server) tokenClient(r *http.Request, gt oauth2.GrantType) (oauth2.ClientInfo, *oauth2.TokenGenerateRequest, error) {
+	if fn := s.ClientAuthenticationHandler; fn != nil {
+		cli, _, err := fn(r)
+		if err != nil {
+			s.emit(r, &oauth2.Event{Type: oauth2.EventClientAuthFailed, ClientID: r.FormValue("client_id"), GrantType: gt, Error: err.Error()})
+			return nil, nil, err
+		}
+		return cli, &oauth2.TokenGenerateRequest{ClientID: cli.GetID(), ClientAuthenticated: true}, nil
+	}
+
+	clientID, clientSecret, err := s.ClientInfoHandler(r)
+	if err != nil {
+		// the public clients authenticate with the client_id only
+		cli := s.publicClient(r)
+		if cli == nil {
+			s.emit(r, &oauth2.Event{Type: oauth2.EventClientAuthFailed, GrantType: gt, Error: err.Error()})
+			return nil, nil, err
+		}
+		clientID = cli.GetID()
+	}
+
+	cli := s.getClient(r.Context(), clientID)
+	if cli != nil && !cli.IsPublic() && clientSecret == "" {
+		s.emit(r, &oauth2.Event{Type: oauth2.EventClientAuthFailed, ClientID: clientID, GrantType: gt})
+		return nil, nil, errors.ErrInvalidClient
+	} else if registered := registeredAuthMethod(cli); registered != "" && registered != requestAuthMethod(r, clientSecret) {
+		s.emit(r, &oauth2.Event{Type: oauth2.EventClientAuthFailed, ClientID: clientID, GrantType: gt})
+		return nil, nil, errors.ErrInvalidClient
+	}
+	return cli, &oauth2.TokenGenerateRequest{ClientID: clientID, ClientSecret: clientSecret}, nil
+}
+
+// requestAuthMethod the authentication method of the client credentials identified by the ClientInfoHandler
+func requestAuthMethod(r *http.Request, clientSecret string) string {
+	if clientSecret == "" {
+		return AuthMethodNone
+	} else if _, _, ok := r.BasicAuth(); ok {
+		return AuthMethodClientSecretBasic
+	}
+	return AuthMethodClientSecretPost
 }
 
 // ValidationTokenRequest the token request validation
 func (s *Server) ValidationTokenRequest(r *http.Request) (oauth2.GrantType, *oauth2.TokenGenerateRequest, error) {
 	if v := r.Method; !(v == "POST" ||
-		(s.Config.AllowGetAccessRequest && v == "GET")) {
+		(s.Config.AllowGetAccessRequest && !s.Config.OAuth21 && v == "GET")) {
 		return "", nil, errors.ErrInvalidRequest
 	}
 
 	gt := oauth2.GrantType(r.FormValue("grant_type"))
-	if gt.String() == "" {
+	gh, ok := s.grantHandler(gt)
+	if !ok || (s.Config.OAuth21 && gt == oauth2.PasswordCredentials) {
 		return "", nil, errors.ErrUnsupportedGrantType
 	}
 
-	clientID, clientSecret, err := s.ClientInfoHandler(r)
+	cli, tgr, err := s.tokenClient(r, gt)
 	if err != nil {
 		return "", nil, err
+	} else if cli != nil && cli.IsPublic() && gt == oauth2.ClientCredentials {
+		return "", nil, errors.ErrUnauthorizedClient
+	} else if !GetClientPolicy(cli).AllowsGrantType(gt) {
+		return "", nil, errors.ErrUnauthorizedClient
 	}
+	tgr.Resource = r.Form["resource"]
+	tgr.Request = r
 
-	tgr := &oauth2.TokenGenerateRequest{
-		ClientID:     clientID,
-		ClientSecret: clientSecret,
-		Request:      r,
+	tgr.AuthorizationDetails, err = s.ValidationAuthorizationDetails(r.Context(), tgr.ClientID, r.FormValue("authorization_details"))
+	if err != nil {
+		return "", nil, err
 	}
 
-	switch gt {
-	case oauth2.AuthorizationCode:
-		tgr.RedirectURI = r.FormValue("redirect_uri")
-		tgr.Code = r.FormValue("code")
-		if tgr.RedirectURI == "" ||
-			tgr.Code == "" {
-			return "", nil, errors.ErrInvalidRequest
-		}
-		tgr.CodeVerifier = r.FormValue("code_verifier")
-		if s.Config.ForcePKCE && tgr.CodeVerifier == "" {
-			return "", nil, errors.ErrInvalidRequest
-		}
-	case oauth2.PasswordCredentials:
-		tgr.Scope = r.FormValue("scope")
-		username, password := r.FormValue("username"), r.FormValue("password")
-		if username == "" || password == "" {
-			return "", nil, errors.ErrInvalidRequest
-		}
+	if err := gh.ParseRequest(r, tgr); err != nil {
+		return "", nil, err
+	}
 
-		userID, err := s.PasswordAuthorizationHandler(r.Context(), clientID, username, password)
-		if err != nil {
-			return "", nil, err
-		} else if userID == "" {
-			return "", nil, errors.ErrInvalidGrant
-		}
-		tgr.UserID = userID
-	case oauth2.ClientCredentials:
-		tgr.Scope = r.FormValue("scope")
-	case oauth2.Refreshing:
-		tgr.Refresh, err = s.RefreshTokenResolveHandler(r)
-		tgr.Scope = r.FormValue("scope")
-		if err != nil {
-			return "", nil, err
-		}
+	if gt == oauth2.AuthorizationCode && tgr.CodeVerifier == "" && s.IsPKCERequired(cli) {
+		return "", nil, errors.ErrInvalidRequest
 	}
 	return gt, tgr, nil
 }
 
 // CheckGrantType check allows grant type
 func (s *Server) CheckGrantType(gt oauth2.GrantType) bool {
+	// OAuth 2.1 removes the implicit and the password grants
+	if s.Config.OAuth21 && (gt == oauth2.Implicit || gt == oauth2.PasswordCredentials) {
+		return false
+	}
 	for _, agt := range s.Config.AllowedGrantTypes {
 		if agt == gt {
 			return true
@@ -392,6 +719,8 @@ func (s *Server) CheckGrantType(gt oauth2.GrantType) bool {
 // GetAccessToken access token
 func (s *Server) GetAccessToken(ctx context.Context, gt oauth2.GrantType, tgr *oauth2.TokenGenerateRequest) (oauth2.TokenInfo,
 	error) {
+	ctx = s.withIssuer(ctx)
+
 	if allowed := s.CheckGrantType(gt); !allowed {
 		return nil, errors.ErrUnauthorizedClient
 	}
@@ -405,76 +734,15 @@ func (s *Server) GetAccessToken(ctx context.Context, gt oauth2.GrantType, tgr *o
 		}
 	}
 
-	switch gt {
-	case oauth2.AuthorizationCode:
-		ti, err := s.Manager.GenerateAccessToken(ctx, gt, tgr)
-		if err != nil {
-			switch err {
-			case errors.ErrInvalidAuthorizeCode, errors.ErrInvalidCodeChallenge, errors.ErrMissingCodeChallenge:
-				return nil, errors.ErrInvalidGrant
-			case errors.ErrInvalidClient:
-				return nil, errors.ErrInvalidClient
-			default:
-				return nil, err
-			}
-		}
-		return ti, nil
-	case oauth2.PasswordCredentials, oauth2.ClientCredentials:
-		if fn := s.ClientScopeHandler; fn != nil {
-			allowed, err := fn(tgr)
-			if err != nil {
-				return nil, err
-			} else if !allowed {
-				return nil, errors.ErrInvalidScope
-			}
-		}
-		return s.Manager.GenerateAccessToken(ctx, gt, tgr)
-	case oauth2.Refreshing:
-		// check scope
-		if scopeFn := s.RefreshingScopeHandler; tgr.Scope != "" && scopeFn != nil {
-			rti, err := s.Manager.LoadRefreshToken(ctx, tgr.Refresh)
-			if err != nil {
-				if err == errors.ErrInvalidRefreshToken || err == errors.ErrExpiredRefreshToken {
-					return nil, errors.ErrInvalidGrant
-				}
-				return nil, err
-			}
-
-			allowed, err := scopeFn(tgr, rti.GetScope())
-			if err != nil {
-				return nil, err
-			} else if !allowed {
-				return nil, errors.ErrInvalidScope
-			}
-		}
-
-		if validationFn := s.RefreshingValidationHandler; validationFn != nil {
-			rti, err := s.Manager.LoadRefreshToken(ctx, tgr.Refresh)
-			if err != nil {
-				if err == errors.ErrInvalidRefreshToken || err == errors.ErrExpiredRefreshToken {
-					return nil, errors.ErrInvalidGrant
-				}
-				return nil, err
-			}
-			allowed, err := validationFn(rti)
-			if err != nil {
-				return nil, err
-			} else if !allowed {
-				return nil, errors.ErrInvalidScope
-			}
-		}
-
-		ti, err := s.Manager.RefreshAccessToken(ctx, tgr)
-		if err != nil {
-			if err == errors.ErrInvalidRefreshToken || err == errors.ErrExpiredRefreshToken {
-				return nil, errors.ErrInvalidGrant
-			}
-			return nil, err
-		}
-		return ti, nil
+	gh, ok := s.grantHandler(gt)
+	if !ok {
+		return nil, errors.ErrUnsupportedGrantType
 	}
 
-	return nil, errors.ErrUnsupportedGrantType
+	if err := gh.Validate(ctx, tgr); err != nil {
+		return nil, err
+	}
+	return gh.Issue(ctx, tgr)
 }
 
 // GetTokenData token data
@@ -485,14 +753,18 @@ func (s *Server) GetTokenData(ti oauth2.TokenInfo) map[string]interface{} {
 		"expires_in":   int64(ti.GetAccessExpiresIn() / time.Second),
 	}
 
-	if scope := ti.GetScope(); scope != "" {
-		data["scope"] = scope
+	if scope := ti.GetScope(); len(scope) > 0 {
+		data["scope"] = scope.String()
 	}
 
 	if refresh := ti.GetRefresh(); refresh != "" {
 		data["refresh_token"] = refresh
 	}
 
+	if dti, ok := ti.(oauth2.AuthorizationDetailsTokenInfo); ok && len(dti.GetAuthorizationDetails()) > 0 {
+		data["authorization_details"] = dti.GetAuthorizationDetails()
+	}
+
 	if fn := s.ExtensionFieldsHandler; fn != nil {
 		ext := fn(ti)
 		for k, v := range ext {
@@ -507,14 +779,33 @@ func (s *Server) GetTokenData(ti oauth2.TokenInfo) map[string]interface{} {
 
 // HandleTokenRequest token request handling
 func (s *Server) HandleTokenRequest(w http.ResponseWriter, r *http.Request) error {
+	r = s.withCorrelationID(r)
 	ctx := r.Context()
 
+	var keys []string
+	if s.RateLimiter != nil {
+		keys = s.rateLimitKeys(r)
+		if retryAfter, err := s.checkRateLimit(ctx, keys); err != nil {
+			return s.tokenError(w, err)
+		} else if retryAfter > 0 {
+			s.emit(r, &oauth2.Event{Type: oauth2.EventRateLimited, GrantType: oauth2.GrantType(r.FormValue("grant_type"))})
+			data, statusCode, header := s.GetErrorData(errors.ErrRateLimited)
+			if header == nil {
+				header = make(http.Header)
+			}
+			header.Set("Retry-After", strconv.FormatInt(int64(math.Ceil(retryAfter.Seconds())), 10))
+			return s.token(w, data, header, statusCode)
+		}
+	}
+
 	gt, tgr, err := s.ValidationTokenRequest(r)
 	if err != nil {
+		s.reportRateLimit(ctx, keys, oauth2.GrantType(r.FormValue("grant_type")), err)
 		return s.tokenError(w, err)
 	}
 
 	ti, err := s.GetAccessToken(ctx, gt, tgr)
+	s.reportRateLimit(ctx, keys, gt, err)
 	if err != nil {
 		return s.tokenError(w, err)
 	}
@@ -522,13 +813,73 @@ func (s *Server) HandleTokenRequest(w http.ResponseWriter, r *http.Request) erro
 	return s.token(w, s.GetTokenData(ti), nil)
 }
 
+func (s *Server) rateLimitKeys(r *http.Request) []string {
+	if fn := s.RateLimitKeyHandler; fn != nil {
+		return fn(r)
+	}
+	return DefaultRateLimitKeys(r)
+}
+
+// checkRateLimit returns how long to wait when a key isn't allowed,
+// the keys after the first denied one aren't charged
+func (s *Server) checkRateLimit(ctx context.Context, keys []string) (time.Duration, error) {
+	for _, key := range keys {
+		allowed, retryAfter, err := s.RateLimiter.Allow(ctx, key)
+		if err != nil {
+			return 0, err
+		} else if !allowed {
+			// at least one second to be reported in the Retry-After header
+			if retryAfter < time.Second {
+				retryAfter = time.Second
+			}
+			return retryAfter, nil
+		}
+	}
+	return 0, nil
+}
+
+// reportRateLimit record the failed authentications, a successful request resets the failures.
+// Only the failed client authentications and resource owner passwords count, not the invalid
+// grants such as the expired refresh tokens, so the clients behind a NAT aren't locked out
+func (s *Server) reportRateLimit(ctx context.Context, keys []string, gt oauth2.GrantType, err error) {
+	failed := errors.Is(err, errors.ErrInvalidClient) ||
+		(gt == oauth2.PasswordCredentials && (errors.Is(err, errors.ErrInvalidGrant) || errors.Is(err, errors.ErrAccessDenied)))
+	for _, key := range keys {
+		if err == nil {
+			_ = s.RateLimiter.Success(ctx, key)
+		} else if failed {
+			_ = s.RateLimiter.Failure(ctx, key)
+		}
+	}
+}
+
+// errorStatusCode the HTTP status code of the error code,
+// the status codes of RFC 6749 section 5.2 are used in the strict mode
+func (s *Server) errorStatusCode(code error) int {
+	if s.Config.StrictErrorStatus {
+		if v, ok := errors.RFCStatusCodes[code]; ok {
+			return v
+		}
+	}
+	if v, ok := errors.StatusCodes[code]; ok {
+		return v
+	}
+	return http.StatusBadRequest
+}
+
 // GetErrorData get error response data
 func (s *Server) GetErrorData(err error) (map[string]interface{}, int, http.Header) {
 	var re errors.Response
-	if v, ok := errors.Descriptions[err]; ok {
+	var oe *errors.OAuthError
+	if errors.As(err, &oe) {
+		re = *oe.Response()
+		if oe.StatusCode == 0 {
+			re.StatusCode = s.errorStatusCode(re.Error)
+		}
+	} else if v, ok := errors.Descriptions[err]; ok {
 		re.Error = err
 		re.Description = v
-		re.StatusCode = errors.StatusCodes[err]
+		re.StatusCode = s.errorStatusCode(err)
 	} else {
 		if fn := s.InternalErrorHandler; fn != nil {
 			if v := fn(err); v != nil {
@@ -577,6 +928,11 @@ func (s *Server) GetErrorData(err error) (map[string]interface{}, int, http.Head
 func (s *Server) ValidationBearerToken(r *http.Request) (oauth2.TokenInfo, error) {
 	ctx := r.Context()
 
+	// OAuth 2.1 doesn't allow the access token in the query
+	if s.Config.OAuth21 && r.URL.Query().Has("access_token") {
+		return nil, errors.ErrInvalidRequest
+	}
+
 	accessToken, ok := s.AccessTokenResolveHandler(r)
 	if !ok {
 		return nil, errors.ErrInvalidAccessToken
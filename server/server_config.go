@@ -14,6 +14,18 @@ func (s *Server) SetAllowGetAccessRequest(allow bool) {
 	s.Config.AllowGetAccessRequest = allow
 }
 
+// SetStrictErrorStatus respond with the error status codes of RFC 6749 section 5.2,
+// e.g. 400 instead of 401 for invalid_grant
+func (s *Server) SetStrictErrorStatus(strict bool) {
+	s.Config.StrictErrorStatus = strict
+}
+
+// SetIssuer set the issuer identifier of the authorization server,
+// it's also the iss claim of the JWT access tokens when the generator has no issuer of its own
+func (s *Server) SetIssuer(issuer string) {
+	s.Config.Issuer = issuer
+}
+
 // SetAllowedResponseType allow the authorization types
 func (s *Server) SetAllowedResponseType(types ...oauth2.ResponseType) {
 	s.Config.AllowedResponseTypes = types
@@ -29,6 +41,11 @@ func (s *Server) SetClientInfoHandler(handler ClientInfoHandler) {
 	s.ClientInfoHandler = handler
 }
 
+// SetClientAuthenticationHandler authenticate the clients with the handler instead of the ClientInfoHandler
+func (s *Server) SetClientAuthenticationHandler(handler ClientAuthenticationHandler) {
+	s.ClientAuthenticationHandler = handler
+}
+
 // SetClientAuthorizedHandler check the client allows to use this authorization grant type
 func (s *Server) SetClientAuthorizedHandler(handler ClientAuthorizedHandler) {
 	s.ClientAuthorizedHandler = handler
@@ -44,6 +61,54 @@ func (s *Server) SetUserAuthorizationHandler(handler UserAuthorizationHandler) {
 	s.UserAuthorizationHandler = handler
 }
 
+// SetUserConsentHandler ask the user to consent, the user consent is checked only when it is set
+// and the manager implements oauth2.ConsentManager
+func (s *Server) SetUserConsentHandler(handler UserConsentHandler) {
+	s.UserConsentHandler = handler
+}
+
+// SetConsentRequiredHandler decide whether the user has to consent to the authorization request
+func (s *Server) SetConsentRequiredHandler(handler ConsentRequiredHandler) {
+	s.ConsentRequiredHandler = handler
+}
+
+// SetScopeRegistry set the known scopes, the requested scopes are checked against it
+// and it replaces the refreshing scope handler
+func (s *Server) SetScopeRegistry(registry *ScopeRegistry) {
+	s.ScopeRegistry = registry
+}
+
+// SetAuthorizationDetailType register the authorization details type (RFC 9396),
+// the validator can be nil to accept every object of the type
+func (s *Server) SetAuthorizationDetailType(typ string, validator AuthorizationDetailValidator) {
+	if s.AuthorizationDetailTypes == nil {
+		s.AuthorizationDetailTypes = make(map[string]AuthorizationDetailValidator)
+	}
+	s.AuthorizationDetailTypes[typ] = validator
+}
+
+// SetRateLimiter limit the token requests, the keys are given by the RateLimitKeyHandler
+// or DefaultRateLimitKeys
+func (s *Server) SetRateLimiter(limiter RateLimiter) {
+	s.RateLimiter = limiter
+}
+
+// SetRateLimitKeyHandler get the rate limited keys of the token request
+func (s *Server) SetRateLimitKeyHandler(handler RateLimitKeyHandler) {
+	s.RateLimitKeyHandler = handler
+}
+
+// SetCorrelationIDHandler get the correlation ID of the events from the request
+func (s *Server) SetCorrelationIDHandler(handler CorrelationIDHandler) {
+	s.CorrelationIDHandler = handler
+}
+
+// Subscribe add the subscribers of the server events, the grant lifecycle
+// events are emitted by the manager
+func (s *Server) Subscribe(subs ...oauth2.EventSubscriber) {
+	s.Subscribers = append(s.Subscribers, subs...)
+}
+
 // SetPasswordAuthorizationHandler get user id from username and password
 func (s *Server) SetPasswordAuthorizationHandler(handler PasswordAuthorizationHandler) {
 	s.PasswordAuthorizationHandler = handler
@@ -103,3 +168,50 @@ func (s *Server) SetRefreshTokenResolveHandler(handler RefreshTokenResolveHandle
 func (s *Server) SetAccessTokenResolveHandler(handler AccessTokenResolveHandler) {
 	s.AccessTokenResolveHandler = handler
 }
+
+// SetGrantHandler register the handler of the grant type (e.g. an extension grant) and allow it,
+// it replaces the built-in handler of the grant type
+func (s *Server) SetGrantHandler(gt oauth2.GrantType, handler GrantHandler) {
+	if s.GrantHandlers == nil {
+		s.GrantHandlers = s.defaultGrantHandlers()
+	}
+	s.GrantHandlers[gt] = handler
+	if !s.CheckGrantType(gt) {
+		s.Config.AllowedGrantTypes = append(s.Config.AllowedGrantTypes, gt)
+	}
+}
+
+// SetResponseType register the response type (e.g. a hybrid flow) with its default response mode and allow it,
+// it replaces the built-in response type
+func (s *Server) SetResponseType(rt oauth2.ResponseType, mode oauth2.ResponseMode, handler ResponseTypeHandler) {
+	rt = oauth2.ParseResponseType(rt.String())
+	if s.ResponseTypes == nil {
+		s.ResponseTypes = s.defaultResponseTypes()
+	}
+	s.ResponseTypes[rt] = &ResponseTypeDefinition{DefaultResponseMode: mode, Handler: handler}
+	if !s.CheckResponseType(rt) {
+		s.Config.AllowedResponseTypes = append(s.Config.AllowedResponseTypes, rt)
+	}
+}
+
+// SetIDTokenHandler issue the ID token of the id_token response types
+func (s *Server) SetIDTokenHandler(handler IDTokenHandler) {
+	s.IDTokenHandler = handler
+}
+
+// SetJARM sign the authorization responses of the jwt response modes,
+// the config isn't set when it's invalid (see JARMConfig.Validate)
+func (s *Server) SetJARM(cfg *JARMConfig) error {
+	if cfg != nil {
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+	}
+	s.JARM = cfg
+	return nil
+}
+
+// SetMetadata set the additional authorization server metadata, e.g. the endpoint URLs
+func (s *Server) SetMetadata(metadata map[string]interface{}) {
+	s.Metadata = metadata
+}
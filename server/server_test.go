@@ -102,7 +102,7 @@ func TestAuthorizeCode(t *testing.T) {
 	}))
 	defer csrv.Close()
 
-	manager.MapClientStorage(clientStore(csrv.URL, true))
+	manager.MapClientStorage(clientStore(csrv.URL, false))
 	srv = server.NewDefaultServer(manager)
 	srv.SetUserAuthorizationHandler(func(w http.ResponseWriter, r *http.Request) (userID string, err error) {
 		userID = "000000"
@@ -365,6 +365,23 @@ func TestRefreshing(t *testing.T) {
 
 			validationAccessToken(t, jresObj.Value("access_token").String().Raw())
 
+			// the client must authenticate and own the refresh token
+			e.POST("/token").
+				WithFormField("grant_type", "refresh_token").
+				WithFormField("refresh_token", jresObj.Value("refresh_token").String().Raw()).
+				WithBasicAuth(clientID, "WRONG").
+				Expect().
+				Status(http.StatusUnauthorized).
+				JSON().Object().ValueEqual("error", "invalid_client")
+
+			e.POST("/token").
+				WithFormField("grant_type", "refresh_token").
+				WithFormField("refresh_token", jresObj.Value("refresh_token").String().Raw()).
+				WithBasicAuth("222222", "22222222").
+				Expect().
+				Status(http.StatusUnauthorized).
+				JSON().Object().ValueEqual("error", "invalid_grant")
+
 			resObj := e.POST("/token").
 				WithFormField("grant_type", "refresh_token").
 				WithFormField("scope", "one").
@@ -381,7 +398,9 @@ func TestRefreshing(t *testing.T) {
 	}))
 	defer csrv.Close()
 
-	manager.MapClientStorage(clientStore(csrv.URL, true))
+	cs := clientStore(csrv.URL, false)
+	cs.(*store.ClientStore).Set("222222", &models.Client{ID: "222222", Secret: "22222222", Domain: csrv.URL})
+	manager.MapClientStorage(cs)
 	srv = server.NewDefaultServer(manager)
 	srv.SetUserAuthorizationHandler(func(w http.ResponseWriter, r *http.Request) (userID string, err error) {
 		userID = "000000"
@@ -412,3 +431,729 @@ func validationAccessToken(t *testing.T, accessToken string) {
 		t.Error("invalid access token")
 	}
 }
+
+func TestAuthorizeCodeWithConsent(t *testing.T) {
+	tsrv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testServer(t, w, r)
+	}))
+	defer tsrv.Close()
+	e := httpexpect.New(t, tsrv.URL)
+
+	var codes []string
+	csrv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if code := r.FormValue("code"); code != "" {
+			codes = append(codes, code)
+		}
+	}))
+	defer csrv.Close()
+
+	cmanager := manage.NewDefaultManager()
+	cmanager.MustTokenStorage(store.NewMemoryTokenStore())
+	cmanager.MapClientStorage(clientStore(csrv.URL, false))
+	cmanager.MapConsentStorage(store.NewConsentStore())
+
+	srv = server.NewDefaultServer(cmanager)
+	srv.SetUserAuthorizationHandler(func(w http.ResponseWriter, r *http.Request) (userID string, err error) {
+		return "000000", nil
+	})
+	var asked []string
+	consented := true
+	srv.SetUserConsentHandler(func(w http.ResponseWriter, r *http.Request, req *server.AuthorizeRequest, scope oauth2.Scope) (bool, error) {
+		asked = append(asked, scope.String())
+		return consented, nil
+	})
+
+	authorize := func(scope string) {
+		e.GET("/authorize").
+			WithQuery("response_type", "code").
+			WithQuery("client_id", clientID).
+			WithQuery("scope", scope).
+			WithQuery("redirect_uri", csrv.URL+"/oauth2").
+			Expect().Status(http.StatusOK)
+	}
+
+	authorize("read")
+	authorize("read")
+	authorize("read write")
+	if len(codes) != 3 {
+		t.Fatalf("unexpected codes: %v", codes)
+	}
+	if fmt.Sprint(asked) != "[read write]" {
+		t.Fatalf("unexpected consent prompts: %v", asked)
+	}
+
+	// the consent page is rendered
+	consented = false
+	if err := cmanager.RevokeConsent(context.Background(), "000000", clientID); err != nil {
+		t.Fatal(err)
+	}
+	authorize("read")
+	if len(codes) != 3 || fmt.Sprint(asked) != "[read write read]" {
+		t.Fatalf("unexpected consent flow: %v %v", codes, asked)
+	}
+
+	// the consent isn't required by the decision hook
+	srv.SetConsentRequiredHandler(func(ctx context.Context, req *server.AuthorizeRequest, consent oauth2.ConsentInfo, missingScope oauth2.Scope) (bool, error) {
+		return consent != nil, nil
+	})
+	authorize("read")
+	if len(codes) != 4 {
+		t.Fatalf("unexpected codes: %v", codes)
+	}
+}
+
+func TestClientCredentialsWithResource(t *testing.T) {
+	tsrv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testServer(t, w, r)
+	}))
+	defer tsrv.Close()
+	e := httpexpect.New(t, tsrv.URL)
+
+	cs := store.NewClientStore()
+	cs.Set(clientID, &models.Client{
+		ID:        clientID,
+		Secret:    clientSecret,
+		Resources: []string{"https://api.example.com"},
+	})
+	manager.MapClientStorage(cs)
+	srv = server.NewDefaultServer(manager)
+
+	e.POST("/token").
+		WithFormField("grant_type", "client_credentials").
+		WithFormField("resource", "https://other.example.com").
+		WithBasicAuth(clientID, clientSecret).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().Object().Value("error").Equal("invalid_target")
+
+	access := e.POST("/token").
+		WithFormField("grant_type", "client_credentials").
+		WithFormField("resource", "https://api.example.com").
+		WithBasicAuth(clientID, clientSecret).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("access_token").String().Raw()
+
+	ti, err := manager.LoadAccessToken(context.Background(), access)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := ti.(oauth2.ResourceTokenInfo).GetResource(); fmt.Sprint(v) != "[https://api.example.com]" {
+		t.Fatalf("unexpected resource: %v", v)
+	}
+}
+
+func TestAuthorizeCodeWithAuthorizationDetails(t *testing.T) {
+	var tokenErr string
+	tsrv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/authorize" {
+			testServer(t, w, r)
+		} else if err := srv.HandleAuthorizeRequest(w, r); err != nil {
+			// the invalid requests aren't redirected before the redirect URI is checked
+			tokenErr = err.Error()
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}))
+	defer tsrv.Close()
+	e := httpexpect.New(t, tsrv.URL)
+
+	details := `[{"type":"payment_initiation","instructedAmount":{"currency":"EUR","amount":"123.50"}}]`
+	csrv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if v := r.Form.Get("error"); v != "" {
+			tokenErr = v
+			return
+		}
+		resObj := e.POST("/token").
+			WithFormField("redirect_uri", csrv.URL+"/oauth2").
+			WithFormField("code", r.Form.Get("code")).
+			WithFormField("grant_type", "authorization_code").
+			WithBasicAuth(clientID, clientSecret).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object()
+
+		resObj.Value("authorization_details").Array().Element(0).Object().
+			ValueEqual("type", "payment_initiation")
+		ti, err := manager.LoadAccessToken(r.Context(), resObj.Value("access_token").String().Raw())
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if v := ti.(oauth2.AuthorizationDetailsTokenInfo).GetAuthorizationDetails(); len(v) != 1 || v[0]["instructedAmount"] == nil {
+			t.Errorf("unexpected authorization details: %v", v)
+		}
+	}))
+	defer csrv.Close()
+
+	manager.MapClientStorage(clientStore(csrv.URL, false))
+	srv = server.NewDefaultServer(manager)
+	srv.SetUserAuthorizationHandler(func(w http.ResponseWriter, r *http.Request) (userID string, err error) {
+		return "000000", nil
+	})
+	srv.SetAuthorizationDetailType("payment_initiation", func(ctx context.Context, clientID string, detail oauth2.AuthorizationDetail) error {
+		if _, ok := detail["instructedAmount"].(map[string]interface{}); !ok {
+			return errors.ErrInvalidAuthorizationDetails
+		}
+		return nil
+	})
+
+	authorize := func(details string, status int) {
+		e.GET("/authorize").
+			WithQuery("response_type", "code").
+			WithQuery("client_id", clientID).
+			WithQuery("authorization_details", details).
+			WithQuery("redirect_uri", csrv.URL+"/oauth2").
+			Expect().Status(status)
+	}
+
+	authorize(details, http.StatusOK)
+	if tokenErr != "" {
+		t.Fatalf("unexpected error: %s", tokenErr)
+	}
+
+	authorize(`[{"type":"account_information"}]`, http.StatusBadRequest)
+	if tokenErr != "invalid_authorization_details" {
+		t.Fatalf("unexpected error: %s", tokenErr)
+	}
+
+	tokenErr = ""
+	authorize(`[{"type":"payment_initiation"}]`, http.StatusBadRequest)
+	if tokenErr != "invalid_authorization_details" {
+		t.Fatalf("unexpected error: %s", tokenErr)
+	}
+}
+
+func TestEventCorrelation(t *testing.T) {
+	tsrv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testServer(t, w, r)
+	}))
+	defer tsrv.Close()
+	e := httpexpect.New(t, tsrv.URL)
+
+	emanager := manage.NewDefaultManager()
+	emanager.MustTokenStorage(store.NewMemoryTokenStore())
+	emanager.MapClientStorage(clientStore("", false))
+
+	var events []*oauth2.Event
+	collect := oauth2.EventSubscriberFunc(func(ctx context.Context, e *oauth2.Event) {
+		events = append(events, e)
+	})
+	emanager.Subscribe(collect)
+	srv = server.NewDefaultServer(emanager)
+	srv.Subscribe(collect)
+
+	e.POST("/token").
+		WithFormField("grant_type", "client_credentials").
+		WithHeader("X-Request-Id", "req-1").
+		WithBasicAuth(clientID, clientSecret).
+		Expect().
+		Status(http.StatusOK)
+
+	e.POST("/token").
+		WithFormField("grant_type", "client_credentials").
+		WithHeader("X-Request-Id", "req-2").
+		Expect().
+		Status(http.StatusUnauthorized)
+
+	if len(events) != 2 {
+		t.Fatalf("unexpected events: %v", events)
+	}
+	if ev := events[0]; ev.Type != oauth2.EventTokenIssued || ev.CorrelationID != "req-1" || ev.IP != "127.0.0.1" ||
+		ev.ClientID != clientID || ev.GrantType != oauth2.ClientCredentials {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+	if ev := events[1]; ev.Type != oauth2.EventClientAuthFailed || ev.CorrelationID != "req-2" {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+}
+
+func TestOAuthError(t *testing.T) {
+	tsrv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testServer(t, w, r)
+	}))
+	defer tsrv.Close()
+	e := httpexpect.New(t, tsrv.URL)
+
+	csrv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("error") != "access_denied" ||
+			q.Get("error_description") != "The user cancelled the request" ||
+			q.Get("error_uri") != "https://example.com/errors/cancelled" ||
+			q.Get("state") != "123" {
+			t.Errorf("unexpected error response: %s", r.URL.RawQuery)
+		}
+	}))
+	defer csrv.Close()
+
+	manager.MapClientStorage(clientStore(csrv.URL, false))
+	srv = server.NewDefaultServer(manager)
+	srv.SetUserAuthorizationHandler(func(w http.ResponseWriter, r *http.Request) (userID string, err error) {
+		return "", errors.NewOAuthError(errors.ErrAccessDenied, "The user cancelled the request").
+			WithURI("https://example.com/errors/cancelled")
+	})
+	srv.SetAllowGetAccessRequest(true)
+	srv.SetClientInfoHandler(server.ClientFormHandler)
+	srv.SetPasswordAuthorizationHandler(func(ctx context.Context, clientID, username, password string) (userID string, err error) {
+		return "", errors.NewOAuthError(errors.ErrInvalidGrant, "The account is locked").
+			WithStatusCode(http.StatusBadRequest).
+			WithCause(fmt.Errorf("user %s is locked", username))
+	})
+
+	e.GET("/authorize").
+		WithQuery("response_type", "code").
+		WithQuery("client_id", clientID).
+		WithQuery("scope", "all").
+		WithQuery("state", "123").
+		WithQuery("redirect_uri", csrv.URL+"/oauth2").
+		Expect().Status(http.StatusOK)
+
+	resObj := e.POST("/token").
+		WithFormField("grant_type", "password").
+		WithFormField("client_id", clientID).
+		WithFormField("client_secret", clientSecret).
+		WithFormField("username", "admin").
+		WithFormField("password", "123456").
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().Object()
+	resObj.Value("error").Equal("invalid_grant")
+	resObj.Value("error_description").Equal("The account is locked")
+	resObj.NotContainsKey("error_uri")
+
+	// the unknown error codes are bad requests
+	srv.SetPasswordAuthorizationHandler(func(ctx context.Context, clientID, username, password string) (userID string, err error) {
+		return "", errors.NewOAuthError(errors.New("account_locked"), "")
+	})
+	e.POST("/token").
+		WithFormField("grant_type", "password").
+		WithFormField("client_id", clientID).
+		WithFormField("client_secret", clientSecret).
+		WithFormField("username", "admin").
+		WithFormField("password", "123456").
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().Object().Value("error").Equal("account_locked")
+}
+
+func TestStrictErrorStatus(t *testing.T) {
+	tsrv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testServer(t, w, r)
+	}))
+	defer tsrv.Close()
+	e := httpexpect.New(t, tsrv.URL)
+
+	manager.MapClientStorage(clientStore("", false))
+	srv = server.NewDefaultServer(manager)
+	srv.SetStrictErrorStatus(true)
+
+	e.POST("/token").
+		WithFormField("grant_type", "refresh_token").
+		WithFormField("refresh_token", "unknown").
+		WithBasicAuth(clientID, clientSecret).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().Object().Value("error").Equal("invalid_grant")
+
+	e.POST("/token").
+		WithFormField("grant_type", "urn:example:unknown").
+		WithBasicAuth(clientID, clientSecret).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().Object().Value("error").Equal("unsupported_grant_type")
+
+	e.POST("/token").
+		WithFormField("grant_type", "client_credentials").
+		WithBasicAuth(clientID, "invalid").
+		Expect().
+		Status(http.StatusUnauthorized).
+		JSON().Object().Value("error").Equal("invalid_client")
+}
+
+func TestOAuth21(t *testing.T) {
+	tsrv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/authorize":
+			if err := srv.HandleAuthorizeRequest(w, r); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			}
+		case "/token":
+			if err := srv.HandleTokenRequest(w, r); err != nil {
+				t.Error(err)
+			}
+		}
+	}))
+	defer tsrv.Close()
+	e := newRedirectExpect(t, tsrv.URL)
+
+	// the refreshing config doesn't rotate the refresh tokens
+	manager.SetRefreshTokenCfg(&manage.RefreshingConfig{IsRemoveAccess: true})
+	defer manager.SetRefreshTokenCfg(nil)
+
+	redirectURI := "http://localhost/oauth2"
+	manager.MapClientStorage(clientStore(redirectURI, true))
+	srv = server.NewServer(server.NewOAuth21Config(), manager)
+	srv.SetClientInfoHandler(server.ClientFormHandler)
+	srv.SetUserAuthorizationHandler(func(w http.ResponseWriter, r *http.Request) (userID string, err error) {
+		return "000000", nil
+	})
+
+	authorize := func(responseType, redirectURI, challenge, method string) *httpexpect.Response {
+		return e.GET("/authorize").
+			WithQuery("response_type", responseType).
+			WithQuery("client_id", clientID).
+			WithQuery("redirect_uri", redirectURI).
+			WithQuery("state", "123").
+			WithQuery("code_challenge", challenge).
+			WithQuery("code_challenge_method", method).
+			Expect()
+	}
+
+	// the implicit grant and the inexact redirect URIs are refused
+	authorize("token", redirectURI, s256ChallengeHash, "S256").Status(http.StatusBadRequest)
+	authorize("code", redirectURI+"/callback", s256ChallengeHash, "S256").Status(http.StatusBadRequest)
+
+	// the S256 PKCE is required
+	authorize("code", redirectURI, "", "").Status(http.StatusBadRequest).
+		Body().Contains("code_challenge is missing")
+	authorize("code", redirectURI, plainChallenge, "plain").Status(http.StatusBadRequest).
+		Body().Contains("code_challenge_method not supported")
+
+	values := redirectValues(t, authorize("code", redirectURI, s256ChallengeHash, "S256").Status(http.StatusFound).Header("Location").Raw(), false)
+	code := values.Get("code")
+	if code == "" {
+		t.Fatalf("unexpected code response: %v", values)
+	}
+
+	// the token requests must be POST requests
+	srv.SetAllowGetAccessRequest(true)
+	e.GET("/token").
+		WithQuery("grant_type", "authorization_code").
+		WithQuery("client_id", clientID).
+		WithQuery("redirect_uri", redirectURI).
+		WithQuery("code", code).
+		WithQuery("code_verifier", s256Challenge).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().Object().ValueEqual("error", "invalid_request")
+
+	resObj := e.POST("/token").
+		WithFormField("grant_type", "authorization_code").
+		WithFormField("client_id", clientID).
+		WithFormField("redirect_uri", redirectURI).
+		WithFormField("code", code).
+		WithFormField("code_verifier", s256Challenge).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+	accessToken := resObj.Value("access_token").String().Raw()
+	refreshToken := resObj.Value("refresh_token").String().Raw()
+
+	// the refresh tokens of the public clients are rotated
+	newRefreshToken := e.POST("/token").
+		WithFormField("grant_type", "refresh_token").
+		WithFormField("client_id", clientID).
+		WithFormField("refresh_token", refreshToken).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("refresh_token").String().NotEqual(refreshToken).Raw()
+	if _, err := manager.LoadRefreshToken(context.Background(), refreshToken); err == nil {
+		t.Error("the used refresh token isn't removed")
+	}
+	if _, err := manager.LoadRefreshToken(context.Background(), newRefreshToken); err != nil {
+		t.Error(err)
+	}
+
+	e.POST("/token").
+		WithFormField("grant_type", "password").
+		WithFormField("client_id", clientID).
+		WithFormField("username", "admin").
+		WithFormField("password", "123456").
+		Expect().
+		Status(http.StatusUnauthorized).
+		JSON().Object().ValueEqual("error", "unsupported_grant_type")
+
+	// the access token isn't accepted in the query
+	req := httptest.NewRequest("GET", "http://example.com?access_token="+accessToken, nil)
+	if _, err := srv.ValidationBearerToken(req); !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("unexpected error of the access token in the query: %v", err)
+	}
+}
+
+func TestClientPolicy(t *testing.T) {
+	tsrv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/authorize":
+			if err := srv.HandleAuthorizeRequest(w, r); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			}
+		case "/token":
+			if err := srv.HandleTokenRequest(w, r); err != nil {
+				t.Error(err)
+			}
+		}
+	}))
+	defer tsrv.Close()
+	e := newRedirectExpect(t, tsrv.URL)
+
+	redirectURI := "http://localhost/oauth2"
+	publicClientID := "222222"
+	cs := store.NewClientStore()
+	cs.Set(publicClientID, &models.Client{
+		ID:     publicClientID,
+		Domain: redirectURI,
+		Public: true,
+		Policy: &oauth2.ClientPolicy{
+			PKCE:                 oauth2.PKCERequired,
+			CodeChallengeMethods: []oauth2.CodeChallengeMethod{oauth2.CodeChallengeS256},
+			GrantTypes:           []oauth2.GrantType{oauth2.AuthorizationCode, oauth2.Refreshing},
+		},
+	})
+	// the legacy confidential client doesn't support PKCE
+	cs.Set(clientID, &models.Client{
+		ID:     clientID,
+		Secret: clientSecret,
+		Domain: redirectURI,
+		Policy: &oauth2.ClientPolicy{
+			PKCE:          oauth2.PKCEOptional,
+			ResponseTypes: []oauth2.ResponseType{oauth2.Code},
+		},
+	})
+	manager.MapClientStorage(cs)
+
+	srv = server.NewDefaultServer(manager)
+	srv.Config.ForcePKCE = true
+	srv.SetUserAuthorizationHandler(func(w http.ResponseWriter, r *http.Request) (userID string, err error) {
+		return "000000", nil
+	})
+
+	authorize := func(responseType, clientID, challenge, method string) *httpexpect.Response {
+		return e.GET("/authorize").
+			WithQuery("response_type", responseType).
+			WithQuery("client_id", clientID).
+			WithQuery("redirect_uri", redirectURI).
+			WithQuery("state", "123").
+			WithQuery("code_challenge", challenge).
+			WithQuery("code_challenge_method", method).
+			Expect()
+	}
+
+	// the public client must use the S256 PKCE
+	authorize("code", publicClientID, "", "").Status(http.StatusBadRequest).
+		Body().Contains("code_challenge is missing")
+	authorize("code", publicClientID, plainChallenge, "plain").Status(http.StatusBadRequest).
+		Body().Contains("code_challenge_method not supported")
+	values := redirectValues(t, authorize("code", publicClientID, s256ChallengeHash, "S256").
+		Status(http.StatusFound).Header("Location").Raw(), false)
+	if values.Get("code") == "" {
+		t.Fatalf("unexpected code response of the public client: %v", values)
+	}
+
+	e.POST("/token").
+		WithFormField("grant_type", "authorization_code").
+		WithFormField("redirect_uri", redirectURI).
+		WithFormField("code", values.Get("code")).
+		WithBasicAuth(publicClientID, "").
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().Object().ValueEqual("error", "invalid_request")
+
+	e.POST("/token").
+		WithFormField("grant_type", "client_credentials").
+		WithBasicAuth(publicClientID, "").
+		Expect().
+		Status(http.StatusUnauthorized).
+		JSON().Object().ValueEqual("error", "unauthorized_client")
+
+	// the legacy client doesn't need PKCE even if the server forces it
+	authorize("token", clientID, "", "").Status(http.StatusBadRequest).
+		Body().Contains("unauthorized_client")
+	values = redirectValues(t, authorize("code", clientID, "", "").
+		Status(http.StatusFound).Header("Location").Raw(), false)
+	if values.Get("code") == "" {
+		t.Fatalf("unexpected code response of the legacy client: %v", values)
+	}
+
+	e.POST("/token").
+		WithFormField("grant_type", "authorization_code").
+		WithFormField("redirect_uri", redirectURI).
+		WithFormField("code", values.Get("code")).
+		WithBasicAuth(clientID, clientSecret).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().ContainsKey("access_token")
+}
+
+func TestIsPKCERequired(t *testing.T) {
+	srv := server.NewDefaultServer(manage.NewDefaultManager())
+	noPolicy := &models.Client{ID: "1"}
+	required := &models.Client{ID: "2", Policy: &oauth2.ClientPolicy{PKCE: oauth2.PKCERequired}}
+	optional := &models.Client{ID: "3", Policy: &oauth2.ClientPolicy{PKCE: oauth2.PKCEOptional}}
+	public := &models.Client{ID: "4", Public: true, Policy: &oauth2.ClientPolicy{PKCE: oauth2.PKCEOptional}}
+
+	if server.GetClientPolicy(noPolicy) != nil || server.GetClientPolicy(nil) != nil {
+		t.Error("unexpected policy of the client without one")
+	} else if server.GetClientPolicy(required) != required.Policy {
+		t.Error("unexpected policy of the client")
+	}
+
+	for _, v := range []struct {
+		cli       oauth2.ClientInfo
+		forcePKCE bool
+		oauth21   bool
+		required  bool
+	}{
+		{nil, false, false, false},
+		{noPolicy, false, false, false},
+		{noPolicy, true, false, true},
+		{required, false, false, true},
+		{optional, true, false, false},
+		{optional, false, true, true},
+		{public, false, false, true},
+	} {
+		srv.Config.ForcePKCE = v.forcePKCE
+		srv.Config.OAuth21 = v.oauth21
+		if srv.IsPKCERequired(v.cli) != v.required {
+			t.Errorf("unexpected PKCE requirement of %v (ForcePKCE %v, OAuth21 %v)", v.cli, v.forcePKCE, v.oauth21)
+		}
+	}
+}
+
+func TestPublicClient(t *testing.T) {
+	tsrv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/authorize":
+			if err := srv.HandleAuthorizeRequest(w, r); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			}
+		case "/token":
+			if err := srv.HandleTokenRequest(w, r); err != nil {
+				t.Error(err)
+			}
+		}
+	}))
+	defer tsrv.Close()
+	e := newRedirectExpect(t, tsrv.URL)
+
+	redirectURI := "http://localhost/oauth2"
+	publicClientID := "222222"
+	cs := store.NewClientStore()
+	cs.Set(publicClientID, &models.Client{ID: publicClientID, Domain: redirectURI, Public: true})
+	// the confidential client without secret must still authenticate
+	cs.Set(clientID, &models.Client{ID: clientID, Domain: redirectURI})
+	manager.MapClientStorage(cs)
+
+	srv = server.NewDefaultServer(manager)
+	srv.SetUserAuthorizationHandler(func(w http.ResponseWriter, r *http.Request) (userID string, err error) {
+		return "000000", nil
+	})
+
+	authorize := func(challenge string) *httpexpect.Response {
+		return e.GET("/authorize").
+			WithQuery("response_type", "code").
+			WithQuery("client_id", publicClientID).
+			WithQuery("redirect_uri", redirectURI).
+			WithQuery("state", "123").
+			WithQuery("code_challenge", challenge).
+			WithQuery("code_challenge_method", "S256").
+			Expect()
+	}
+
+	// PKCE is mandatory for the public clients
+	authorize("").Status(http.StatusBadRequest).Body().Contains("code_challenge is missing")
+
+	// but only for the response types returning a code
+	location := e.GET("/authorize").
+		WithQuery("response_type", "token").
+		WithQuery("client_id", publicClientID).
+		WithQuery("redirect_uri", redirectURI).
+		WithQuery("state", "123").
+		Expect().
+		Status(http.StatusFound).
+		Header("Location").Raw()
+	if v := redirectValues(t, location, true); v.Get("access_token") == "" {
+		t.Fatalf("unexpected token response: %v", v)
+	}
+	values := redirectValues(t, authorize(s256ChallengeHash).Status(http.StatusFound).Header("Location").Raw(), false)
+	if values.Get("code") == "" {
+		t.Fatalf("unexpected code response: %v", values)
+	}
+
+	e.POST("/token").
+		WithFormField("grant_type", "authorization_code").
+		WithFormField("client_id", publicClientID).
+		WithFormField("redirect_uri", redirectURI).
+		WithFormField("code", values.Get("code")).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().Object().ValueEqual("error", "invalid_request")
+
+	// the public client authenticates with the client_id only
+	values = redirectValues(t, authorize(s256ChallengeHash).Status(http.StatusFound).Header("Location").Raw(), false)
+	e.POST("/token").
+		WithFormField("grant_type", "authorization_code").
+		WithFormField("client_id", publicClientID).
+		WithFormField("redirect_uri", redirectURI).
+		WithFormField("code", values.Get("code")).
+		WithFormField("code_verifier", s256Challenge).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().ContainsKey("access_token")
+
+	e.POST("/token").
+		WithFormField("grant_type", "client_credentials").
+		WithFormField("client_id", publicClientID).
+		Expect().
+		Status(http.StatusUnauthorized).
+		JSON().Object().ValueEqual("error", "unauthorized_client")
+
+	// the confidential clients can't authenticate with the client_id only
+	e.POST("/token").
+		WithFormField("grant_type", "client_credentials").
+		WithFormField("client_id", clientID).
+		Expect().
+		Status(http.StatusUnauthorized).
+		JSON().Object().ValueEqual("error", "invalid_client")
+
+	e.POST("/token").
+		WithFormField("grant_type", "client_credentials").
+		WithBasicAuth(clientID, "").
+		Expect().
+		Status(http.StatusUnauthorized).
+		JSON().Object().ValueEqual("error", "invalid_client")
+}
+
+func TestAuthorizeErrorRedirect(t *testing.T) {
+	tsrv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := srv.HandleAuthorizeRequest(w, r); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}))
+	defer tsrv.Close()
+	e := newRedirectExpect(t, tsrv.URL)
+
+	manager.MapClientStorage(clientStore("https://client.example.com", false))
+	srv = server.NewDefaultServer(manager)
+	srv.SetUserAuthorizationHandler(func(w http.ResponseWriter, r *http.Request) (string, error) {
+		return "000000", nil
+	})
+	srv.SetScopeRegistry(server.NewScopeRegistry(&server.ScopeDefinition{Name: "read", Default: true}))
+
+	// the errors are never sent to the redirect URIs the client hasn't registered
+	authorize := func(query map[string]string) *httpexpect.Response {
+		req := e.GET("/authorize").
+			WithQuery("response_type", "code").
+			WithQuery("client_id", clientID).
+			WithQuery("redirect_uri", "https://evil.example/cb").
+			WithQuery("state", "123")
+		for k, v := range query {
+			req = req.WithQuery(k, v)
+		}
+		res := req.Expect().Status(http.StatusBadRequest)
+		res.Header("Location").Empty()
+		return res
+	}
+	authorize(map[string]string{"scope": "unknown"})
+	authorize(map[string]string{"authorization_details": `[{"type":"unknown"}]`})
+	authorize(map[string]string{"scope": "read"})
+}
@@ -9,6 +9,14 @@ type (
 		GetByID(ctx context.Context, id string) (ClientInfo, error)
 	}
 
+	// ClientUpdateStore the client information storage interface that persists the client changes
+	ClientUpdateStore interface {
+		ClientStore
+
+		// update the stored client information
+		Update(ctx context.Context, info ClientInfo) error
+	}
+
 	// TokenStore the token information storage interface
 	TokenStore interface {
 		// create and store the new token information
@@ -32,4 +40,22 @@ type (
 		// use the refresh token for token information data
 		GetByRefresh(ctx context.Context, refresh string) (TokenInfo, error)
 	}
+
+	// TokenRevocationStore the token information storage interface that deletes the tokens of a user
+	TokenRevocationStore interface {
+		// delete all the token information issued to the client for the user
+		RemoveByClientUser(ctx context.Context, clientID, userID string) error
+	}
+
+	// ConsentStore the user consent storage interface
+	ConsentStore interface {
+		// store the user consent, replacing the consent of the user for the client
+		Save(ctx context.Context, info ConsentInfo) error
+
+		// get the user consent for the client
+		Get(ctx context.Context, userID, clientID string) (ConsentInfo, error)
+
+		// delete the user consent for the client
+		Remove(ctx context.Context, userID, clientID string) error
+	}
 )
@@ -0,0 +1,323 @@
+package store
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-oauth2/oauth2/v4"
+)
+
+// CacheConfig cache configuration parameters
+type CacheConfig struct {
+	// the maximum number of cached entries, 0 means unlimited
+	Size int
+	// the lifetime of a cached lookup
+	TTL time.Duration
+	// the lifetime of a cached miss, 0 disables the negative caching
+	NegativeTTL time.Duration
+}
+
+// DefaultCacheConfig the default cache configuration
+var DefaultCacheConfig = &CacheConfig{Size: 10000, TTL: time.Minute, NegativeTTL: time.Second * 10}
+
+// CacheStats the cache hit and miss counters
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+type cacheEntry struct {
+	key      string
+	value    interface{}
+	expireAt time.Time
+}
+
+// lruCache a size bounded LRU cache with per entry expiration
+type lruCache struct {
+	sync.Mutex
+	size   int
+	ll     *list.List
+	items  map[string]*list.Element
+	hits   uint64
+	misses uint64
+}
+
+func newLRUCache(size int) *lruCache {
+	return &lruCache{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+func (c *lruCache) get(key string) (interface{}, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		entry := e.Value.(*cacheEntry)
+		if time.Now().Before(entry.expireAt) {
+			c.ll.MoveToFront(e)
+			atomic.AddUint64(&c.hits, 1)
+			return entry.value, true
+		}
+		c.removeElement(e)
+	}
+	atomic.AddUint64(&c.misses, 1)
+	return nil, false
+}
+
+func (c *lruCache) set(key string, value interface{}, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+
+	expireAt := time.Now().Add(ttl)
+	if e, ok := c.items[key]; ok {
+		c.ll.MoveToFront(e)
+		entry := e.Value.(*cacheEntry)
+		entry.value = value
+		entry.expireAt = expireAt
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&cacheEntry{key: key, value: value, expireAt: expireAt})
+	if c.size > 0 && c.ll.Len() > c.size {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+func (c *lruCache) remove(key string) {
+	c.Lock()
+	defer c.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		c.removeElement(e)
+	}
+}
+
+// removeFunc remove the entries whose value matches
+func (c *lruCache) removeFunc(match func(value interface{}) bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	for e := c.ll.Front(); e != nil; {
+		next := e.Next()
+		if match(e.Value.(*cacheEntry).value) {
+			c.removeElement(e)
+		}
+		e = next
+	}
+}
+
+func (c *lruCache) purge() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+}
+
+func (c *lruCache) removeElement(e *list.Element) {
+	c.ll.Remove(e)
+	delete(c.items, e.Value.(*cacheEntry).key)
+}
+
+func (c *lruCache) stats() CacheStats {
+	return CacheStats{
+		Hits:   atomic.LoadUint64(&c.hits),
+		Misses: atomic.LoadUint64(&c.misses),
+	}
+}
+
+// NewCachedTokenStore create a token store wrapper that caches the access and refresh token lookups
+func NewCachedTokenStore(store oauth2.TokenStore, cfg *CacheConfig) *CachedTokenStore {
+	if cfg == nil {
+		cfg = DefaultCacheConfig
+	}
+	return &CachedTokenStore{
+		store: store,
+		cfg:   cfg,
+		cache: newLRUCache(cfg.Size),
+	}
+}
+
+// CachedTokenStore token storage wrapper with a LRU cache of the token lookups.
+//
+// The authorization codes are never cached. Removals only invalidate the local cache,
+// so with several servers the TTL bounds how long a removed token can still be loaded.
+type CachedTokenStore struct {
+	store oauth2.TokenStore
+	cfg   *CacheConfig
+	cache *lruCache
+}
+
+// Stats returns the cache hit and miss counters
+func (ts *CachedTokenStore) Stats() CacheStats {
+	return ts.cache.stats()
+}
+
+// Purge remove all the cached entries
+func (ts *CachedTokenStore) Purge() {
+	ts.cache.purge()
+}
+
+func (ts *CachedTokenStore) get(key string, load func() (oauth2.TokenInfo, error)) (oauth2.TokenInfo, error) {
+	if v, ok := ts.cache.get(key); ok {
+		if v == nil {
+			return nil, nil
+		}
+		// the manager modifies the loaded token information
+		return cloneToken(v.(oauth2.TokenInfo)), nil
+	}
+
+	ti, err := load()
+	if err != nil {
+		return nil, err
+	} else if ti == nil {
+		ts.cache.set(key, nil, ts.cfg.NegativeTTL)
+		return nil, nil
+	}
+	ts.cache.set(key, cloneToken(ti), ts.cfg.TTL)
+	return ti, nil
+}
+
+// Create create and store the new token information
+func (ts *CachedTokenStore) Create(ctx context.Context, info oauth2.TokenInfo) error {
+	if err := ts.store.Create(ctx, info); err != nil {
+		return err
+	}
+	// drop the cached misses
+	if access := info.GetAccess(); access != "" {
+		ts.cache.remove("access:" + access)
+	}
+	if refresh := info.GetRefresh(); refresh != "" {
+		ts.cache.remove("refresh:" + refresh)
+	}
+	return nil
+}
+
+// RemoveByCode use the authorization code to delete the token information
+func (ts *CachedTokenStore) RemoveByCode(ctx context.Context, code string) error {
+	return ts.store.RemoveByCode(ctx, code)
+}
+
+// RemoveByAccess use the access token to delete the token information
+func (ts *CachedTokenStore) RemoveByAccess(ctx context.Context, access string) error {
+	ts.cache.remove("access:" + access)
+	return ts.store.RemoveByAccess(ctx, access)
+}
+
+// RemoveByRefresh use the refresh token to delete the token information
+func (ts *CachedTokenStore) RemoveByRefresh(ctx context.Context, refresh string) error {
+	ts.cache.remove("refresh:" + refresh)
+	return ts.store.RemoveByRefresh(ctx, refresh)
+}
+
+// RemoveByClientUser delete all the token information issued to the client for the user
+func (ts *CachedTokenStore) RemoveByClientUser(ctx context.Context, clientID, userID string) error {
+	rs, ok := ts.store.(oauth2.TokenRevocationStore)
+	if !ok {
+		return errors.New("the token store doesn't support removing by client and user")
+	}
+	defer ts.cache.removeFunc(func(v interface{}) bool {
+		ti, ok := v.(oauth2.TokenInfo)
+		return ok && ti.GetClientID() == clientID && ti.GetUserID() == userID
+	})
+	return rs.RemoveByClientUser(ctx, clientID, userID)
+}
+
+// GetByCode use the authorization code for token information data
+func (ts *CachedTokenStore) GetByCode(ctx context.Context, code string) (oauth2.TokenInfo, error) {
+	return ts.store.GetByCode(ctx, code)
+}
+
+// GetByAccess use the access token for token information data
+func (ts *CachedTokenStore) GetByAccess(ctx context.Context, access string) (oauth2.TokenInfo, error) {
+	return ts.get("access:"+access, func() (oauth2.TokenInfo, error) {
+		return ts.store.GetByAccess(ctx, access)
+	})
+}
+
+// GetByRefresh use the refresh token for token information data
+func (ts *CachedTokenStore) GetByRefresh(ctx context.Context, refresh string) (oauth2.TokenInfo, error) {
+	return ts.get("refresh:"+refresh, func() (oauth2.TokenInfo, error) {
+		return ts.store.GetByRefresh(ctx, refresh)
+	})
+}
+
+// NewCachedClientStore create a client store wrapper that caches the client lookups
+func NewCachedClientStore(store oauth2.ClientStore, cfg *CacheConfig) *CachedClientStore {
+	if cfg == nil {
+		cfg = DefaultCacheConfig
+	}
+	return &CachedClientStore{
+		store: store,
+		cfg:   cfg,
+		cache: newLRUCache(cfg.Size),
+	}
+}
+
+// CachedClientStore client storage wrapper with a LRU cache of the client lookups,
+// the lookups return copies of the cached models.Client so that the callers can't modify it
+type CachedClientStore struct {
+	store oauth2.ClientStore
+	cfg   *CacheConfig
+	cache *lruCache
+}
+
+// Stats returns the cache hit and miss counters
+func (cs *CachedClientStore) Stats() CacheStats {
+	return cs.cache.stats()
+}
+
+// Invalidate remove the cached client information, call it after the client is updated or removed
+func (cs *CachedClientStore) Invalidate(id string) {
+	cs.cache.remove(id)
+}
+
+// Purge remove all the cached entries
+func (cs *CachedClientStore) Purge() {
+	cs.cache.purge()
+}
+
+// Update update the client information when the underlying store supports it
+func (cs *CachedClientStore) Update(ctx context.Context, info oauth2.ClientInfo) error {
+	us, ok := cs.store.(oauth2.ClientUpdateStore)
+	if !ok {
+		return errors.New("the client store doesn't support updates")
+	}
+	defer cs.cache.remove(info.GetID())
+	return us.Update(ctx, info)
+}
+
+// GetByID according to the ID for the client information
+func (cs *CachedClientStore) GetByID(ctx context.Context, id string) (oauth2.ClientInfo, error) {
+	if v, ok := cs.cache.get(id); ok {
+		if miss, ok := v.(clientMiss); ok {
+			return nil, miss.err
+		}
+		return cloneClient(v.(oauth2.ClientInfo)), nil
+	}
+
+	cli, err := cs.store.GetByID(ctx, id)
+	if (err == nil && cli == nil) || errors.Is(err, ErrNotFound) {
+		cs.cache.set(id, clientMiss{err: err}, cs.cfg.NegativeTTL)
+		return nil, err
+	} else if err != nil {
+		return nil, err
+	}
+	cs.cache.set(id, cloneClient(cli), cs.cfg.TTL)
+	return cli, nil
+}
+
+// clientMiss a cached client lookup that found nothing
+type clientMiss struct {
+	err error
+}
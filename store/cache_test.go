@@ -0,0 +1,180 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/models"
+	"github.com/go-oauth2/oauth2/v4/store"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type countingTokenStore struct {
+	oauth2.TokenStore
+	lookups int
+}
+
+func (ts *countingTokenStore) GetByAccess(ctx context.Context, access string) (oauth2.TokenInfo, error) {
+	ts.lookups++
+	return ts.TokenStore.GetByAccess(ctx, access)
+}
+
+func (ts *countingTokenStore) RemoveByClientUser(ctx context.Context, clientID, userID string) error {
+	return ts.TokenStore.(oauth2.TokenRevocationStore).RemoveByClientUser(ctx, clientID, userID)
+}
+
+type countingClientStore struct {
+	oauth2.ClientStore
+	lookups int
+}
+
+func (cs *countingClientStore) GetByID(ctx context.Context, id string) (oauth2.ClientInfo, error) {
+	cs.lookups++
+	return cs.ClientStore.GetByID(ctx, id)
+}
+
+func TestCachedTokenStore(t *testing.T) {
+	Convey("Test cached token store", t, func() {
+		mstore, err := store.NewMemoryTokenStore()
+		So(err, ShouldBeNil)
+		cstore := store.NewCachedTokenStore(mstore, nil)
+
+		testToken(cstore)
+
+		Convey("Test remove the tokens by client and user", func() {
+			mstore, err := store.NewMemoryTokenStore()
+			So(err, ShouldBeNil)
+			testRemoveByClientUser(context.Background(), store.NewCachedTokenStore(mstore, nil))
+		})
+
+		Convey("Test cached lookups", func() {
+			ctx := context.Background()
+			inner := &countingTokenStore{TokenStore: mstore}
+			cstore := store.NewCachedTokenStore(inner, &store.CacheConfig{Size: 2, TTL: time.Minute, NegativeTTL: time.Minute})
+			info := &models.Token{
+				ClientID:        "1",
+				UserID:          "1_5",
+				Access:          "1_5_1",
+				AccessCreateAt:  time.Now(),
+				AccessExpiresIn: time.Second * 5,
+			}
+
+			// negative caching
+			ainfo, err := cstore.GetByAccess(ctx, info.Access)
+			So(err, ShouldBeNil)
+			So(ainfo, ShouldBeNil)
+			ainfo, err = cstore.GetByAccess(ctx, info.Access)
+			So(err, ShouldBeNil)
+			So(ainfo, ShouldBeNil)
+			So(inner.lookups, ShouldEqual, 1)
+
+			// create invalidates the cached miss
+			err = cstore.Create(ctx, info)
+			So(err, ShouldBeNil)
+			ainfo, err = cstore.GetByAccess(ctx, info.Access)
+			So(err, ShouldBeNil)
+			So(ainfo.GetUserID(), ShouldEqual, info.UserID)
+			So(inner.lookups, ShouldEqual, 2)
+
+			// the cached token information can't be modified by the caller
+			ainfo.SetUserID("changed")
+			ainfo, err = cstore.GetByAccess(ctx, info.Access)
+			So(err, ShouldBeNil)
+			So(ainfo.GetUserID(), ShouldEqual, info.UserID)
+			So(inner.lookups, ShouldEqual, 2)
+			So(cstore.Stats(), ShouldResemble, store.CacheStats{Hits: 2, Misses: 2})
+
+			// remove invalidates the cached token
+			err = cstore.RemoveByAccess(ctx, info.Access)
+			So(err, ShouldBeNil)
+			ainfo, err = cstore.GetByAccess(ctx, info.Access)
+			So(err, ShouldBeNil)
+			So(ainfo, ShouldBeNil)
+			So(inner.lookups, ShouldEqual, 3)
+
+			// the least recently used entry is evicted
+			cstore.GetByAccess(ctx, "a")
+			cstore.GetByAccess(ctx, "b")
+			cstore.GetByAccess(ctx, "c")
+			So(inner.lookups, ShouldEqual, 6)
+			cstore.GetByAccess(ctx, "c")
+			So(inner.lookups, ShouldEqual, 6)
+			cstore.GetByAccess(ctx, "a")
+			So(inner.lookups, ShouldEqual, 7)
+		})
+
+		Convey("Test remove by client and user invalidates only their tokens", func() {
+			ctx := context.Background()
+			inner := &countingTokenStore{TokenStore: mstore}
+			cstore := store.NewCachedTokenStore(inner, &store.CacheConfig{TTL: time.Minute})
+			newToken := func(userID string) *models.Token {
+				return &models.Token{
+					ClientID:        "1",
+					UserID:          userID,
+					Access:          "access_" + userID,
+					AccessCreateAt:  time.Now(),
+					AccessExpiresIn: time.Minute,
+				}
+			}
+			revoked, kept := newToken("u1"), newToken("u2")
+			So(cstore.Create(ctx, revoked), ShouldBeNil)
+			So(cstore.Create(ctx, kept), ShouldBeNil)
+			_, _ = cstore.GetByAccess(ctx, revoked.Access)
+			_, _ = cstore.GetByAccess(ctx, kept.Access)
+			So(inner.lookups, ShouldEqual, 2)
+
+			err := cstore.RemoveByClientUser(ctx, "1", "u1")
+			So(err, ShouldBeNil)
+
+			ainfo, err := cstore.GetByAccess(ctx, revoked.Access)
+			So(err, ShouldBeNil)
+			So(ainfo, ShouldBeNil)
+			So(inner.lookups, ShouldEqual, 3)
+			ainfo, err = cstore.GetByAccess(ctx, kept.Access)
+			So(err, ShouldBeNil)
+			So(ainfo.GetUserID(), ShouldEqual, "u2")
+			So(inner.lookups, ShouldEqual, 3)
+		})
+	})
+}
+
+func TestCachedClientStore(t *testing.T) {
+	Convey("Test cached client store", t, func() {
+		ctx := context.Background()
+		clientStore := store.NewClientStore()
+		clientStore.Set("1", &models.Client{ID: "1", Secret: "2"})
+		inner := &countingClientStore{ClientStore: clientStore}
+		cstore := store.NewCachedClientStore(inner, &store.CacheConfig{TTL: time.Minute, NegativeTTL: time.Minute})
+
+		cli, err := cstore.GetByID(ctx, "1")
+		So(err, ShouldBeNil)
+		So(cli.GetID(), ShouldEqual, "1")
+		cli, err = cstore.GetByID(ctx, "1")
+		So(err, ShouldBeNil)
+		So(cli.GetID(), ShouldEqual, "1")
+		So(inner.lookups, ShouldEqual, 1)
+
+		// the cached client can't be modified by the callers
+		cli.(*models.Client).Secret = "3"
+		cli, err = cstore.GetByID(ctx, "1")
+		So(err, ShouldBeNil)
+		So(cli.GetSecret(), ShouldEqual, "2")
+
+		_, err = cstore.GetByID(ctx, "2")
+		So(err, ShouldEqual, store.ErrNotFound)
+		_, err = cstore.GetByID(ctx, "2")
+		So(err, ShouldEqual, store.ErrNotFound)
+		So(inner.lookups, ShouldEqual, 2)
+		So(cstore.Stats(), ShouldResemble, store.CacheStats{Hits: 3, Misses: 2})
+
+		clientStore.Set("2", &models.Client{ID: "2", Secret: "2"})
+		cstore.Invalidate("2")
+		cli, err = cstore.GetByID(ctx, "2")
+		So(err, ShouldBeNil)
+		So(cli.GetID(), ShouldEqual, "2")
+		So(inner.lookups, ShouldEqual, 3)
+	})
+}
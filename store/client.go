@@ -8,6 +8,9 @@ import (
 	"github.com/go-oauth2/oauth2/v4"
 )
 
+// ErrNotFound the client was not found
+var ErrNotFound = errors.New("not found")
+
 // NewClientStore create client store
 func NewClientStore() *ClientStore {
 	return &ClientStore{
@@ -29,7 +32,7 @@ func (cs *ClientStore) GetByID(ctx context.Context, id string) (oauth2.ClientInf
 	if c, ok := cs.data[id]; ok {
 		return c, nil
 	}
-	return nil, errors.New("not found")
+	return nil, ErrNotFound
 }
 
 // Set set client information
@@ -40,3 +43,8 @@ func (cs *ClientStore) Set(id string, cli oauth2.ClientInfo) (err error) {
 	cs.data[id] = cli
 	return
 }
+
+// Update update the client information
+func (cs *ClientStore) Update(ctx context.Context, cli oauth2.ClientInfo) error {
+	return cs.Set(cli.GetID(), cli)
+}
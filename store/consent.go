@@ -0,0 +1,51 @@
+package store
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-oauth2/oauth2/v4"
+)
+
+// NewConsentStore create user consent store
+func NewConsentStore() *ConsentStore {
+	return &ConsentStore{
+		data: make(map[string]oauth2.ConsentInfo),
+	}
+}
+
+// ConsentStore user consent information store
+type ConsentStore struct {
+	sync.RWMutex
+	data map[string]oauth2.ConsentInfo
+}
+
+func consentKey(userID, clientID string) string {
+	return userID + "\x00" + clientID
+}
+
+// Save store the user consent, replacing the consent of the user for the client
+func (cs *ConsentStore) Save(ctx context.Context, info oauth2.ConsentInfo) error {
+	cs.Lock()
+	defer cs.Unlock()
+
+	cs.data[consentKey(info.GetUserID(), info.GetClientID())] = info
+	return nil
+}
+
+// Get get the user consent for the client
+func (cs *ConsentStore) Get(ctx context.Context, userID, clientID string) (oauth2.ConsentInfo, error) {
+	cs.RLock()
+	defer cs.RUnlock()
+
+	return cs.data[consentKey(userID, clientID)], nil
+}
+
+// Remove delete the user consent for the client
+func (cs *ConsentStore) Remove(ctx context.Context, userID, clientID string) error {
+	cs.Lock()
+	defer cs.Unlock()
+
+	delete(cs.data, consentKey(userID, clientID))
+	return nil
+}
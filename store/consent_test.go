@@ -0,0 +1,42 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-oauth2/oauth2/v4/models"
+	"github.com/go-oauth2/oauth2/v4/store"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestConsentStore(t *testing.T) {
+	Convey("Test consent store", t, func() {
+		ctx := context.Background()
+		cs := store.NewConsentStore()
+
+		err := cs.Save(ctx, &models.Consent{UserID: "u1", ClientID: "1", Scope: "read", CreateAt: time.Now()})
+		So(err, ShouldBeNil)
+
+		ci, err := cs.Get(ctx, "u1", "1")
+		So(err, ShouldBeNil)
+		So(ci.GetScope().String(), ShouldEqual, "read")
+
+		ci, err = cs.Get(ctx, "u1", "2")
+		So(err, ShouldBeNil)
+		So(ci, ShouldBeNil)
+
+		err = cs.Save(ctx, &models.Consent{UserID: "u1", ClientID: "1", Scope: "read write", CreateAt: time.Now()})
+		So(err, ShouldBeNil)
+		ci, err = cs.Get(ctx, "u1", "1")
+		So(err, ShouldBeNil)
+		So(ci.GetScope().String(), ShouldEqual, "read write")
+
+		err = cs.Remove(ctx, "u1", "1")
+		So(err, ShouldBeNil)
+		ci, err = cs.Get(ctx, "u1", "1")
+		So(err, ShouldBeNil)
+		So(ci, ShouldBeNil)
+	})
+}
@@ -0,0 +1,268 @@
+package store
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/models"
+)
+
+// the extension fields of the stored token holding the encrypted payload
+const (
+	encryptedKeyIDField   = "enc_kid"
+	encryptedDataField    = "enc_data"
+	encryptedBindingField = "enc_bind"
+)
+
+// the token values bound to the encrypted payload
+const (
+	bindCode = iota
+	bindAccess
+	bindRefresh
+)
+
+// NewEncryptedTokenStore create a token store wrapper that encrypts the token information with AES-GCM,
+// keys maps the key ID to the AES key (16, 24 or 32 bytes) and kid is the key used for the new tokens
+func NewEncryptedTokenStore(store oauth2.TokenStore, kid string, keys map[string][]byte) (*EncryptedTokenStore, error) {
+	if _, ok := keys[kid]; !ok {
+		return nil, fmt.Errorf("unknown encryption key id %q", kid)
+	}
+
+	aeads := make(map[string]cipher.AEAD, len(keys))
+	indexKeys := make(map[string][]byte, len(keys))
+	for id, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		aeads[id] = aead
+
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte("index"))
+		indexKeys[id] = h.Sum(nil)
+	}
+
+	return &EncryptedTokenStore{
+		store:     store,
+		kid:       kid,
+		aeads:     aeads,
+		indexKeys: indexKeys,
+	}, nil
+}
+
+// EncryptedTokenStore token storage wrapper that encrypts the token information at rest.
+//
+// The underlying store only sees the token values, their lifetimes and keyed digests of the
+// client and user ID (so that the tokens can be removed by client and user), everything else
+// (client and user ID, scope, redirect URI, code challenge and extension fields) is sealed
+// into the extension of the stored token. The payload is bound to the token values, it can't be
+// moved to another stored token. Keep the old keys in the key set after a rotation
+// until the tokens encrypted with them have expired.
+//
+// The tokens stored without encryption are rejected, see SetAllowPlaintext.
+type EncryptedTokenStore struct {
+	store          oauth2.TokenStore
+	kid            string
+	aeads          map[string]cipher.AEAD
+	indexKeys      map[string][]byte
+	allowPlaintext bool
+}
+
+// SetAllowPlaintext accept the tokens stored before the encryption was enabled until they expire,
+// off by default: anyone able to write to the underlying store could plant a forged token
+func (ts *EncryptedTokenStore) SetAllowPlaintext(allow bool) {
+	ts.allowPlaintext = allow
+}
+
+// index the keyed digest of the client or user ID stored in plain
+func (ts *EncryptedTokenStore) index(kid, id string) string {
+	if id == "" {
+		return ""
+	}
+	h := hmac.New(sha256.New, ts.indexKeys[kid])
+	h.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+func bindingDigest(value string) string {
+	if value == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// binding the digests of the code, access and refresh token the payload is bound to
+func binding(code, access, refresh string) string {
+	return strings.Join([]string{bindingDigest(code), bindingDigest(access), bindingDigest(refresh)}, ".")
+}
+
+// additionalData the AEAD additional data of the payload
+func additionalData(kid, binding string) []byte {
+	return []byte(kid + ":" + binding)
+}
+
+func (ts *EncryptedTokenStore) encrypt(info oauth2.TokenInfo, binding string) (string, error) {
+	// the token values are kept by the envelope only
+	ti := cloneToken(info)
+	ti.SetCode("")
+	ti.SetAccess("")
+	ti.SetRefresh("")
+	jv, err := json.Marshal(ti)
+	if err != nil {
+		return "", err
+	}
+
+	aead := ts.aeads[ts.kid]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, jv, additionalData(ts.kid, binding))), nil
+}
+
+func (ts *EncryptedTokenStore) decrypt(kid, data, binding string) (*models.Token, error) {
+	aead, ok := ts.aeads[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown encryption key id %q", kid)
+	}
+
+	buf, err := base64.RawURLEncoding.DecodeString(data)
+	if err != nil {
+		return nil, err
+	} else if len(buf) < aead.NonceSize() {
+		return nil, errors.New("invalid encrypted token data")
+	}
+
+	jv, err := aead.Open(nil, buf[:aead.NonceSize()], buf[aead.NonceSize():], additionalData(kid, binding))
+	if err != nil {
+		return nil, err
+	}
+
+	var tm models.Token
+	if err := json.Unmarshal(jv, &tm); err != nil {
+		return nil, err
+	}
+	return &tm, nil
+}
+
+// Create create and store the new token information
+func (ts *EncryptedTokenStore) Create(ctx context.Context, info oauth2.TokenInfo) error {
+	bind := binding(info.GetCode(), info.GetAccess(), info.GetRefresh())
+	data, err := ts.encrypt(info, bind)
+	if err != nil {
+		return err
+	}
+
+	envelope := models.NewToken()
+	envelope.SetClientID(ts.index(ts.kid, info.GetClientID()))
+	envelope.SetUserID(ts.index(ts.kid, info.GetUserID()))
+	envelope.SetCode(info.GetCode())
+	envelope.SetCodeCreateAt(info.GetCodeCreateAt())
+	envelope.SetCodeExpiresIn(info.GetCodeExpiresIn())
+	envelope.SetAccess(info.GetAccess())
+	envelope.SetAccessCreateAt(info.GetAccessCreateAt())
+	envelope.SetAccessExpiresIn(info.GetAccessExpiresIn())
+	envelope.SetRefresh(info.GetRefresh())
+	envelope.SetRefreshCreateAt(info.GetRefreshCreateAt())
+	envelope.SetRefreshExpiresIn(info.GetRefreshExpiresIn())
+	envelope.Extension.Set(encryptedKeyIDField, ts.kid)
+	envelope.Extension.Set(encryptedDataField, data)
+	envelope.Extension.Set(encryptedBindingField, bind)
+
+	return ts.store.Create(ctx, envelope)
+}
+
+// open decrypt the token information loaded from the underlying store by the token value,
+// the payload must be bound to the value
+func (ts *EncryptedTokenStore) open(envelope oauth2.TokenInfo, err error, bound int, value string) (oauth2.TokenInfo, error) {
+	if err != nil || envelope == nil {
+		return nil, err
+	}
+
+	eti, ok := envelope.(oauth2.ExtendableTokenInfo)
+	if !ok || eti.GetExtension().Get(encryptedDataField) == "" {
+		// stored before the encryption was enabled
+		if ts.allowPlaintext {
+			return envelope, nil
+		}
+		return nil, errors.New("the token data isn't encrypted")
+	}
+
+	bind := eti.GetExtension().Get(encryptedBindingField)
+	if digests := strings.Split(bind, "."); len(digests) != 3 ||
+		digests[bound] != bindingDigest(value) {
+		return nil, errors.New("the encrypted token data isn't bound to the token")
+	}
+
+	ti, err := ts.decrypt(eti.GetExtension().Get(encryptedKeyIDField), eti.GetExtension().Get(encryptedDataField), bind)
+	if err != nil {
+		return nil, err
+	}
+	ti.SetCode(envelope.GetCode())
+	ti.SetAccess(envelope.GetAccess())
+	ti.SetRefresh(envelope.GetRefresh())
+	return ti, nil
+}
+
+// RemoveByCode use the authorization code to delete the token information
+func (ts *EncryptedTokenStore) RemoveByCode(ctx context.Context, code string) error {
+	return ts.store.RemoveByCode(ctx, code)
+}
+
+// RemoveByAccess use the access token to delete the token information
+func (ts *EncryptedTokenStore) RemoveByAccess(ctx context.Context, access string) error {
+	return ts.store.RemoveByAccess(ctx, access)
+}
+
+// RemoveByRefresh use the refresh token to delete the token information
+func (ts *EncryptedTokenStore) RemoveByRefresh(ctx context.Context, refresh string) error {
+	return ts.store.RemoveByRefresh(ctx, refresh)
+}
+
+// RemoveByClientUser delete all the token information issued to the client for the user,
+// the tokens encrypted with any key of the key set are removed
+func (ts *EncryptedTokenStore) RemoveByClientUser(ctx context.Context, clientID, userID string) error {
+	rs, ok := ts.store.(oauth2.TokenRevocationStore)
+	if !ok {
+		return errors.New("the token store doesn't support removing by client and user")
+	}
+	for kid := range ts.indexKeys {
+		if err := rs.RemoveByClientUser(ctx, ts.index(kid, clientID), ts.index(kid, userID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetByCode use the authorization code for token information data
+func (ts *EncryptedTokenStore) GetByCode(ctx context.Context, code string) (oauth2.TokenInfo, error) {
+	ti, err := ts.store.GetByCode(ctx, code)
+	return ts.open(ti, err, bindCode, code)
+}
+
+// GetByAccess use the access token for token information data
+func (ts *EncryptedTokenStore) GetByAccess(ctx context.Context, access string) (oauth2.TokenInfo, error) {
+	ti, err := ts.store.GetByAccess(ctx, access)
+	return ts.open(ti, err, bindAccess, access)
+}
+
+// GetByRefresh use the refresh token for token information data
+func (ts *EncryptedTokenStore) GetByRefresh(ctx context.Context, refresh string) (oauth2.TokenInfo, error) {
+	ti, err := ts.store.GetByRefresh(ctx, refresh)
+	return ts.open(ti, err, bindRefresh, refresh)
+}
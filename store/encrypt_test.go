@@ -0,0 +1,148 @@
+package store_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/models"
+	"github.com/go-oauth2/oauth2/v4/store"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEncryptedTokenStore(t *testing.T) {
+	Convey("Test encrypted token store", t, func() {
+		key1 := []byte("0123456789abcdef0123456789abcdef")
+		key2 := []byte("fedcba9876543210fedcba9876543210")
+
+		mstore, err := store.NewMemoryTokenStore()
+		So(err, ShouldBeNil)
+		estore, err := store.NewEncryptedTokenStore(mstore, "1", map[string][]byte{"1": key1})
+		So(err, ShouldBeNil)
+
+		testToken(estore)
+
+		Convey("Test remove the tokens by client and user", func() {
+			mstore, err := store.NewMemoryTokenStore()
+			So(err, ShouldBeNil)
+			estore, err := store.NewEncryptedTokenStore(mstore, "1", map[string][]byte{"1": key1})
+			So(err, ShouldBeNil)
+			testRemoveByClientUser(context.Background(), estore)
+		})
+
+		Convey("Test invalid key", func() {
+			_, err := store.NewEncryptedTokenStore(mstore, "2", map[string][]byte{"1": key1})
+			So(err, ShouldNotBeNil)
+			_, err = store.NewEncryptedTokenStore(mstore, "1", map[string][]byte{"1": []byte("short")})
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Test plaintext token", func() {
+			ctx := context.Background()
+			info := &models.Token{
+				ClientID:        "1",
+				UserID:          "1_6",
+				Scope:           "admin",
+				Access:          "1_6_1",
+				AccessCreateAt:  time.Now(),
+				AccessExpiresIn: time.Second * 5,
+			}
+			So(mstore.Create(ctx, info), ShouldBeNil)
+
+			_, err := estore.GetByAccess(ctx, info.Access)
+			So(err, ShouldNotBeNil)
+
+			estore.SetAllowPlaintext(true)
+			ainfo, err := estore.GetByAccess(ctx, info.Access)
+			So(err, ShouldBeNil)
+			So(ainfo.GetScope().String(), ShouldEqual, "admin")
+		})
+
+		Convey("Test payload is encrypted", func() {
+			ctx := context.Background()
+			info := &models.Token{
+				ClientID:        "1",
+				UserID:          "1_5",
+				Scope:           "all",
+				Access:          "1_5_1",
+				AccessCreateAt:  time.Now(),
+				AccessExpiresIn: time.Second * 5,
+				Extension:       url.Values{"email": {"user@example.com"}},
+			}
+			err := estore.Create(ctx, info)
+			So(err, ShouldBeNil)
+
+			raw, err := mstore.GetByAccess(ctx, info.Access)
+			So(err, ShouldBeNil)
+			So(raw.GetClientID(), ShouldNotBeEmpty)
+			So(raw.GetClientID(), ShouldNotEqual, info.ClientID)
+			So(raw.GetUserID(), ShouldNotBeEmpty)
+			So(raw.GetUserID(), ShouldNotEqual, info.UserID)
+			So(raw.GetScope(), ShouldBeEmpty)
+			ext := raw.(oauth2.ExtendableTokenInfo).GetExtension()
+			So(ext.Get("email"), ShouldBeEmpty)
+			So(ext.Get("enc_kid"), ShouldEqual, "1")
+
+			ainfo, err := estore.GetByAccess(ctx, info.Access)
+			So(err, ShouldBeNil)
+			So(ainfo.GetAccess(), ShouldEqual, info.Access)
+			So(ainfo.GetClientID(), ShouldEqual, info.ClientID)
+			So(ainfo.GetUserID(), ShouldEqual, info.UserID)
+			So(ainfo.GetScope().String(), ShouldEqual, info.Scope)
+			So(ainfo.(oauth2.ExtendableTokenInfo).GetExtension().Get("email"), ShouldEqual, "user@example.com")
+
+			Convey("Test key rotation", func() {
+				rotated, err := store.NewEncryptedTokenStore(mstore, "2", map[string][]byte{"1": key1, "2": key2})
+				So(err, ShouldBeNil)
+
+				ainfo, err := rotated.GetByAccess(ctx, info.Access)
+				So(err, ShouldBeNil)
+				So(ainfo.GetUserID(), ShouldEqual, info.UserID)
+
+				retired, err := store.NewEncryptedTokenStore(mstore, "2", map[string][]byte{"2": key2})
+				So(err, ShouldBeNil)
+				_, err = retired.GetByAccess(ctx, info.Access)
+				So(err, ShouldNotBeNil)
+			})
+
+			Convey("Test payload moved to another token", func() {
+				other := &models.Token{
+					ClientID:        "2",
+					UserID:          "2_5",
+					Access:          "2_5_1",
+					AccessCreateAt:  time.Now(),
+					AccessExpiresIn: time.Second * 5,
+				}
+				err := estore.Create(ctx, other)
+				So(err, ShouldBeNil)
+
+				// copy the sealed payload of the first token to the second one
+				for _, fields := range [][]string{{"enc_kid", "enc_data"}, {"enc_kid", "enc_data", "enc_bind"}} {
+					raw, err := mstore.GetByAccess(ctx, info.Access)
+					So(err, ShouldBeNil)
+					swapped, err := mstore.GetByAccess(ctx, other.Access)
+					So(err, ShouldBeNil)
+					ext := swapped.(oauth2.ExtendableTokenInfo).GetExtension()
+					for _, k := range fields {
+						ext.Set(k, raw.(oauth2.ExtendableTokenInfo).GetExtension().Get(k))
+					}
+					So(mstore.RemoveByAccess(ctx, other.Access), ShouldBeNil)
+					So(mstore.Create(ctx, swapped), ShouldBeNil)
+
+					_, err = estore.GetByAccess(ctx, other.Access)
+					So(err, ShouldNotBeNil)
+				}
+			})
+
+			Convey("Test wrong key", func() {
+				wrong, err := store.NewEncryptedTokenStore(mstore, "1", map[string][]byte{"1": key2})
+				So(err, ShouldBeNil)
+				_, err = wrong.GetByAccess(ctx, info.Access)
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
@@ -0,0 +1,145 @@
+package store
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/go-oauth2/oauth2/v4"
+)
+
+// hashPrefix marks a token value that has already been hashed
+const hashPrefix = "hs256:"
+
+// NewHashedTokenStore create a token store wrapper that keeps only the
+// HMAC-SHA256 digests of the authorization code, access and refresh tokens
+func NewHashedTokenStore(store oauth2.TokenStore, pepper []byte) *HashedTokenStore {
+	return &HashedTokenStore{
+		store:  store,
+		pepper: pepper,
+	}
+}
+
+// HashedTokenStore token storage wrapper that hashes the token values with a server pepper,
+// so that a leaked database dump can't be replayed.
+//
+// The token information returned by the lookups carries the presented value in plaintext,
+// the other token values are replaced by handles of their digests. The handles are authenticated
+// with the pepper and recognized by the remove methods, so that the manager can still delete
+// the old tokens when refreshing while a digest taken from the database can't revoke anything.
+type HashedTokenStore struct {
+	store  oauth2.TokenStore
+	pepper []byte
+}
+
+// Hash returns the digest stored for the token value
+func (ts *HashedTokenStore) Hash(value string) string {
+	if value == "" {
+		return ""
+	}
+	h := hmac.New(sha256.New, ts.pepper)
+	h.Write([]byte(value))
+	return hashPrefix + base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// handle the value standing for the stored digest in the token information returned by the lookups
+func (ts *HashedTokenStore) handle(digest string) string {
+	h := hmac.New(sha256.New, ts.pepper)
+	h.Write([]byte("handle:" + digest))
+	return digest + "." + base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// storeKey the digest of the value, the handles returned by the lookups are resolved to their digest
+func (ts *HashedTokenStore) storeKey(value string) string {
+	if i := strings.LastIndexByte(value, '.'); i > 0 && strings.HasPrefix(value, hashPrefix) &&
+		hmac.Equal([]byte(value), []byte(ts.handle(value[:i]))) {
+		return value[:i]
+	}
+	return ts.Hash(value)
+}
+
+// reveal restore the presented value of the token information loaded by its digest,
+// the other digests are replaced by their handles
+func (ts *HashedTokenStore) reveal(ti oauth2.TokenInfo, hashed, value string) {
+	conv := func(v string) string {
+		switch v {
+		case "":
+			return ""
+		case hashed:
+			return value
+		}
+		return ts.handle(v)
+	}
+	ti.SetCode(conv(ti.GetCode()))
+	ti.SetAccess(conv(ti.GetAccess()))
+	ti.SetRefresh(conv(ti.GetRefresh()))
+}
+
+// Create create and store the new token information
+func (ts *HashedTokenStore) Create(ctx context.Context, info oauth2.TokenInfo) error {
+	ti := cloneToken(info)
+	ti.SetCode(ts.storeKey(info.GetCode()))
+	ti.SetAccess(ts.storeKey(info.GetAccess()))
+	ti.SetRefresh(ts.storeKey(info.GetRefresh()))
+	return ts.store.Create(ctx, ti)
+}
+
+// RemoveByCode use the authorization code to delete the token information
+func (ts *HashedTokenStore) RemoveByCode(ctx context.Context, code string) error {
+	return ts.store.RemoveByCode(ctx, ts.storeKey(code))
+}
+
+// RemoveByAccess use the access token to delete the token information
+func (ts *HashedTokenStore) RemoveByAccess(ctx context.Context, access string) error {
+	return ts.store.RemoveByAccess(ctx, ts.storeKey(access))
+}
+
+// RemoveByRefresh use the refresh token to delete the token information
+func (ts *HashedTokenStore) RemoveByRefresh(ctx context.Context, refresh string) error {
+	return ts.store.RemoveByRefresh(ctx, ts.storeKey(refresh))
+}
+
+// RemoveByClientUser delete all the token information issued to the client for the user
+func (ts *HashedTokenStore) RemoveByClientUser(ctx context.Context, clientID, userID string) error {
+	rs, ok := ts.store.(oauth2.TokenRevocationStore)
+	if !ok {
+		return errors.New("the token store doesn't support removing by client and user")
+	}
+	return rs.RemoveByClientUser(ctx, clientID, userID)
+}
+
+// GetByCode use the authorization code for token information data
+func (ts *HashedTokenStore) GetByCode(ctx context.Context, code string) (oauth2.TokenInfo, error) {
+	hashed := ts.Hash(code)
+	ti, err := ts.store.GetByCode(ctx, hashed)
+	if err != nil || ti == nil {
+		return nil, err
+	}
+	ts.reveal(ti, hashed, code)
+	return ti, nil
+}
+
+// GetByAccess use the access token for token information data
+func (ts *HashedTokenStore) GetByAccess(ctx context.Context, access string) (oauth2.TokenInfo, error) {
+	hashed := ts.Hash(access)
+	ti, err := ts.store.GetByAccess(ctx, hashed)
+	if err != nil || ti == nil {
+		return nil, err
+	}
+	ts.reveal(ti, hashed, access)
+	return ti, nil
+}
+
+// GetByRefresh use the refresh token for token information data
+func (ts *HashedTokenStore) GetByRefresh(ctx context.Context, refresh string) (oauth2.TokenInfo, error) {
+	hashed := ts.Hash(refresh)
+	ti, err := ts.store.GetByRefresh(ctx, hashed)
+	if err != nil || ti == nil {
+		return nil, err
+	}
+	ts.reveal(ti, hashed, refresh)
+	return ti, nil
+}
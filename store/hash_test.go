@@ -0,0 +1,113 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/manage"
+	"github.com/go-oauth2/oauth2/v4/models"
+	"github.com/go-oauth2/oauth2/v4/store"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHashedTokenStore(t *testing.T) {
+	Convey("Test hashed token store", t, func() {
+		mstore, err := store.NewMemoryTokenStore()
+		So(err, ShouldBeNil)
+		hstore := store.NewHashedTokenStore(mstore, []byte("pepper"))
+
+		testToken(hstore)
+
+		Convey("Test remove the tokens by client and user", func() {
+			mstore, err := store.NewMemoryTokenStore()
+			So(err, ShouldBeNil)
+			testRemoveByClientUser(context.Background(), store.NewHashedTokenStore(mstore, []byte("pepper")))
+		})
+
+		Convey("Test plaintext is not stored", func() {
+			ctx := context.Background()
+			info := &models.Token{
+				ClientID:         "1",
+				UserID:           "1_5",
+				Access:           "1_5_1",
+				AccessCreateAt:   time.Now(),
+				AccessExpiresIn:  time.Second * 5,
+				Refresh:          "1_5_2",
+				RefreshCreateAt:  time.Now(),
+				RefreshExpiresIn: time.Second * 15,
+			}
+			err := hstore.Create(ctx, info)
+			So(err, ShouldBeNil)
+			So(info.Access, ShouldEqual, "1_5_1")
+
+			ainfo, err := mstore.GetByAccess(ctx, info.Access)
+			So(err, ShouldBeNil)
+			So(ainfo, ShouldBeNil)
+
+			ainfo, err = mstore.GetByAccess(ctx, hstore.Hash(info.Access))
+			So(err, ShouldBeNil)
+			So(ainfo.GetAccess(), ShouldEqual, hstore.Hash(info.Access))
+			So(ainfo.GetRefresh(), ShouldEqual, hstore.Hash(info.Refresh))
+
+			// the stored digest can't be used as a token
+			ainfo, err = hstore.GetByAccess(ctx, hstore.Hash(info.Access))
+			So(err, ShouldBeNil)
+			So(ainfo, ShouldBeNil)
+
+			ainfo, err = store.NewHashedTokenStore(mstore, []byte("other")).GetByAccess(ctx, info.Access)
+			So(err, ShouldBeNil)
+			So(ainfo, ShouldBeNil)
+
+			ainfo, err = hstore.GetByAccess(ctx, info.Access)
+			So(err, ShouldBeNil)
+			So(ainfo.GetAccess(), ShouldEqual, info.Access)
+			So(ainfo.GetUserID(), ShouldEqual, info.UserID)
+			So(ainfo.GetRefresh(), ShouldNotEqual, hstore.Hash(info.Refresh))
+
+			// the stored digest can't revoke the token
+			err = hstore.RemoveByRefresh(ctx, hstore.Hash(info.Refresh))
+			So(err, ShouldBeNil)
+			rinfo, err := hstore.GetByRefresh(ctx, info.Refresh)
+			So(err, ShouldBeNil)
+			So(rinfo, ShouldNotBeNil)
+
+			// the handle returned by the lookup can
+			err = hstore.RemoveByRefresh(ctx, ainfo.GetRefresh())
+			So(err, ShouldBeNil)
+			rinfo, err = hstore.GetByRefresh(ctx, info.Refresh)
+			So(err, ShouldBeNil)
+			So(rinfo, ShouldBeNil)
+		})
+
+		Convey("Test refreshing removes the old tokens", func() {
+			ctx := context.Background()
+			manager := manage.NewDefaultManager()
+			manager.MapTokenStorage(hstore)
+			clientStore := store.NewClientStore()
+			clientStore.Set("1", &models.Client{ID: "1", Secret: "11", Domain: "http://localhost"})
+			manager.MapClientStorage(clientStore)
+
+			ti, err := manager.GenerateAccessToken(ctx, oauth2.PasswordCredentials, &oauth2.TokenGenerateRequest{
+				ClientID:     "1",
+				ClientSecret: "11",
+				UserID:       "1_6",
+			})
+			So(err, ShouldBeNil)
+
+			rti, err := manager.RefreshAccessToken(ctx, &oauth2.TokenGenerateRequest{ClientID: "1", ClientSecret: "11", Refresh: ti.GetRefresh()})
+			So(err, ShouldBeNil)
+
+			_, err = manager.LoadAccessToken(ctx, ti.GetAccess())
+			So(err, ShouldNotBeNil)
+			_, err = manager.LoadRefreshToken(ctx, ti.GetRefresh())
+			So(err, ShouldNotBeNil)
+
+			ainfo, err := manager.LoadAccessToken(ctx, rti.GetAccess())
+			So(err, ShouldBeNil)
+			So(ainfo.GetUserID(), ShouldEqual, "1_6")
+		})
+	})
+}
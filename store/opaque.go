@@ -0,0 +1,145 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/errors"
+	"github.com/go-oauth2/oauth2/v4/generates"
+	"github.com/go-oauth2/oauth2/v4/models"
+)
+
+// NewOpaqueTokenStore create a token store that decodes the tokens generated by gen,
+// the authorization codes are kept in the code store (can be nil without the authorization code grant)
+func NewOpaqueTokenStore(gen *generates.OpaqueAccessGenerate, codeStore oauth2.TokenStore) *OpaqueTokenStore {
+	return &OpaqueTokenStore{
+		gen:       gen,
+		codeStore: codeStore,
+		revoked:   make(map[string]time.Time),
+	}
+}
+
+// OpaqueTokenStore stateless token storage, the access and refresh tokens carry the encrypted
+// token information, so they are decoded instead of stored.
+//
+// The removed tokens, e.g. the refresh tokens replaced when refreshing, are denied by their ID
+// until they expire. The denylist is in memory: it's local to the store instance and lost on restart.
+type OpaqueTokenStore struct {
+	gen       *generates.OpaqueAccessGenerate
+	codeStore oauth2.TokenStore
+
+	mu          sync.Mutex
+	revoked     map[string]time.Time
+	lastCleanup time.Time
+}
+
+// revoke deny the tokens of the ID until the token information expires,
+// for good if it doesn't
+func (ts *OpaqueTokenStore) revoke(token string, ti oauth2.TokenInfo) {
+	id, ok := ts.gen.TokenID(token)
+	if !ok {
+		return
+	}
+	var expireAt time.Time
+	if ti.GetAccessExpiresIn() > 0 && ti.GetRefreshExpiresIn() > 0 {
+		expireAt = ti.GetAccessCreateAt().Add(ti.GetAccessExpiresIn())
+		if v := ti.GetRefreshCreateAt().Add(ti.GetRefreshExpiresIn()); v.After(expireAt) {
+			expireAt = v
+		}
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(ts.lastCleanup) > time.Minute {
+		for k, v := range ts.revoked {
+			if !v.IsZero() && now.After(v) {
+				delete(ts.revoked, k)
+			}
+		}
+		ts.lastCleanup = now
+	}
+	ts.revoked[id] = expireAt
+}
+
+func (ts *OpaqueTokenStore) isRevoked(token string) bool {
+	id, ok := ts.gen.TokenID(token)
+	if !ok {
+		return false
+	}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	_, ok = ts.revoked[id]
+	return ok
+}
+
+// Create store the authorization code, the tokens need no storage
+func (ts *OpaqueTokenStore) Create(ctx context.Context, info oauth2.TokenInfo) error {
+	if info.GetCode() == "" {
+		return nil
+	} else if ts.codeStore == nil {
+		return errors.New("the authorization code requires a code store")
+	}
+	return ts.codeStore.Create(ctx, info)
+}
+
+// RemoveByCode use the authorization code to delete the token information
+func (ts *OpaqueTokenStore) RemoveByCode(ctx context.Context, code string) error {
+	if ts.codeStore == nil {
+		return nil
+	}
+	return ts.codeStore.RemoveByCode(ctx, code)
+}
+
+// RemoveByAccess deny the access token and the refresh token generated with it
+func (ts *OpaqueTokenStore) RemoveByAccess(ctx context.Context, access string) error {
+	ti := models.NewToken()
+	if err := ts.gen.DecodeAccess(access, ti); err == nil {
+		ts.revoke(access, ti)
+	}
+	return nil
+}
+
+// RemoveByRefresh deny the refresh token and the access token generated with it
+func (ts *OpaqueTokenStore) RemoveByRefresh(ctx context.Context, refresh string) error {
+	ti := models.NewToken()
+	if err := ts.gen.DecodeRefresh(refresh, ti); err == nil {
+		ts.revoke(refresh, ti)
+	}
+	return nil
+}
+
+// GetByCode use the authorization code for token information data
+func (ts *OpaqueTokenStore) GetByCode(ctx context.Context, code string) (oauth2.TokenInfo, error) {
+	if ts.codeStore == nil {
+		return nil, errors.ErrInvalidAuthorizeCode
+	}
+	return ts.codeStore.GetByCode(ctx, code)
+}
+
+// GetByAccess decode the access token into the token information,
+// the tokens failing the decryption or removed return errors.ErrInvalidAccessToken
+func (ts *OpaqueTokenStore) GetByAccess(ctx context.Context, access string) (oauth2.TokenInfo, error) {
+	ti := models.NewToken()
+	if err := ts.gen.DecodeAccess(access, ti); err != nil {
+		return nil, err
+	} else if ts.isRevoked(access) {
+		return nil, errors.ErrInvalidAccessToken
+	}
+	return ti, nil
+}
+
+// GetByRefresh decode the refresh token into the token information,
+// the tokens failing the decryption or removed return errors.ErrInvalidRefreshToken
+func (ts *OpaqueTokenStore) GetByRefresh(ctx context.Context, refresh string) (oauth2.TokenInfo, error) {
+	ti := models.NewToken()
+	if err := ts.gen.DecodeRefresh(refresh, ti); err != nil {
+		return nil, err
+	} else if ts.isRevoked(refresh) {
+		return nil, errors.ErrInvalidRefreshToken
+	}
+	return ti, nil
+}
@@ -0,0 +1,113 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/errors"
+	"github.com/go-oauth2/oauth2/v4/generates"
+	"github.com/go-oauth2/oauth2/v4/manage"
+	"github.com/go-oauth2/oauth2/v4/models"
+	"github.com/go-oauth2/oauth2/v4/store"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestOpaqueTokenStore(t *testing.T) {
+	Convey("Test opaque token store", t, func() {
+		ctx := context.Background()
+		gen, err := generates.NewOpaqueAccessGenerate([]byte("0123456789abcdef"))
+		So(err, ShouldBeNil)
+		codeStore, err := store.NewMemoryTokenStore()
+		So(err, ShouldBeNil)
+
+		manager := manage.NewDefaultManager()
+		manager.MapAccessGenerate(gen)
+		manager.MapTokenStorage(store.NewOpaqueTokenStore(gen, codeStore))
+		clientStore := store.NewClientStore()
+		clientStore.Set("1", &models.Client{ID: "1", Secret: "11", Domain: "http://localhost"})
+		manager.MapClientStorage(clientStore)
+
+		cti, err := manager.GenerateAuthToken(ctx, oauth2.Code, &oauth2.TokenGenerateRequest{
+			ClientID:    "1",
+			UserID:      "1_1",
+			RedirectURI: "http://localhost/oauth2",
+			Scope:       oauth2.ParseScope("all"),
+		})
+		So(err, ShouldBeNil)
+
+		ti, err := manager.GenerateAccessToken(ctx, oauth2.AuthorizationCode, &oauth2.TokenGenerateRequest{
+			ClientID:     "1",
+			ClientSecret: "11",
+			RedirectURI:  "http://localhost/oauth2",
+			Code:         cti.GetCode(),
+		})
+		So(err, ShouldBeNil)
+
+		// the authorization code can be used only once
+		_, err = manager.GenerateAccessToken(ctx, oauth2.AuthorizationCode, &oauth2.TokenGenerateRequest{
+			ClientID:     "1",
+			ClientSecret: "11",
+			RedirectURI:  "http://localhost/oauth2",
+			Code:         cti.GetCode(),
+		})
+		So(err, ShouldNotBeNil)
+
+		ainfo, err := manager.LoadAccessToken(ctx, ti.GetAccess())
+		So(err, ShouldBeNil)
+		So(ainfo.GetUserID(), ShouldEqual, "1_1")
+		So(ainfo.GetScope().String(), ShouldEqual, "all")
+
+		_, err = manager.LoadAccessToken(ctx, ti.GetRefresh())
+		So(err, ShouldNotBeNil)
+
+		rti, err := manager.RefreshAccessToken(ctx, &oauth2.TokenGenerateRequest{
+			ClientID:     "1",
+			ClientSecret: "11",
+			Refresh:      ti.GetRefresh(),
+			Scope:        oauth2.ParseScope("owner"),
+		})
+		So(err, ShouldBeNil)
+
+		ainfo, err = manager.LoadAccessToken(ctx, rti.GetAccess())
+		So(err, ShouldBeNil)
+		So(ainfo.GetUserID(), ShouldEqual, "1_1")
+		So(ainfo.GetScope().String(), ShouldEqual, "owner")
+
+		rinfo, err := manager.LoadRefreshToken(ctx, rti.GetRefresh())
+		So(err, ShouldBeNil)
+		So(rinfo.GetClientID(), ShouldEqual, "1")
+
+		// the replaced tokens are denied
+		_, err = manager.LoadRefreshToken(ctx, ti.GetRefresh())
+		So(err, ShouldNotBeNil)
+		_, err = manager.LoadAccessToken(ctx, ti.GetAccess())
+		So(err, ShouldNotBeNil)
+		_, err = manager.RefreshAccessToken(ctx, &oauth2.TokenGenerateRequest{
+			ClientID:     "1",
+			ClientSecret: "11",
+			Refresh:      ti.GetRefresh(),
+		})
+		So(err, ShouldNotBeNil)
+
+		// the revoked access token denies its refresh token too
+		So(manager.RemoveAccessToken(ctx, rti.GetAccess()), ShouldBeNil)
+		_, err = manager.LoadAccessToken(ctx, rti.GetAccess())
+		So(err, ShouldNotBeNil)
+		_, err = manager.LoadRefreshToken(ctx, rti.GetRefresh())
+		So(err, ShouldNotBeNil)
+
+		_, err = manager.LoadAccessToken(ctx, "invalid")
+		So(err, ShouldNotBeNil)
+
+		// the tampered tokens are reported, not taken as not found
+		ostore := store.NewOpaqueTokenStore(gen, nil)
+		_, err = ostore.GetByAccess(ctx, ti.GetAccess()+"x")
+		So(err, ShouldEqual, errors.ErrInvalidAccessToken)
+		_, err = ostore.GetByRefresh(ctx, rti.GetAccess())
+		So(err, ShouldEqual, errors.ErrInvalidRefreshToken)
+		_, err = ostore.GetByCode(ctx, cti.GetCode())
+		So(err, ShouldEqual, errors.ErrInvalidAuthorizeCode)
+	})
+}
@@ -0,0 +1,241 @@
+package redis
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/models"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// NewRedisStore create a token store instance based on redis
+func NewRedisStore(opts *redis.Options, keyNamespace ...string) *TokenStore {
+	return NewRedisStoreWithCli(redis.NewClient(opts), keyNamespace...)
+}
+
+// NewRedisClusterStore create a token store instance based on redis cluster
+func NewRedisClusterStore(opts *redis.ClusterOptions, keyNamespace ...string) *TokenStore {
+	return NewRedisStoreWithCli(redis.NewClusterClient(opts), keyNamespace...)
+}
+
+// NewRedisStoreWithCli create a token store instance with the redis client
+func NewRedisStoreWithCli(cli redis.UniversalClient, keyNamespace ...string) *TokenStore {
+	store := &TokenStore{cli: cli}
+	if len(keyNamespace) > 0 {
+		store.ns = keyNamespace[0]
+	}
+	return store
+}
+
+// TokenStore token storage based on redis(https://redis.io),
+// using the same key layout and TTL logic as the buntdb store
+type TokenStore struct {
+	cli redis.UniversalClient
+	ns  string
+}
+
+// Close close the redis client
+func (ts *TokenStore) Close() error {
+	return ts.cli.Close()
+}
+
+func (ts *TokenStore) wrapperKey(key string) string {
+	return ts.ns + key
+}
+
+// set store the key with the buntdb expiration semantics, redis keeps the keys
+// set with a TTL not greater than 0 forever so the keys already expired aren't written
+func (ts *TokenStore) set(ctx context.Context, pipe redis.Pipeliner, key string, value interface{}, expires bool, exp time.Duration) {
+	if !expires {
+		pipe.Set(ctx, ts.wrapperKey(key), value, 0)
+		return
+	} else if exp <= 0 {
+		return
+	}
+	pipe.Set(ctx, ts.wrapperKey(key), value, exp)
+}
+
+// clientUserKey the key of the sorted set indexing the token information issued to the client for the user,
+// the members are the authorization codes and basic IDs scored by their expiration
+func (ts *TokenStore) clientUserKey(clientID, userID string) string {
+	return ts.wrapperKey("client_user:" + base64.RawURLEncoding.EncodeToString([]byte(clientID)) + ":" +
+		base64.RawURLEncoding.EncodeToString([]byte(userID)))
+}
+
+// indexScript add the member to the index, drop the expired members and
+// expire the index with its last member
+const indexScript = `
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', '(' .. ARGV[1])
+redis.call('ZADD', KEYS[1], ARGV[2], ARGV[3])
+if redis.call('ZCOUNT', KEYS[1], '+inf', '+inf') > 0 then
+	redis.call('PERSIST', KEYS[1])
+else
+	local last = redis.call('ZRANGE', KEYS[1], -1, -1, 'WITHSCORES')
+	redis.call('EXPIREAT', KEYS[1], last[2])
+end
+return 1
+`
+
+// index add the stored key to the client and user index
+func (ts *TokenStore) index(ctx context.Context, pipe redis.Pipeliner, info oauth2.TokenInfo, key string, expires bool, exp time.Duration) {
+	if expires && exp <= 0 {
+		return
+	}
+	now := time.Now()
+	score := "+inf"
+	if expires {
+		score = strconv.FormatInt(now.Add(exp).Unix()+1, 10)
+	}
+	pipe.Eval(ctx, indexScript, []string{ts.clientUserKey(info.GetClientID(), info.GetUserID())},
+		now.Unix(), score, key)
+}
+
+// Create create and store the new token information
+func (ts *TokenStore) Create(ctx context.Context, info oauth2.TokenInfo) error {
+	ct := time.Now()
+	jv, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	_, err = ts.cli.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if code := info.GetCode(); code != "" {
+			ts.set(ctx, pipe, code, jv, true, info.GetCodeExpiresIn())
+			ts.index(ctx, pipe, info, code, true, info.GetCodeExpiresIn())
+			return nil
+		}
+
+		basicID := uuid.Must(uuid.NewRandom()).String()
+		aexp := info.GetAccessExpiresIn()
+		rexp := aexp
+		expires := true
+		if refresh := info.GetRefresh(); refresh != "" {
+			rexp = info.GetRefreshCreateAt().Add(info.GetRefreshExpiresIn()).Sub(ct)
+			if aexp.Seconds() > rexp.Seconds() {
+				aexp = rexp
+			}
+			expires = info.GetRefreshExpiresIn() != 0
+			ts.set(ctx, pipe, refresh, basicID, expires, rexp)
+		}
+
+		ts.set(ctx, pipe, basicID, jv, expires, rexp)
+		ts.set(ctx, pipe, info.GetAccess(), basicID, expires, aexp)
+		ts.index(ctx, pipe, info, basicID, expires, rexp)
+		return nil
+	})
+	return err
+}
+
+// remove key
+func (ts *TokenStore) remove(ctx context.Context, key string) error {
+	return ts.cli.Del(ctx, ts.wrapperKey(key)).Err()
+}
+
+// RemoveByCode use the authorization code to delete the token information
+func (ts *TokenStore) RemoveByCode(ctx context.Context, code string) error {
+	return ts.remove(ctx, code)
+}
+
+// RemoveByAccess use the access token to delete the token information
+func (ts *TokenStore) RemoveByAccess(ctx context.Context, access string) error {
+	return ts.remove(ctx, access)
+}
+
+// RemoveByRefresh use the refresh token to delete the token information
+func (ts *TokenStore) RemoveByRefresh(ctx context.Context, refresh string) error {
+	return ts.remove(ctx, refresh)
+}
+
+// RemoveByClientUser delete all the token information issued to the client for the user
+func (ts *TokenStore) RemoveByClientUser(ctx context.Context, clientID, userID string) error {
+	ik := ts.clientUserKey(clientID, userID)
+	members, err := ts.cli.ZRange(ctx, ik, 0, -1).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := []string{ik}
+	for _, key := range members {
+		// the index points to the authorization code or the basic ID of the tokens
+		keys = append(keys, ts.wrapperKey(key))
+		jv, err := ts.cli.Get(ctx, ts.wrapperKey(key)).Bytes()
+		if err == redis.Nil {
+			continue
+		} else if err != nil {
+			return err
+		}
+		var tm models.Token
+		if err := json.Unmarshal(jv, &tm); err != nil {
+			continue
+		}
+		if tm.Access != "" {
+			keys = append(keys, ts.wrapperKey(tm.Access))
+		}
+		if tm.Refresh != "" {
+			keys = append(keys, ts.wrapperKey(tm.Refresh))
+		}
+	}
+
+	// the keys may live on other cluster nodes
+	for _, k := range keys {
+		if err := ts.cli.Del(ctx, k).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ts *TokenStore) getData(ctx context.Context, key string) (oauth2.TokenInfo, error) {
+	jv, err := ts.cli.Get(ctx, ts.wrapperKey(key)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var tm models.Token
+	if err := json.Unmarshal(jv, &tm); err != nil {
+		return nil, err
+	}
+	return &tm, nil
+}
+
+func (ts *TokenStore) getBasicID(ctx context.Context, key string) (string, error) {
+	basicID, err := ts.cli.Get(ctx, ts.wrapperKey(key)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return "", nil
+		}
+		return "", err
+	}
+	return basicID, nil
+}
+
+// GetByCode use the authorization code for token information data
+func (ts *TokenStore) GetByCode(ctx context.Context, code string) (oauth2.TokenInfo, error) {
+	return ts.getData(ctx, code)
+}
+
+// GetByAccess use the access token for token information data
+func (ts *TokenStore) GetByAccess(ctx context.Context, access string) (oauth2.TokenInfo, error) {
+	basicID, err := ts.getBasicID(ctx, access)
+	if err != nil || basicID == "" {
+		return nil, err
+	}
+	return ts.getData(ctx, basicID)
+}
+
+// GetByRefresh use the refresh token for token information data
+func (ts *TokenStore) GetByRefresh(ctx context.Context, refresh string) (oauth2.TokenInfo, error) {
+	basicID, err := ts.getBasicID(ctx, refresh)
+	if err != nil || basicID == "" {
+		return nil, err
+	}
+	return ts.getData(ctx, basicID)
+}
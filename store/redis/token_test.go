@@ -0,0 +1,213 @@
+package redis_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-oauth2/oauth2/v4/models"
+	oredis "github.com/go-oauth2/oauth2/v4/store/redis"
+	"github.com/redis/go-redis/v9"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTokenStore(t *testing.T) {
+	Convey("Test redis token store", t, func() {
+		mr := miniredis.RunT(t)
+		store := oredis.NewRedisStore(&redis.Options{Addr: mr.Addr()}, "oauth2:")
+		defer store.Close()
+		ctx := context.Background()
+
+		Convey("Test authorization code store", func() {
+			info := &models.Token{
+				ClientID:      "1",
+				UserID:        "1_1",
+				RedirectURI:   "http://localhost/",
+				Scope:         "all",
+				Code:          "11_11_11",
+				CodeCreateAt:  time.Now(),
+				CodeExpiresIn: time.Second * 5,
+			}
+			err := store.Create(ctx, info)
+			So(err, ShouldBeNil)
+			So(mr.Exists("oauth2:11_11_11"), ShouldBeTrue)
+			So(mr.TTL("oauth2:11_11_11"), ShouldEqual, time.Second*5)
+
+			cinfo, err := store.GetByCode(ctx, info.Code)
+			So(err, ShouldBeNil)
+			So(cinfo.GetUserID(), ShouldEqual, info.UserID)
+
+			err = store.RemoveByCode(ctx, info.Code)
+			So(err, ShouldBeNil)
+
+			cinfo, err = store.GetByCode(ctx, info.Code)
+			So(err, ShouldBeNil)
+			So(cinfo, ShouldBeNil)
+		})
+
+		Convey("Test access token store", func() {
+			info := &models.Token{
+				ClientID:        "1",
+				UserID:          "1_1",
+				RedirectURI:     "http://localhost/",
+				Scope:           "all",
+				Access:          "1_1_1",
+				AccessCreateAt:  time.Now(),
+				AccessExpiresIn: time.Second * 5,
+			}
+			err := store.Create(ctx, info)
+			So(err, ShouldBeNil)
+
+			ainfo, err := store.GetByAccess(ctx, info.GetAccess())
+			So(err, ShouldBeNil)
+			So(ainfo.GetUserID(), ShouldEqual, info.GetUserID())
+
+			err = store.RemoveByAccess(ctx, info.GetAccess())
+			So(err, ShouldBeNil)
+
+			ainfo, err = store.GetByAccess(ctx, info.GetAccess())
+			So(err, ShouldBeNil)
+			So(ainfo, ShouldBeNil)
+		})
+
+		Convey("Test refresh token store", func() {
+			info := &models.Token{
+				ClientID:         "1",
+				UserID:           "1_2",
+				RedirectURI:      "http://localhost/",
+				Scope:            "all",
+				Access:           "1_2_1",
+				AccessCreateAt:   time.Now(),
+				AccessExpiresIn:  time.Second * 5,
+				Refresh:          "1_2_2",
+				RefreshCreateAt:  time.Now(),
+				RefreshExpiresIn: time.Second * 15,
+			}
+			err := store.Create(ctx, info)
+			So(err, ShouldBeNil)
+
+			rinfo, err := store.GetByRefresh(ctx, info.GetRefresh())
+			So(err, ShouldBeNil)
+			So(rinfo.GetUserID(), ShouldEqual, info.GetUserID())
+
+			err = store.RemoveByRefresh(ctx, info.GetRefresh())
+			So(err, ShouldBeNil)
+
+			rinfo, err = store.GetByRefresh(ctx, info.GetRefresh())
+			So(err, ShouldBeNil)
+			So(rinfo, ShouldBeNil)
+		})
+
+		Convey("Test refresh token without expiration", func() {
+			info := &models.Token{
+				ClientID:        "1",
+				UserID:          "1_4",
+				Access:          "1_4_1",
+				AccessCreateAt:  time.Now(),
+				AccessExpiresIn: time.Second * 5,
+				Refresh:         "1_4_2",
+				RefreshCreateAt: time.Now(),
+			}
+			err := store.Create(ctx, info)
+			So(err, ShouldBeNil)
+			So(mr.TTL("oauth2:1_4_1"), ShouldEqual, 0)
+			So(mr.TTL("oauth2:1_4_2"), ShouldEqual, 0)
+			So(mr.Exists("oauth2:client_user:MQ:MV80"), ShouldBeTrue)
+			So(mr.TTL("oauth2:client_user:MQ:MV80"), ShouldEqual, 0)
+		})
+
+		Convey("Test TTL", func() {
+			info := &models.Token{
+				ClientID:         "1",
+				UserID:           "1_1",
+				RedirectURI:      "http://localhost/",
+				Scope:            "all",
+				Access:           "1_3_1",
+				AccessCreateAt:   time.Now(),
+				AccessExpiresIn:  time.Second * 1,
+				Refresh:          "1_3_2",
+				RefreshCreateAt:  time.Now(),
+				RefreshExpiresIn: time.Second * 1,
+			}
+			err := store.Create(ctx, info)
+			So(err, ShouldBeNil)
+
+			mr.FastForward(time.Second * 1)
+			ainfo, err := store.GetByAccess(ctx, info.Access)
+			So(err, ShouldBeNil)
+			So(ainfo, ShouldBeNil)
+			rinfo, err := store.GetByRefresh(ctx, info.Refresh)
+			So(err, ShouldBeNil)
+			So(rinfo, ShouldBeNil)
+		})
+
+		Convey("Test the expired refresh token isn't stored", func() {
+			info := &models.Token{
+				ClientID:         "1",
+				UserID:           "1_5",
+				Access:           "1_5_1",
+				AccessCreateAt:   time.Now(),
+				AccessExpiresIn:  time.Second * 5,
+				Refresh:          "1_5_2",
+				RefreshCreateAt:  time.Now().Add(-time.Second * 10),
+				RefreshExpiresIn: time.Second * 5,
+			}
+			err := store.Create(ctx, info)
+			So(err, ShouldBeNil)
+			So(mr.Exists("oauth2:1_5_1"), ShouldBeFalse)
+			So(mr.Exists("oauth2:1_5_2"), ShouldBeFalse)
+			So(len(mr.Keys()), ShouldEqual, 0)
+		})
+
+		Convey("Test remove the tokens by client and user", func() {
+			newToken := func(clientID, userID, suffix string) *models.Token {
+				return &models.Token{
+					ClientID:         clientID,
+					UserID:           userID,
+					Access:           "access_" + suffix,
+					AccessCreateAt:   time.Now(),
+					AccessExpiresIn:  time.Minute,
+					Refresh:          "refresh_" + suffix,
+					RefreshCreateAt:  time.Now(),
+					RefreshExpiresIn: time.Hour,
+				}
+			}
+			code := &models.Token{
+				ClientID:      "1",
+				UserID:        "u1",
+				Code:          "code_1",
+				CodeCreateAt:  time.Now(),
+				CodeExpiresIn: time.Minute,
+			}
+			So(store.Create(ctx, code), ShouldBeNil)
+			revoked := newToken("1", "u1", "1")
+			So(store.Create(ctx, revoked), ShouldBeNil)
+			kept := newToken("1", "u2", "2")
+			So(store.Create(ctx, kept), ShouldBeNil)
+			mr.Set("other:key", `{"ClientID":"1","UserID":"u1"}`)
+
+			// the index expires with its last token
+			index := "oauth2:client_user:MQ:dTE"
+			members, err := mr.ZMembers(index)
+			So(err, ShouldBeNil)
+			So(len(members), ShouldEqual, 2)
+			So(mr.TTL(index), ShouldBeGreaterThan, time.Minute*59)
+			So(mr.TTL(index), ShouldBeLessThanOrEqualTo, time.Hour+time.Second*2)
+
+			err = store.RemoveByClientUser(ctx, "1", "u1")
+			So(err, ShouldBeNil)
+
+			So(mr.Exists("oauth2:code_1"), ShouldBeFalse)
+			So(mr.Exists("oauth2:access_1"), ShouldBeFalse)
+			So(mr.Exists("oauth2:refresh_1"), ShouldBeFalse)
+			So(mr.Exists("oauth2:access_2"), ShouldBeTrue)
+			So(mr.Exists("other:key"), ShouldBeTrue)
+			So(mr.Exists(index), ShouldBeFalse)
+			ainfo, err := store.GetByAccess(ctx, kept.Access)
+			So(err, ShouldBeNil)
+			So(ainfo.GetUserID(), ShouldEqual, "u2")
+		})
+	})
+}
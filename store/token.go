@@ -2,7 +2,9 @@ package store
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"strings"
 	"time"
 
 	"github.com/go-oauth2/oauth2/v4"
@@ -30,6 +32,15 @@ type TokenStore struct {
 	db *buntdb.DB
 }
 
+// clientUserPrefix the key prefix of the index of the token information by client and user
+const clientUserPrefix = "client_user:"
+
+// clientUserKey the index key prefix of the token information issued to the client for the user
+func clientUserKey(clientID, userID string) string {
+	return clientUserPrefix + base64.RawURLEncoding.EncodeToString([]byte(clientID)) + ":" +
+		base64.RawURLEncoding.EncodeToString([]byte(userID)) + ":"
+}
+
 // Create create and store the new token information
 func (ts *TokenStore) Create(ctx context.Context, info oauth2.TokenInfo) error {
 	ct := time.Now()
@@ -40,7 +51,11 @@ func (ts *TokenStore) Create(ctx context.Context, info oauth2.TokenInfo) error {
 
 	return ts.db.Update(func(tx *buntdb.Tx) error {
 		if code := info.GetCode(); code != "" {
-			_, _, err := tx.Set(code, string(jv), &buntdb.SetOptions{Expires: true, TTL: info.GetCodeExpiresIn()})
+			opts := &buntdb.SetOptions{Expires: true, TTL: info.GetCodeExpiresIn()}
+			if _, _, err := tx.Set(code, string(jv), opts); err != nil {
+				return err
+			}
+			_, _, err := tx.Set(clientUserKey(info.GetClientID(), info.GetUserID())+code, "", opts)
 			return err
 		}
 
@@ -65,6 +80,10 @@ func (ts *TokenStore) Create(ctx context.Context, info oauth2.TokenInfo) error {
 			return err
 		}
 		_, _, err = tx.Set(info.GetAccess(), basicID, &buntdb.SetOptions{Expires: expires, TTL: aexp})
+		if err != nil {
+			return err
+		}
+		_, _, err = tx.Set(clientUserKey(info.GetClientID(), info.GetUserID())+basicID, "", &buntdb.SetOptions{Expires: expires, TTL: rexp})
 		return err
 	})
 }
@@ -96,6 +115,51 @@ func (ts *TokenStore) RemoveByRefresh(ctx context.Context, refresh string) error
 	return ts.remove(refresh)
 }
 
+// RemoveByClientUser delete all the token information issued to the client for the user
+func (ts *TokenStore) RemoveByClientUser(ctx context.Context, clientID, userID string) error {
+	prefix := clientUserKey(clientID, userID)
+	return ts.db.Update(func(tx *buntdb.Tx) error {
+		var indexKeys []string
+		err := tx.AscendKeys(prefix+"*", func(key, value string) bool {
+			indexKeys = append(indexKeys, key)
+			return true
+		})
+		if err != nil {
+			return err
+		}
+
+		var keys []string
+		for _, indexKey := range indexKeys {
+			// the index points to the authorization code or the basic ID of the tokens
+			key := strings.TrimPrefix(indexKey, prefix)
+			keys = append(keys, indexKey, key)
+			jv, err := tx.Get(key)
+			if err == buntdb.ErrNotFound {
+				continue
+			} else if err != nil {
+				return err
+			}
+			var tm models.Token
+			if err := json.Unmarshal([]byte(jv), &tm); err != nil {
+				continue
+			}
+			if tm.Access != "" {
+				keys = append(keys, tm.Access)
+			}
+			if tm.Refresh != "" {
+				keys = append(keys, tm.Refresh)
+			}
+		}
+
+		for _, key := range keys {
+			if _, err := tx.Delete(key); err != nil && err != buntdb.ErrNotFound {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 func (ts *TokenStore) getData(key string) (oauth2.TokenInfo, error) {
 	var ti oauth2.TokenInfo
 	err := ts.db.View(func(tx *buntdb.Tx) error {
@@ -137,3 +137,65 @@ func testToken(store oauth2.TokenStore) {
 		So(rinfo, ShouldBeNil)
 	})
 }
+
+func TestTokenStoreRemoveByClientUser(t *testing.T) {
+	Convey("Test remove the tokens by client and user", t, func() {
+		ctx := context.Background()
+		ts, err := store.NewMemoryTokenStore()
+		So(err, ShouldBeNil)
+		testRemoveByClientUser(ctx, ts)
+	})
+}
+
+func testRemoveByClientUser(ctx context.Context, ts oauth2.TokenStore) {
+	newToken := func(clientID, userID, suffix string) *models.Token {
+		return &models.Token{
+			ClientID:         clientID,
+			UserID:           userID,
+			Access:           "access_" + suffix,
+			AccessCreateAt:   time.Now(),
+			AccessExpiresIn:  time.Minute,
+			Refresh:          "refresh_" + suffix,
+			RefreshCreateAt:  time.Now(),
+			RefreshExpiresIn: time.Hour,
+		}
+	}
+	code := &models.Token{
+		ClientID:      "1",
+		UserID:        "u1",
+		Code:          "code_1",
+		CodeCreateAt:  time.Now(),
+		CodeExpiresIn: time.Minute,
+	}
+	So(ts.Create(ctx, code), ShouldBeNil)
+	revoked := newToken("1", "u1", "1")
+	So(ts.Create(ctx, revoked), ShouldBeNil)
+	otherUser := newToken("1", "u2", "2")
+	So(ts.Create(ctx, otherUser), ShouldBeNil)
+	otherClient := newToken("2", "u1", "3")
+	So(ts.Create(ctx, otherClient), ShouldBeNil)
+
+	ti, err := ts.GetByAccess(ctx, revoked.Access)
+	So(err, ShouldBeNil)
+	So(ti, ShouldNotBeNil)
+
+	err = ts.(oauth2.TokenRevocationStore).RemoveByClientUser(ctx, "1", "u1")
+	So(err, ShouldBeNil)
+
+	ti, err = ts.GetByCode(ctx, code.Code)
+	So(err, ShouldBeNil)
+	So(ti, ShouldBeNil)
+	ti, err = ts.GetByAccess(ctx, revoked.Access)
+	So(err, ShouldBeNil)
+	So(ti, ShouldBeNil)
+	ti, err = ts.GetByRefresh(ctx, revoked.Refresh)
+	So(err, ShouldBeNil)
+	So(ti, ShouldBeNil)
+
+	ti, err = ts.GetByAccess(ctx, otherUser.Access)
+	So(err, ShouldBeNil)
+	So(ti, ShouldNotBeNil)
+	ti, err = ts.GetByRefresh(ctx, otherClient.Refresh)
+	So(err, ShouldBeNil)
+	So(ti, ShouldNotBeNil)
+}
@@ -0,0 +1,60 @@
+package store
+
+import (
+	"net/url"
+
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/models"
+)
+
+// cloneToken copy the token information into a new instance of the same model
+func cloneToken(ti oauth2.TokenInfo) oauth2.TokenInfo {
+	c := ti.New()
+	c.SetClientID(ti.GetClientID())
+	c.SetUserID(ti.GetUserID())
+	c.SetRedirectURI(ti.GetRedirectURI())
+	c.SetScope(ti.GetScope())
+	c.SetCode(ti.GetCode())
+	c.SetCodeCreateAt(ti.GetCodeCreateAt())
+	c.SetCodeExpiresIn(ti.GetCodeExpiresIn())
+	c.SetCodeChallenge(ti.GetCodeChallenge())
+	c.SetCodeChallengeMethod(ti.GetCodeChallengeMethod())
+	c.SetAccess(ti.GetAccess())
+	c.SetAccessCreateAt(ti.GetAccessCreateAt())
+	c.SetAccessExpiresIn(ti.GetAccessExpiresIn())
+	c.SetRefresh(ti.GetRefresh())
+	c.SetRefreshCreateAt(ti.GetRefreshCreateAt())
+	c.SetRefreshExpiresIn(ti.GetRefreshExpiresIn())
+
+	if eti, ok := ti.(oauth2.ExtendableTokenInfo); ok {
+		if ec, ok := c.(oauth2.ExtendableTokenInfo); ok && eti.GetExtension() != nil {
+			ext := make(url.Values, len(eti.GetExtension()))
+			for k, v := range eti.GetExtension() {
+				ext[k] = append([]string(nil), v...)
+			}
+			ec.SetExtension(ext)
+		}
+	}
+	if rti, ok := ti.(oauth2.ResourceTokenInfo); ok {
+		if rc, ok := c.(oauth2.ResourceTokenInfo); ok && rti.GetResource() != nil {
+			rc.SetResource(append([]string(nil), rti.GetResource()...))
+		}
+		if rc, ok := c.(oauth2.ResourceTokenInfo); ok && rti.GetGrantedResource() != nil {
+			rc.SetGrantedResource(append([]string(nil), rti.GetGrantedResource()...))
+		}
+	}
+	if dti, ok := ti.(oauth2.AuthorizationDetailsTokenInfo); ok {
+		if dc, ok := c.(oauth2.AuthorizationDetailsTokenInfo); ok && dti.GetAuthorizationDetails() != nil {
+			dc.SetAuthorizationDetails(append([]oauth2.AuthorizationDetail(nil), dti.GetAuthorizationDetails()...))
+		}
+	}
+	return c
+}
+
+// cloneClient copy the models.Client, the other client models are returned as is
+func cloneClient(cli oauth2.ClientInfo) oauth2.ClientInfo {
+	if c, ok := cli.(*models.Client); ok {
+		return c.Clone()
+	}
+	return cli
+}
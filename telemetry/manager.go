@@ -0,0 +1,144 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-oauth2/oauth2/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// WrapManager create the manager wrapper that traces the operations and counts the issued tokens
+func (t *Telemetry) WrapManager(m oauth2.Manager) *Manager {
+	return &Manager{manager: m, t: t}
+}
+
+// Manager the instrumented authorization management,
+// it also forwards the user consent management when the wrapped manager supports it
+type Manager struct {
+	manager oauth2.Manager
+	t       *Telemetry
+}
+
+func (m *Manager) issued(ctx context.Context, grantType string) {
+	m.t.issued.Add(ctx, 1, metric.WithAttributes(AttrGrantType.String(grantType)))
+}
+
+// GetClient get the client information
+func (m *Manager) GetClient(ctx context.Context, clientID string) (cli oauth2.ClientInfo, err error) {
+	ctx, end := m.t.start(ctx, "oauth2.Manager/GetClient", AttrClientID.String(clientID))
+	defer func() { end(err) }()
+	return m.manager.GetClient(ctx, clientID)
+}
+
+// GenerateAuthToken generate the authorization token(code)
+func (m *Manager) GenerateAuthToken(ctx context.Context, rt oauth2.ResponseType, tgr *oauth2.TokenGenerateRequest) (ti oauth2.TokenInfo, err error) {
+	ctx, end := m.t.start(ctx, "oauth2.Manager/GenerateAuthToken",
+		AttrClientID.String(tgr.ClientID), attribute.String("oauth2.response_type", rt.String()))
+	defer func() { end(err) }()
+
+	ti, err = m.manager.GenerateAuthToken(ctx, rt, tgr)
+	if err == nil && rt == oauth2.Token {
+		m.issued(ctx, string(oauth2.Implicit))
+	}
+	return ti, err
+}
+
+// GenerateAccessToken generate the access token
+func (m *Manager) GenerateAccessToken(ctx context.Context, gt oauth2.GrantType, tgr *oauth2.TokenGenerateRequest) (ti oauth2.TokenInfo, err error) {
+	ctx, end := m.t.start(ctx, "oauth2.Manager/GenerateAccessToken",
+		AttrClientID.String(tgr.ClientID), AttrGrantType.String(string(gt)))
+	defer func() { end(err) }()
+
+	ti, err = m.manager.GenerateAccessToken(ctx, gt, tgr)
+	if err == nil {
+		m.issued(ctx, string(gt))
+	}
+	return ti, err
+}
+
+// RefreshAccessToken refreshing an access token
+func (m *Manager) RefreshAccessToken(ctx context.Context, tgr *oauth2.TokenGenerateRequest) (ti oauth2.TokenInfo, err error) {
+	ctx, end := m.t.start(ctx, "oauth2.Manager/RefreshAccessToken",
+		AttrClientID.String(tgr.ClientID), AttrGrantType.String(string(oauth2.Refreshing)))
+	defer func() { end(err) }()
+
+	ti, err = m.manager.RefreshAccessToken(ctx, tgr)
+	if err == nil {
+		m.issued(ctx, string(oauth2.Refreshing))
+	}
+	return ti, err
+}
+
+// RemoveAccessToken use the access token to delete the token information
+func (m *Manager) RemoveAccessToken(ctx context.Context, access string) (err error) {
+	ctx, end := m.t.start(ctx, "oauth2.Manager/RemoveAccessToken")
+	defer func() { end(err) }()
+	return m.manager.RemoveAccessToken(ctx, access)
+}
+
+// RemoveRefreshToken use the refresh token to delete the token information
+func (m *Manager) RemoveRefreshToken(ctx context.Context, refresh string) (err error) {
+	ctx, end := m.t.start(ctx, "oauth2.Manager/RemoveRefreshToken")
+	defer func() { end(err) }()
+	return m.manager.RemoveRefreshToken(ctx, refresh)
+}
+
+// LoadAccessToken according to the access token for corresponding token information
+func (m *Manager) LoadAccessToken(ctx context.Context, access string) (ti oauth2.TokenInfo, err error) {
+	ctx, end := m.t.start(ctx, "oauth2.Manager/LoadAccessToken")
+	defer func() { end(err) }()
+	return m.manager.LoadAccessToken(ctx, access)
+}
+
+// LoadRefreshToken according to the refresh token for corresponding token information
+func (m *Manager) LoadRefreshToken(ctx context.Context, refresh string) (ti oauth2.TokenInfo, err error) {
+	ctx, end := m.t.start(ctx, "oauth2.Manager/LoadRefreshToken")
+	defer func() { end(err) }()
+	return m.manager.LoadRefreshToken(ctx, refresh)
+}
+
+func (m *Manager) consentManager() (oauth2.ConsentManager, error) {
+	cm, ok := m.manager.(oauth2.ConsentManager)
+	if !ok {
+		return nil, errors.New("the manager doesn't support the user consent")
+	}
+	return cm, nil
+}
+
+// CheckConsent get the valid user consent for the client and the requested scope not consented yet
+func (m *Manager) CheckConsent(ctx context.Context, userID, clientID string, scope oauth2.Scope) (ci oauth2.ConsentInfo, missingScope oauth2.Scope, err error) {
+	ctx, end := m.t.start(ctx, "oauth2.Manager/CheckConsent", AttrClientID.String(clientID))
+	defer func() { end(err) }()
+
+	cm, err := m.consentManager()
+	if err != nil {
+		return nil, nil, err
+	}
+	return cm.CheckConsent(ctx, userID, clientID, scope)
+}
+
+// GrantConsent add the scope to the user consent for the client
+func (m *Manager) GrantConsent(ctx context.Context, userID, clientID string, scope oauth2.Scope) (ci oauth2.ConsentInfo, err error) {
+	ctx, end := m.t.start(ctx, "oauth2.Manager/GrantConsent", AttrClientID.String(clientID))
+	defer func() { end(err) }()
+
+	cm, err := m.consentManager()
+	if err != nil {
+		return nil, err
+	}
+	return cm.GrantConsent(ctx, userID, clientID, scope)
+}
+
+// RevokeConsent delete the user consent and the tokens issued to the client for the user
+func (m *Manager) RevokeConsent(ctx context.Context, userID, clientID string) (err error) {
+	ctx, end := m.t.start(ctx, "oauth2.Manager/RevokeConsent", AttrClientID.String(clientID))
+	defer func() { end(err) }()
+
+	cm, err := m.consentManager()
+	if err != nil {
+		return err
+	}
+	return cm.RevokeConsent(ctx, userID, clientID)
+}
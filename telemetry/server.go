@@ -0,0 +1,103 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-oauth2/oauth2/v4/errors"
+	"github.com/go-oauth2/oauth2/v4/server"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentServer wrap the server manager and count the error responses by OAuth error code,
+// the response error handler already set on the server is still called
+func (t *Telemetry) InstrumentServer(srv *server.Server) {
+	if _, ok := srv.Manager.(*Manager); !ok {
+		srv.Manager = t.WrapManager(srv.Manager)
+	}
+
+	next := srv.ResponseErrorHandler
+	srv.SetResponseErrorHandler(func(re *errors.Response) {
+		if next != nil {
+			next(re)
+		}
+		if re.Error != nil {
+			t.errors.Add(context.Background(), 1, metric.WithAttributes(
+				AttrOperation.String("oauth2.Server"), AttrErrorCode.String(re.Error.Error())))
+		}
+	})
+}
+
+// HandlerFunc trace the server handler (e.g. srv.HandleTokenRequest) and record the request duration,
+// the operation names the span and the duration attribute
+func (t *Telemetry) HandlerFunc(operation string, handler func(w http.ResponseWriter, r *http.Request) error) http.HandlerFunc {
+	name := "oauth2.Server/" + operation
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx, span := t.tracer.Start(r.Context(), name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attribute.String("http.request.method", r.Method)))
+		defer span.End()
+
+		rw := &statusWriter{ResponseWriter: w}
+		err := handler(rw, r.WithContext(ctx))
+		if err != nil {
+			span.RecordError(err)
+			if !rw.written {
+				// the error isn't sent to the client, it may reveal the server internals
+				writeServerError(rw)
+			}
+		}
+
+		status := rw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes(AttrStatusCode.Int(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		t.requestDuration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(
+			AttrOperation.String(operation), AttrStatusCode.Int(status)))
+	}
+}
+
+// writeServerError write the server_error response of the unhandled error
+func writeServerError(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(http.StatusInternalServerError)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"error":             errors.ErrServerError.Error(),
+		"error_description": errors.Descriptions[errors.ErrServerError],
+	})
+}
+
+// statusWriter record the response status code
+type statusWriter struct {
+	http.ResponseWriter
+	status  int
+	written bool
+}
+
+func (w *statusWriter) WriteHeader(statusCode int) {
+	if !w.written {
+		w.status = statusCode
+		w.written = true
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if !w.written {
+		w.status = http.StatusOK
+		w.written = true
+	}
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,96 @@
+package telemetry_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect"
+	"github.com/go-oauth2/oauth2/v4/server"
+	"github.com/go-oauth2/oauth2/v4/telemetry"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestServer(t *testing.T) {
+	Convey("Test instrumented server", t, func() {
+		tel, recorder, reader := newTelemetry()
+		srv := server.NewDefaultServer(newManager(tel))
+		tel.InstrumentServer(srv)
+		_, ok := srv.Manager.(*telemetry.Manager)
+		So(ok, ShouldBeTrue)
+
+		tsrv := httptest.NewServer(tel.HandlerFunc("token", srv.HandleTokenRequest))
+		defer tsrv.Close()
+		e := httpexpect.New(t, tsrv.URL)
+
+		e.POST("/token").
+			WithFormField("grant_type", "client_credentials").
+			WithBasicAuth("1", "11").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().ContainsKey("access_token")
+
+		e.POST("/token").
+			WithFormField("grant_type", "client_credentials").
+			WithBasicAuth("1", "invalid").
+			Expect().
+			Status(http.StatusUnauthorized)
+
+		Convey("Spans", func() {
+			roots := make(map[trace.SpanID]bool)
+			for _, s := range recorder.Ended() {
+				if s.Name() == "oauth2.Server/token" {
+					roots[s.SpanContext().SpanID()] = true
+				}
+			}
+			So(roots, ShouldHaveLength, 2)
+
+			// the manager spans are children of the request spans
+			children := 0
+			for _, s := range recorder.Ended() {
+				if s.Name() == "oauth2.Manager/GenerateAccessToken" && roots[s.Parent().SpanID()] {
+					children++
+				}
+			}
+			So(children, ShouldEqual, 2)
+		})
+
+		Convey("Error responses by OAuth error code", func() {
+			So(counterValue(reader, telemetry.MetricErrors,
+				telemetry.AttrOperation.String("oauth2.Server")), ShouldEqual, 1)
+		})
+
+		Convey("Request duration", func() {
+			hist, ok := collect(reader, telemetry.MetricRequestDuration).(metricdata.Histogram[float64])
+			So(ok, ShouldBeTrue)
+			statuses := make(map[int64]uint64)
+			for _, dp := range hist.DataPoints {
+				v, _ := dp.Attributes.Value(telemetry.AttrStatusCode)
+				statuses[v.AsInt64()] += dp.Count
+			}
+			So(statuses[http.StatusOK], ShouldEqual, 1)
+			So(statuses[http.StatusUnauthorized], ShouldEqual, 1)
+		})
+	})
+}
+
+func TestServerUnhandledError(t *testing.T) {
+	Convey("Test the unhandled handler error isn't sent to the client", t, func() {
+		tel, _, _ := newTelemetry()
+		tsrv := httptest.NewServer(tel.HandlerFunc("custom", func(w http.ResponseWriter, r *http.Request) error {
+			return errors.New("dial tcp 10.0.0.1:5432: connection refused")
+		}))
+		defer tsrv.Close()
+
+		obj := httpexpect.New(t, tsrv.URL).POST("/").
+			Expect().
+			Status(http.StatusInternalServerError).
+			JSON().Object()
+		obj.ValueEqual("error", "server_error")
+		So(obj.Raw()["error_description"], ShouldNotContainSubstring, "10.0.0.1")
+	})
+}
@@ -0,0 +1,130 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-oauth2/oauth2/v4"
+)
+
+// call trace the store call and record its duration
+func (t *Telemetry) call(ctx context.Context, store, operation string, fn func(ctx context.Context) error) error {
+	ctx, end := t.start(ctx, "oauth2."+store+"/"+operation)
+	defer t.measure(ctx, store, operation, time.Now())
+	err := fn(ctx)
+	end(err)
+	return err
+}
+
+// WrapTokenStore create the token store wrapper that traces the calls and records their duration
+func (t *Telemetry) WrapTokenStore(store oauth2.TokenStore) *TokenStore {
+	return &TokenStore{store: store, t: t}
+}
+
+// TokenStore the instrumented token storage,
+// it also forwards RemoveByClientUser when the wrapped store supports it
+type TokenStore struct {
+	store oauth2.TokenStore
+	t     *Telemetry
+}
+
+func (ts *TokenStore) get(ctx context.Context, operation string, fn func(ctx context.Context) (oauth2.TokenInfo, error)) (ti oauth2.TokenInfo, err error) {
+	err = ts.t.call(ctx, "TokenStore", operation, func(ctx context.Context) error {
+		ti, err = fn(ctx)
+		return err
+	})
+	return ti, err
+}
+
+// Create create and store the new token information
+func (ts *TokenStore) Create(ctx context.Context, info oauth2.TokenInfo) error {
+	return ts.t.call(ctx, "TokenStore", "Create", func(ctx context.Context) error {
+		return ts.store.Create(ctx, info)
+	})
+}
+
+// RemoveByCode delete the authorization code
+func (ts *TokenStore) RemoveByCode(ctx context.Context, code string) error {
+	return ts.t.call(ctx, "TokenStore", "RemoveByCode", func(ctx context.Context) error {
+		return ts.store.RemoveByCode(ctx, code)
+	})
+}
+
+// RemoveByAccess use the access token to delete the token information
+func (ts *TokenStore) RemoveByAccess(ctx context.Context, access string) error {
+	return ts.t.call(ctx, "TokenStore", "RemoveByAccess", func(ctx context.Context) error {
+		return ts.store.RemoveByAccess(ctx, access)
+	})
+}
+
+// RemoveByRefresh use the refresh token to delete the token information
+func (ts *TokenStore) RemoveByRefresh(ctx context.Context, refresh string) error {
+	return ts.t.call(ctx, "TokenStore", "RemoveByRefresh", func(ctx context.Context) error {
+		return ts.store.RemoveByRefresh(ctx, refresh)
+	})
+}
+
+// RemoveByClientUser delete all the token information issued to the client for the user
+func (ts *TokenStore) RemoveByClientUser(ctx context.Context, clientID, userID string) error {
+	return ts.t.call(ctx, "TokenStore", "RemoveByClientUser", func(ctx context.Context) error {
+		rs, ok := ts.store.(oauth2.TokenRevocationStore)
+		if !ok {
+			return errors.New("the token store doesn't support removing by client and user")
+		}
+		return rs.RemoveByClientUser(ctx, clientID, userID)
+	})
+}
+
+// GetByCode use the authorization code for token information data
+func (ts *TokenStore) GetByCode(ctx context.Context, code string) (oauth2.TokenInfo, error) {
+	return ts.get(ctx, "GetByCode", func(ctx context.Context) (oauth2.TokenInfo, error) {
+		return ts.store.GetByCode(ctx, code)
+	})
+}
+
+// GetByAccess use the access token for token information data
+func (ts *TokenStore) GetByAccess(ctx context.Context, access string) (oauth2.TokenInfo, error) {
+	return ts.get(ctx, "GetByAccess", func(ctx context.Context) (oauth2.TokenInfo, error) {
+		return ts.store.GetByAccess(ctx, access)
+	})
+}
+
+// GetByRefresh use the refresh token for token information data
+func (ts *TokenStore) GetByRefresh(ctx context.Context, refresh string) (oauth2.TokenInfo, error) {
+	return ts.get(ctx, "GetByRefresh", func(ctx context.Context) (oauth2.TokenInfo, error) {
+		return ts.store.GetByRefresh(ctx, refresh)
+	})
+}
+
+// WrapClientStore create the client store wrapper that traces the calls and records their duration
+func (t *Telemetry) WrapClientStore(store oauth2.ClientStore) *ClientStore {
+	return &ClientStore{store: store, t: t}
+}
+
+// ClientStore the instrumented client storage,
+// it also forwards Update when the wrapped store supports it
+type ClientStore struct {
+	store oauth2.ClientStore
+	t     *Telemetry
+}
+
+// GetByID according to the ID for the client information
+func (cs *ClientStore) GetByID(ctx context.Context, id string) (cli oauth2.ClientInfo, err error) {
+	err = cs.t.call(ctx, "ClientStore", "GetByID", func(ctx context.Context) error {
+		cli, err = cs.store.GetByID(ctx, id)
+		return err
+	})
+	return cli, err
+}
+
+// Update update the stored client information
+func (cs *ClientStore) Update(ctx context.Context, info oauth2.ClientInfo) error {
+	return cs.t.call(ctx, "ClientStore", "Update", func(ctx context.Context) error {
+		us, ok := cs.store.(oauth2.ClientUpdateStore)
+		if !ok {
+			return errors.New("the client store doesn't support updates")
+		}
+		return us.Update(ctx, info)
+	})
+}
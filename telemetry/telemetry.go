@@ -0,0 +1,162 @@
+// Package telemetry instruments the authorization server with OpenTelemetry spans and metrics.
+//
+// It is an optional module with its own go.mod, so the core module doesn't depend on OpenTelemetry:
+//
+//	go get github.com/go-oauth2/oauth2/v4/telemetry
+//
+//	tel, err := telemetry.New()
+//	manager.MapTokenStorage(tel.WrapTokenStore(tokenStore))
+//	manager.MapClientStorage(tel.WrapClientStore(clientStore))
+//	srv := server.NewDefaultServer(manager)
+//	tel.InstrumentServer(srv)
+//	http.Handle("/token", tel.HandlerFunc("token", srv.HandleTokenRequest))
+package telemetry
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-oauth2/oauth2/v4/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName the instrumentation scope name of the tracer and the meter
+const ScopeName = "github.com/go-oauth2/oauth2/v4/telemetry"
+
+// the metric names
+const (
+	MetricTokensIssued    = "oauth2.tokens.issued"
+	MetricErrors          = "oauth2.errors"
+	MetricStoreDuration   = "oauth2.store.duration"
+	MetricRequestDuration = "oauth2.server.duration"
+)
+
+// the attribute keys
+const (
+	AttrOperation  = attribute.Key("oauth2.operation")
+	AttrGrantType  = attribute.Key("oauth2.grant_type")
+	AttrClientID   = attribute.Key("oauth2.client_id")
+	AttrErrorCode  = attribute.Key("oauth2.error")
+	AttrStore      = attribute.Key("oauth2.store")
+	AttrStatusCode = attribute.Key("http.response.status_code")
+)
+
+// Option the instrumentation option
+type Option func(*Telemetry)
+
+// WithTracerProvider set the tracer provider, the global one is used by default
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(t *Telemetry) {
+		t.tracer = tp.Tracer(ScopeName)
+	}
+}
+
+// WithMeterProvider set the meter provider, the global one is used by default
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(t *Telemetry) {
+		t.meter = mp.Meter(ScopeName)
+	}
+}
+
+// New create the instrumentation
+func New(opts ...Option) (*Telemetry, error) {
+	t := &Telemetry{
+		tracer: otel.GetTracerProvider().Tracer(ScopeName),
+		meter:  otel.GetMeterProvider().Meter(ScopeName),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	var err error
+	t.issued, err = t.meter.Int64Counter(MetricTokensIssued,
+		metric.WithDescription("The number of the issued tokens by grant type"),
+		metric.WithUnit("{token}"))
+	if err != nil {
+		return nil, err
+	}
+	t.errors, err = t.meter.Int64Counter(MetricErrors,
+		metric.WithDescription("The number of the errors by OAuth error code"),
+		metric.WithUnit("{error}"))
+	if err != nil {
+		return nil, err
+	}
+	t.storeDuration, err = t.meter.Float64Histogram(MetricStoreDuration,
+		metric.WithDescription("The duration of the store calls"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+	t.requestDuration, err = t.meter.Float64Histogram(MetricRequestDuration,
+		metric.WithDescription("The duration of the server requests"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Telemetry the spans and metrics instrumentation
+type Telemetry struct {
+	tracer          trace.Tracer
+	meter           metric.Meter
+	issued          metric.Int64Counter
+	errors          metric.Int64Counter
+	storeDuration   metric.Float64Histogram
+	requestDuration metric.Float64Histogram
+}
+
+// start a span of the operation, the returned function ends it and records the error
+func (t *Telemetry) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, func(err error)) {
+	ctx, span := t.tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+	return ctx, func(err error) {
+		if err != nil {
+			code := ErrorCode(err)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, code)
+			t.errors.Add(ctx, 1, metric.WithAttributes(AttrOperation.String(name), AttrErrorCode.String(code)))
+		}
+		span.End()
+	}
+}
+
+// measure record the duration of the store call
+func (t *Telemetry) measure(ctx context.Context, store, operation string, start time.Time) {
+	t.storeDuration.Record(ctx, time.Since(start).Seconds(),
+		metric.WithAttributes(AttrStore.String(store), AttrOperation.String(operation)))
+}
+
+// ErrorCode returns the OAuth error code of the error, the manager errors are mapped
+// to the codes the server responds with and the unknown errors to server_error
+func ErrorCode(err error) string {
+	var oe *errors.OAuthError
+	if errors.As(err, &oe) {
+		return oe.ErrorCode()
+	}
+	for e := range errors.Descriptions {
+		if errors.Is(err, e) {
+			return e.Error()
+		}
+	}
+
+	switch {
+	case errors.Is(err, errors.ErrInvalidAccessToken),
+		errors.Is(err, errors.ErrExpiredAccessToken),
+		errors.Is(err, errors.ErrMissingAccessToken):
+		return errors.ErrInvalidToken.Error()
+	case errors.Is(err, errors.ErrInvalidAuthorizeCode),
+		errors.Is(err, errors.ErrInvalidRefreshToken),
+		errors.Is(err, errors.ErrExpiredRefreshToken),
+		errors.Is(err, errors.ErrInvalidCodeChallenge),
+		errors.Is(err, errors.ErrMissingCodeVerifier):
+		return errors.ErrInvalidGrant.Error()
+	case errors.Is(err, errors.ErrInvalidRedirectURI),
+		errors.Is(err, errors.ErrMissingCodeChallenge):
+		return errors.ErrInvalidRequest.Error()
+	}
+	return errors.ErrServerError.Error()
+}
@@ -0,0 +1,151 @@
+package telemetry_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/errors"
+	"github.com/go-oauth2/oauth2/v4/manage"
+	"github.com/go-oauth2/oauth2/v4/models"
+	"github.com/go-oauth2/oauth2/v4/store"
+	"github.com/go-oauth2/oauth2/v4/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func newTelemetry() (*telemetry.Telemetry, *tracetest.SpanRecorder, *sdkmetric.ManualReader) {
+	recorder := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	tel, err := telemetry.New(
+		telemetry.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))),
+		telemetry.WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+	)
+	So(err, ShouldBeNil)
+	return tel, recorder, reader
+}
+
+func spanNames(recorder *tracetest.SpanRecorder) []string {
+	var names []string
+	for _, s := range recorder.Ended() {
+		names = append(names, s.Name())
+	}
+	return names
+}
+
+func collect(reader *sdkmetric.ManualReader, name string) metricdata.Aggregation {
+	var rm metricdata.ResourceMetrics
+	So(reader.Collect(context.Background(), &rm), ShouldBeNil)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m.Data
+			}
+		}
+	}
+	return nil
+}
+
+// counterValue the sum of the counter data points with the attribute
+func counterValue(reader *sdkmetric.ManualReader, name string, kv attribute.KeyValue) int64 {
+	sum, ok := collect(reader, name).(metricdata.Sum[int64])
+	if !ok {
+		return 0
+	}
+	var n int64
+	for _, dp := range sum.DataPoints {
+		if v, ok := dp.Attributes.Value(kv.Key); ok && v == kv.Value {
+			n += dp.Value
+		}
+	}
+	return n
+}
+
+func newManager(tel *telemetry.Telemetry) *telemetry.Manager {
+	m := manage.NewDefaultManager()
+	ts, err := store.NewMemoryTokenStore()
+	So(err, ShouldBeNil)
+	m.MapTokenStorage(tel.WrapTokenStore(ts))
+	clientStore := store.NewClientStore()
+	clientStore.Set("1", &models.Client{ID: "1", Secret: "11", Domain: "http://localhost"})
+	m.MapClientStorage(tel.WrapClientStore(clientStore))
+	return tel.WrapManager(m)
+}
+
+func TestManager(t *testing.T) {
+	Convey("Test instrumented manager", t, func() {
+		tel, recorder, reader := newTelemetry()
+		m := newManager(tel)
+		ctx := context.Background()
+
+		ti, err := m.GenerateAccessToken(ctx, oauth2.ClientCredentials, &oauth2.TokenGenerateRequest{
+			ClientID:     "1",
+			ClientSecret: "11",
+		})
+		So(err, ShouldBeNil)
+		So(ti.GetAccess(), ShouldNotBeEmpty)
+
+		_, err = m.GenerateAccessToken(ctx, oauth2.ClientCredentials, &oauth2.TokenGenerateRequest{
+			ClientID:     "1",
+			ClientSecret: "invalid",
+		})
+		So(err, ShouldEqual, errors.ErrInvalidClient)
+
+		_, err = m.LoadAccessToken(ctx, "unknown")
+		So(err, ShouldEqual, errors.ErrInvalidAccessToken)
+
+		Convey("Spans", func() {
+			names := spanNames(recorder)
+			So(names, ShouldContain, "oauth2.Manager/GenerateAccessToken")
+			So(names, ShouldContain, "oauth2.Manager/LoadAccessToken")
+			So(names, ShouldContain, "oauth2.TokenStore/Create")
+			So(names, ShouldContain, "oauth2.ClientStore/GetByID")
+
+			// the store spans are children of the manager spans
+			for _, s := range recorder.Ended() {
+				if s.Name() == "oauth2.TokenStore/Create" {
+					So(s.Parent().IsValid(), ShouldBeTrue)
+				}
+			}
+		})
+
+		Convey("Tokens issued by grant type", func() {
+			So(counterValue(reader, telemetry.MetricTokensIssued,
+				telemetry.AttrGrantType.String("client_credentials")), ShouldEqual, 1)
+		})
+
+		Convey("Errors by OAuth error code", func() {
+			So(counterValue(reader, telemetry.MetricErrors,
+				telemetry.AttrErrorCode.String("invalid_client")), ShouldBeGreaterThan, 0)
+			So(counterValue(reader, telemetry.MetricErrors,
+				telemetry.AttrErrorCode.String("invalid_token")), ShouldBeGreaterThan, 0)
+		})
+
+		Convey("Store latency", func() {
+			hist, ok := collect(reader, telemetry.MetricStoreDuration).(metricdata.Histogram[float64])
+			So(ok, ShouldBeTrue)
+			ops := make(map[string]uint64)
+			for _, dp := range hist.DataPoints {
+				v, _ := dp.Attributes.Value(telemetry.AttrOperation)
+				ops[v.AsString()] += dp.Count
+			}
+			So(ops["Create"], ShouldEqual, 1)
+			So(ops["GetByID"], ShouldEqual, 2)
+			So(ops["GetByAccess"], ShouldEqual, 1)
+		})
+	})
+}
+
+func TestErrorCode(t *testing.T) {
+	Convey("Test OAuth error code", t, func() {
+		So(telemetry.ErrorCode(errors.ErrInvalidScope), ShouldEqual, "invalid_scope")
+		So(telemetry.ErrorCode(errors.ErrExpiredRefreshToken), ShouldEqual, "invalid_grant")
+		So(telemetry.ErrorCode(errors.ErrExpiredAccessToken), ShouldEqual, "invalid_token")
+		So(telemetry.ErrorCode(errors.New("unexpected")), ShouldEqual, "server_error")
+	})
+}